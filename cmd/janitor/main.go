@@ -0,0 +1,81 @@
+// Command janitor removes every user, app, cert and key matching the naming scheme used by the
+// e2e tests from a Workflow cluster. Use it to clean up after a run of the tests that crashed. Do
+// not use it while the tests are running!
+//
+// The controller is the one given by -controller, which defaults to $DEIS_CONTROLLER_URL. If
+// neither is set, requests for the controller are routed to the router at
+// $DEIS_ROUTER_SERVICE_HOST, as they are by the tests.
+//
+//	go run ./cmd/janitor -controller http://deis.your.cluster
+//	DEIS_ROUTER_SERVICE_HOST=192.0.2.10 DEIS_ROUTER_SERVICE_PORT=31182 go run ./cmd/janitor
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/auth"
+	"github.com/deis/workflow-e2e/tests/janitor"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/resolver"
+	"github.com/deis/workflow-e2e/tests/settings"
+)
+
+func main() {
+	controllerURL := flag.String("controller", os.Getenv("DEIS_CONTROLLER_URL"), "the `URL` of the Workflow controller")
+	flag.Parse()
+
+	var httpClient *http.Client
+	if *controllerURL == "" {
+		if !resolver.Enabled() {
+			fmt.Fprintln(os.Stderr, "Set -controller, $DEIS_CONTROLLER_URL or $DEIS_ROUTER_SERVICE_HOST.")
+			flag.Usage()
+			os.Exit(2)
+		}
+		*controllerURL = resolver.URL("http", "deis."+settings.DeisRootHostname)
+		httpClient = &http.Client{Transport: resolver.Transport()}
+	}
+
+	admin := model.Admin
+	client, err := newClient(*controllerURL, "", httpClient)
+	if err != nil {
+		fail(err)
+	}
+	token, err := auth.Login(client, admin.Username, admin.Password)
+	if err != nil {
+		fail(fmt.Errorf("could not log in as %s (%s)", admin.Username, err))
+	}
+	client, err = newClient(*controllerURL, token, httpClient)
+	if err != nil {
+		fail(err)
+	}
+
+	errs := janitor.New(client, os.Stdout).SweepAll()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// newClient returns a client for the controller at controllerURL that sends its requests with
+// httpClient, unless it is nil.
+func newClient(controllerURL, token string, httpClient *http.Client) (*deis.Client, error) {
+	client, err := deis.New(false, controllerURL, token)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
+	return client, nil
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	os.Exit(1)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/fixtures"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
 	"github.com/deis/workflow-e2e/tests/settings"
 	"github.com/deis/workflow-e2e/tests/util"
 
@@ -52,10 +54,7 @@ var _ = Describe("deis apps", func() {
 			bogusAppName := "bogus-app-name"
 
 			Specify("that user cannot get information about that app", func() {
-				sess, err := cmd.Start("deis info -a %s", &user, bogusAppName)
-				Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(1))
+				apps.InfoExpectingError(user, bogusAppName, deis.ErrNotFound)
 			})
 
 			Specify("that user cannot retrieve logs for that app", func() {
@@ -95,10 +94,7 @@ var _ = Describe("deis apps", func() {
 			})
 
 			Specify("that user cannot create a new app with the same name", func() {
-				sess, err := cmd.Start("deis apps:create %s", &user, app.Name)
-				Eventually(sess.Err).Should(Say("Application with this id already exists."))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).ShouldNot(Exit(0))
+				apps.CreateExpectingError(user, app.Name, deis.ErrDuplicateApp)
 			})
 
 			Context("and another user also exists", func() {
@@ -113,14 +109,15 @@ var _ = Describe("deis apps", func() {
 					auth.Cancel(otherUser)
 				})
 
+				Specify("the other user cannot destroy that app", func() {
+					apps.DestroyExpectingError(otherUser, app.Name, deis.ErrForbidden)
+				})
+
 				Specify("that first user can transfer ownership to the other user", func() {
 					sess, err := cmd.Start("deis apps:transfer --app=%s %s", &user, app.Name, otherUser.Username)
 					Expect(err).NotTo(HaveOccurred())
 					Eventually(sess).Should(Exit(0))
-					sess, err = cmd.Start("deis info -a %s", &user, app.Name)
-					Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrForbidden)))
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(1))
+					apps.InfoExpectingError(user, app.Name, deis.ErrForbidden)
 					// Transer back or else cleanup will fail.
 					sess, err = cmd.Start("deis apps:transfer --app=%s %s", &otherUser, app.Name, user.Username)
 					Expect(err).NotTo(HaveOccurred())
@@ -134,12 +131,11 @@ var _ = Describe("deis apps", func() {
 		Context("who has a local git repo containing source code", func() {
 
 			BeforeEach(func() {
-				output, err := cmd.Execute(`git clone https://github.com/deis/example-go.git`)
-				Expect(err).NotTo(HaveOccurred(), output)
+				writeFixture(fixtures.Go)
 			})
 
 			Specify("that user can create an app with a git remote", func() {
-				os.Chdir("example-go")
+				os.Chdir(fixtures.Go.Name)
 				app := apps.Create(user)
 				apps.Destroy(user, app)
 			})
@@ -149,7 +145,6 @@ var _ = Describe("deis apps", func() {
 		Context("who owns an existing app that has already been deployed", func() {
 
 			uuidRegExp := `[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}`
-			procsRegexp := `(%s-[\w-]+) up \(v\d+\)`
 			var app model.App
 
 			BeforeEach(func() {
@@ -169,11 +164,19 @@ var _ = Describe("deis apps", func() {
 				Eventually(sess).Should(Say(`owner:\s*%s`, user.Username))
 				Eventually(sess).Should(Say(`id:\s*%s`, app.Name))
 				Eventually(sess).Should(Say("=== %s Processes", app.Name))
-				Eventually(sess).Should(Say(procsRegexp, app.Name))
 				Eventually(sess).Should(Say("=== %s Domains", app.Name))
-				Eventually(sess).Should(Say("%s", app.Name))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				procs, err := parse.PsList(sess.Out.Contents())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(procs).NotTo(BeEmpty())
+				for _, proc := range procs {
+					Expect(proc.Name).To(HavePrefix(app.Name + "-"))
+				}
+				domains, err := parse.DomainsList(sess.Out.Contents())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(domains).To(ContainElement(app.Name))
 			})
 
 			Specify("that user can retrieve logs for that app", func() {
@@ -209,7 +212,7 @@ var _ = Describe("deis apps", func() {
 			})
 
 			Specify("that user can run a command with quotes in that app's environment", func() {
-				sess, err := cmd.Start("deis apps:run --app=%s echo 'Hello, \\\"高座\\\"'", &user, app.Name)
+				sess, err := cmd.Deis(&user, "apps:run", "--app="+app.Name, `echo 'Hello, "高座"'`)
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess, (settings.MaxEventuallyTimeout)).Should(Say("Hello, \"高座\""))
 				Eventually(sess).Should(Exit(0))
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"github.com/deis/workflow-e2e/tests/authz"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/cmd/apps"
+	"github.com/deis/workflow-e2e/tests/cmd/auth"
+	"github.com/deis/workflow-e2e/tests/cmd/certs"
+	"github.com/deis/workflow-e2e/tests/cmd/configs"
+	"github.com/deis/workflow-e2e/tests/cmd/domains"
+	"github.com/deis/workflow-e2e/tests/cmd/perms"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("deis authorization", func() {
+
+	Specify("the matrix covers every family of commands that the CLI lists", func() {
+		sess, err := cmd.Start("deis help", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(sess).Should(Exit(0))
+		families, err := parse.Subcommands(sess.Out.Contents())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authz.Uncovered(families)).To(BeEmpty(),
+			"the authorization matrix has no command from these families, nor are they authz.Unscoped")
+	})
+
+	Context("with an existing user who owns an existing app", func() {
+
+		var owner model.User
+		var app model.App
+		// appOwner is whoever owns the app once the spec is done with it.
+		var appOwner model.User
+		var others []model.User
+		// cleanups undo, once the app is destroyed, whatever a spec did beyond the app.
+		var cleanups []func()
+
+		BeforeEach(func() {
+			owner = auth.Register()
+			app = apps.Create(owner, "--no-remote")
+			appOwner = owner
+			others = nil
+			cleanups = nil
+		})
+
+		AfterEach(func() {
+			if app.Name != "" {
+				apps.Destroy(appOwner, app)
+			}
+			for i := len(cleanups) - 1; i >= 0; i-- {
+				cleanups[i]()
+			}
+			for _, user := range others {
+				auth.Cancel(user)
+			}
+			auth.Cancel(owner)
+		})
+
+		// register registers a user who is cancelled once the spec is done with them.
+		register := func() model.User {
+			user := auth.Register()
+			others = append(others, user)
+			return user
+		}
+
+		// actor returns a user who plays the specified role with respect to the app.
+		actor := func(role authz.Role) model.User {
+			switch role {
+			case authz.Owner:
+				return owner
+			case authz.Collaborator:
+				user := register()
+				perms.Create(owner, app, user)
+				return user
+			case authz.Admin:
+				return model.Admin
+			case authz.Unrelated:
+				return register()
+			case authz.Anonymous:
+				// A profile with no token makes the CLI send requests that carry no credentials.
+				user := model.NewUser()
+				cmd.CreateHome(&user)
+				cmd.SaveProfile(user, "")
+				return user
+			}
+			Fail("unknown role " + string(role))
+			return model.User{}
+		}
+
+		DescribeTable("each command may be executed by exactly the roles the matrix allows",
+			func(command authz.Command, role authz.Role, outcome authz.Outcome) {
+				user := actor(role)
+				values := map[string]string{authz.AppPlaceholder: app.Name}
+
+				var grantee model.User
+				if command.Needs(authz.UserPlaceholder) {
+					grantee = register()
+					values[authz.UserPlaceholder] = grantee.Username
+				}
+				if command.Needs(authz.CollaboratorPlaceholder) {
+					collaborator := register()
+					perms.Create(owner, app, collaborator)
+					values[authz.CollaboratorPlaceholder] = collaborator.Username
+				}
+				domain := app.Name + ".authz.example.com"
+				if command.Needs(authz.DomainPlaceholder) {
+					domains.Add(owner, app, domain)
+					values[authz.DomainPlaceholder] = domain
+				}
+				var cert model.Cert
+				if command.Needs(authz.CertPlaceholder) {
+					cert = model.NewCertWith(model.CertOptions{CommonName: domain})
+					values[authz.CertPlaceholder] = cert.Name
+					values[authz.CertPathPlaceholder] = cert.CertPath
+					values[authz.KeyPathPlaceholder] = cert.KeyPath
+					if !command.Needs(authz.CertPathPlaceholder) {
+						certs.Add(owner, cert)
+						cleanups = append(cleanups, func() { certs.Remove(owner, cert) })
+					}
+				}
+				if command.Needs(authz.TagPlaceholder) {
+					values[authz.TagPlaceholder] = nodeLabel()
+				}
+				for key, value := range command.Config {
+					configs.Set(owner, app, key, value)
+				}
+
+				sess, err := cmd.Deis(&user, command.Expand(values)...)
+				Expect(err).NotTo(HaveOccurred())
+				if outcome != authz.Allowed {
+					Eventually(sess.Err).Should(Say("%d", outcome.StatusCode()))
+					Eventually(sess).Should(Exit())
+					Expect(sess.ExitCode()).NotTo(BeZero())
+					return
+				}
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+
+				if command.Needs(authz.CertPathPlaceholder) {
+					model.Created.Track(model.CertResource(user, cert))
+					cleanups = append(cleanups, func() { certs.Remove(user, cert) })
+				}
+				switch command.Effect {
+				case authz.Destroys:
+					model.Created.Forget(model.AppResource(appOwner, app))
+					app = model.App{}
+				case authz.Transfers:
+					model.Created.Forget(model.AppResource(appOwner, app))
+					appOwner = grantee
+					model.Created.Track(model.AppResource(appOwner, app))
+				}
+			},
+			authz.Entries()...,
+		)
+
+	})
+
+})
@@ -0,0 +1,181 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/ginkgo/extensions/table"
+)
+
+// The types and functions in this package describe who may execute which deis commands against
+// an app, as a matrix of commands against the roles a user may play with respect to that app.
+// Entries expands the matrix into Ginkgo table entries, one for each command and role, so that a
+// single table in the suite verifies every cell.
+
+// Role is the relationship between the user executing a command and the app it acts upon.
+type Role string
+
+const (
+	// Owner is the user that created the app.
+	Owner Role = "owner"
+	// Collaborator is a user the owner has granted permissions on the app to with perms:create.
+	Collaborator Role = "collaborator"
+	// Admin is a system administrator.
+	Admin Role = "admin"
+	// Unrelated is a registered user with no permissions on the app.
+	Unrelated Role = "unrelated user"
+	// Anonymous is a client with no valid token.
+	Anonymous Role = "anonymous"
+)
+
+// Roles are every Role, in the order in which entries are generated for them.
+var Roles = []Role{Owner, Collaborator, Admin, Unrelated, Anonymous}
+
+// Outcome is the result of executing a command.
+type Outcome string
+
+const (
+	// Allowed means the command succeeds.
+	Allowed Outcome = "allowed"
+	// Forbidden means the controller refuses the command with 403 Forbidden.
+	Forbidden Outcome = "forbidden"
+	// Unauthorized means the controller refuses the command with 401 Unauthorized.
+	Unauthorized Outcome = "unauthorized"
+)
+
+// StatusCode returns the HTTP status code with which the controller refuses commands that have
+// the outcome, or 0 if it does not refuse them.
+func (o Outcome) StatusCode() int {
+	switch o {
+	case Forbidden:
+		return 403
+	case Unauthorized:
+		return 401
+	}
+	return 0
+}
+
+// Placeholders that may appear in a Command's Args. Each but AppPlaceholder names something that
+// is prepared before the command is executed, only for commands whose Args contain it.
+const (
+	// AppPlaceholder is replaced by the name of the app.
+	AppPlaceholder = "{{app}}"
+	// UserPlaceholder is replaced by the name of a registered user other than the one executing
+	// the command, for commands that act upon a second user.
+	UserPlaceholder = "{{user}}"
+	// CollaboratorPlaceholder is replaced by the name of a registered user, other than the one
+	// executing the command, whom the owner has granted permissions on the app to.
+	CollaboratorPlaceholder = "{{collaborator}}"
+	// DomainPlaceholder is replaced by a domain that the owner has added to the app.
+	DomainPlaceholder = "{{domain}}"
+	// CertPlaceholder is replaced by the name of a certificate for the domain DomainPlaceholder is
+	// replaced by. The owner has added it, unless the command's Args also contain
+	// CertPathPlaceholder, in which case the command adds it.
+	CertPlaceholder = "{{cert}}"
+	// CertPathPlaceholder is replaced by the path of the file that holds the certificate.
+	CertPathPlaceholder = "{{cert-path}}"
+	// KeyPathPlaceholder is replaced by the path of the file that holds the certificate's key.
+	KeyPathPlaceholder = "{{key-path}}"
+	// TagPlaceholder is replaced by KEY=value, where KEY and value are those of a label of a node
+	// in the cluster.
+	TagPlaceholder = "{{tag}}"
+)
+
+// Effect is what a command that is allowed does to the app, beyond reading or modifying it.
+type Effect int
+
+const (
+	// Keeps means the app remains its owner's, fit to be destroyed by them.
+	Keeps Effect = iota
+	// Destroys means the app no longer exists.
+	Destroys
+	// Transfers means the app belongs to the user UserPlaceholder is replaced by.
+	Transfers
+)
+
+// Command is a deis command and who may execute it.
+type Command struct {
+	// Resource is the family of deis commands to which the command belongs, such as "config".
+	Resource string
+	// Args are the arguments to the deis CLI, which may contain placeholders.
+	Args []string
+	// Config is the config, if any, that the owner sets on the app before the command is executed,
+	// for commands that depend on the app's config or on its having an earlier release.
+	Config map[string]string
+	// Effect is what the command does to the app when it is allowed.
+	Effect Effect
+	// Outcomes is the expected outcome of the command for each Role.
+	Outcomes map[Role]Outcome
+}
+
+// Expand returns the command's Args with each placeholder replaced by its value in the specified
+// map. Placeholders missing from the map are left in place.
+func (c Command) Expand(values map[string]string) []string {
+	var oldnew []string
+	for placeholder, value := range values {
+		oldnew = append(oldnew, placeholder, value)
+	}
+	replacer := strings.NewReplacer(oldnew...)
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+// Needs returns whether the command's Args contain the specified placeholder.
+func (c Command) Needs(placeholder string) bool {
+	for _, arg := range c.Args {
+		if strings.Contains(arg, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Command) String() string {
+	return "deis " + strings.Join(c.Args, " ")
+}
+
+// Entries returns a table entry for each cell of the matrix, whose parameters are the Command,
+// the Role executing it and the expected Outcome. It panics if any command lacks an outcome for
+// any Role, so that the suite cannot even be built with a gap in the matrix.
+func Entries() []table.TableEntry {
+	var entries []table.TableEntry
+	for _, c := range Matrix {
+		for _, role := range Roles {
+			outcome, ok := c.Outcomes[role]
+			if !ok {
+				panic(fmt.Sprintf("the authorization matrix gives no outcome of `%s` for the %s", c, role))
+			}
+			description := fmt.Sprintf("`%s` is %s for the %s", c, outcome, role)
+			entries = append(entries, table.Entry(description, c, role, outcome))
+		}
+	}
+	return entries
+}
+
+// appScoped are the outcomes of commands that anyone with access to an app may execute.
+func appScoped() map[Role]Outcome {
+	return map[Role]Outcome{
+		Owner:        Allowed,
+		Collaborator: Allowed,
+		Admin:        Allowed,
+		Unrelated:    Forbidden,
+		Anonymous:    Unauthorized,
+	}
+}
+
+// ownerOnly are the outcomes of commands that only the owner of an app, or an admin, may execute.
+func ownerOnly() map[Role]Outcome {
+	outcomes := appScoped()
+	outcomes[Collaborator] = Forbidden
+	return outcomes
+}
+
+// authenticated are the outcomes of commands that any registered user may execute.
+func authenticated() map[Role]Outcome {
+	outcomes := appScoped()
+	outcomes[Unrelated] = Allowed
+	return outcomes
+}
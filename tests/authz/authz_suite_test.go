@@ -0,0 +1,13 @@
+package authz
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuthz(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Authz")
+}
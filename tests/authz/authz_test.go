@@ -0,0 +1,87 @@
+package authz
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the authorization matrix", func() {
+
+	It("covers every family of commands that acts upon apps", func() {
+		families := []string{"apps", "config", "keys", "whitelist"}
+		Expect(Uncovered(families)).To(BeEmpty())
+		Expect(Uncovered(append(families, "services"))).To(Equal([]string{"services"}))
+	})
+
+	It("gives no commands from families that act upon no app", func() {
+		for _, c := range Matrix {
+			Expect(Unscoped).NotTo(ContainElement(c.Resource), c.String())
+		}
+	})
+
+	It("modifies every resource but builds", func() {
+		modified := map[string]bool{"builds": true}
+		for _, c := range Matrix {
+			if c.Args[0] != c.Resource+":list" && c.Args[0] != c.Resource+":info" {
+				modified[c.Resource] = true
+			}
+		}
+		for _, c := range Matrix {
+			Expect(modified).To(HaveKey(c.Resource), "no command modifies %s", c.Resource)
+		}
+	})
+
+	It("gives an outcome for every role of every command", func() {
+		for _, c := range Matrix {
+			for _, role := range Roles {
+				Expect(c.Outcomes).To(HaveKey(role), "%s for the %s", c, role)
+			}
+		}
+	})
+
+	It("expands into an entry for each cell", func() {
+		Expect(Entries()).To(HaveLen(len(Matrix) * len(Roles)))
+	})
+
+	It("denies every command to anonymous clients", func() {
+		for _, c := range Matrix {
+			Expect(c.Outcomes[Anonymous]).To(Equal(Unauthorized), c.String())
+		}
+	})
+
+	It("panics on a gap in the matrix", func() {
+		saved := Matrix
+		defer func() { Matrix = saved }()
+		Matrix = []Command{{Resource: "apps", Args: []string{"apps:info"}, Outcomes: map[Role]Outcome{Owner: Allowed}}}
+		Expect(func() { Entries() }).To(Panic())
+	})
+
+	It("replaces placeholders in args", func() {
+		c := Command{Args: []string{"perms:create", UserPlaceholder, "--app=" + AppPlaceholder}}
+		Expect(c.Expand(map[string]string{AppPlaceholder: "test-1", UserPlaceholder: "test-2"})).To(Equal(
+			[]string{"perms:create", "test-2", "--app=test-1"}))
+		Expect(c.Expand(map[string]string{AppPlaceholder: "test-1"})).To(Equal(
+			[]string{"perms:create", "{{user}}", "--app=test-1"}))
+		Expect(c.String()).To(Equal("deis perms:create {{user}} --app={{app}}"))
+		Expect(c.Needs(UserPlaceholder)).To(BeTrue())
+		Expect(c.Needs(CertPlaceholder)).To(BeFalse())
+	})
+
+	It("transfers the app to a user it names and destroys it only with confirmation", func() {
+		for _, c := range Matrix {
+			switch c.Effect {
+			case Transfers:
+				Expect(c.Needs(UserPlaceholder)).To(BeTrue(), c.String())
+			case Destroys:
+				Expect(c.Args).To(ContainElement("--confirm="+AppPlaceholder), c.String())
+			}
+		}
+	})
+
+	It("maps refusals to status codes", func() {
+		Expect(Allowed.StatusCode()).To(Equal(0))
+		Expect(Forbidden.StatusCode()).To(Equal(403))
+		Expect(Unauthorized.StatusCode()).To(Equal(401))
+	})
+
+})
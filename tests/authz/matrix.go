@@ -0,0 +1,74 @@
+package authz
+
+// Unscoped are the families of deis commands that act upon no app, and so have no place in the
+// matrix. `deis git` only configures the remote of a local repository.
+var Unscoped = []string{"auth", "git", "keys", "users", "version"}
+
+// Uncovered returns those of the specified families of deis commands, as listed by `deis help`,
+// that are not Unscoped but to which no command in the matrix belongs.
+func Uncovered(families []string) []string {
+	covered := map[string]bool{}
+	for _, resource := range Unscoped {
+		covered[resource] = true
+	}
+	for _, c := range Matrix {
+		covered[c.Resource] = true
+	}
+	var uncovered []string
+	for _, family := range families {
+		if !covered[family] {
+			uncovered = append(uncovered, family)
+		}
+	}
+	return uncovered
+}
+
+// Matrix is every command whose authorization is verified, including at least one that reads and
+// one that modifies each resource. The exception is builds, whose only modifying command,
+// builds:create, deploys the app; ps:scale is left out for the same reason, in favor of ps:restart,
+// which restarts no processes in an app that was never deployed. Unless their Effect says otherwise,
+// commands that modify an app do so in ways that leave it fit to be destroyed by its owner.
+var Matrix = []Command{
+	{Resource: "apps", Args: []string{"apps:info", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "apps", Args: []string{"apps:transfer", UserPlaceholder, "--app=" + AppPlaceholder}, Effect: Transfers, Outcomes: ownerOnly()},
+	{Resource: "apps", Args: []string{"apps:destroy", "--app=" + AppPlaceholder, "--confirm=" + AppPlaceholder}, Effect: Destroys, Outcomes: ownerOnly()},
+	{Resource: "autoscale", Args: []string{"autoscale:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "autoscale", Args: []string{"autoscale:set", "cmd", "--min=1", "--max=2", "--cpu-percent=50", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "builds", Args: []string{"builds:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "config", Args: []string{"config:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "config", Args: []string{"config:set", "AUTHZ=true", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "ps", Args: []string{"ps:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "ps", Args: []string{"ps:restart", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "domains", Args: []string{"domains:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "domains", Args: []string{"domains:add", AppPlaceholder + ".authz.example.com", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	// Certificates belong to no app, so any registered user may list and add them, but only their
+	// owner may attach them to a domain.
+	{Resource: "certs", Args: []string{"certs:list"}, Outcomes: authenticated()},
+	{Resource: "certs", Args: []string{"certs:add", CertPlaceholder, CertPathPlaceholder, KeyPathPlaceholder}, Outcomes: authenticated()},
+	{Resource: "certs", Args: []string{"certs:attach", CertPlaceholder, DomainPlaceholder}, Outcomes: ownerOnly()},
+	{Resource: "limits", Args: []string{"limits:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "limits", Args: []string{"limits:set", "cmd=64M", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "tags", Args: []string{"tags:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "tags", Args: []string{"tags:set", TagPlaceholder, "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "labels", Args: []string{"labels:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "labels", Args: []string{"labels:add", "authz=true", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "registry", Args: []string{"registry:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	// A private registry may only be set once the app's PORT is.
+	{Resource: "registry", Args: []string{"registry:set", "username=authz", "password=authz", "--app=" + AppPlaceholder}, Config: map[string]string{"PORT": "5000"}, Outcomes: appScoped()},
+	{Resource: "healthchecks", Args: []string{"healthchecks:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "healthchecks", Args: []string{"healthchecks:set", "liveness", "httpGet", "5000", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "whitelist", Args: []string{"whitelist:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "whitelist", Args: []string{"whitelist:add", "10.0.1.0/24", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "maintenance", Args: []string{"maintenance:info", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "maintenance", Args: []string{"maintenance:on", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "routing", Args: []string{"routing:info", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "routing", Args: []string{"routing:disable", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "tls", Args: []string{"tls:info", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "tls", Args: []string{"tls:enable", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "releases", Args: []string{"releases:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	// Setting config creates the release after the initial one, so there is one to roll back.
+	{Resource: "releases", Args: []string{"releases:rollback", "--app=" + AppPlaceholder}, Config: map[string]string{"AUTHZ": "true"}, Outcomes: appScoped()},
+	{Resource: "perms", Args: []string{"perms:list", "--app=" + AppPlaceholder}, Outcomes: appScoped()},
+	{Resource: "perms", Args: []string{"perms:create", UserPlaceholder, "--app=" + AppPlaceholder}, Outcomes: ownerOnly()},
+	{Resource: "perms", Args: []string{"perms:delete", CollaboratorPlaceholder, "--app=" + AppPlaceholder}, Outcomes: ownerOnly()},
+}
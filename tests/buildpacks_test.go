@@ -3,14 +3,15 @@ package tests
 import (
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/git"
 	"github.com/deis/workflow-e2e/tests/cmd/keys"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	"github.com/deis/workflow-e2e/tests/fixtures"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -39,57 +40,51 @@ var _ = Describe("all buildpack apps", func() {
 			})
 
 			DescribeTable("can deploy an example buildpack app",
-				func(url, buildpack, banner string) {
+				func(f fixtures.Fixture) {
 
 					var app model.App
 
-					output, err := cmd.Execute(`git clone %s`, url)
-					Expect(err).NotTo(HaveOccurred(), output)
-					// infer app directory from URL
-					splits := strings.Split(url, "/")
-					dir := strings.TrimSuffix(splits[len(splits)-1], ".git")
-					os.Chdir(dir)
+					os.Chdir(writeFixture(f))
 					// create with custom buildpack if needed
 					var args []string
-					if buildpack != "" {
-						args = append(args, fmt.Sprintf("--buildpack %s", buildpack))
+					if f.Buildpack != "" {
+						args = append(args, fmt.Sprintf("--buildpack %s", f.Buildpack))
 					}
 					app = apps.Create(user, args...)
 					defer apps.Destroy(user, app)
-					git.Push(user, keyPath, app, banner)
+					report := git.Push(user, keyPath, app, f.Manifest.Response)
+					Expect(report.Kind).To(Equal(parse.BuildpackBuild))
+					Expect(report.Version).To(Equal(2))
+					Expect(ps.Types(user, app)).To(ConsistOf(f.Manifest.ProcessTypes))
+					Expect(ps.List(user, app, f.Manifest.ProcessTypes[0])).NotTo(BeEmpty())
 
 				},
 
-				// NOTE: Keep this list up-to-date with any example apps that are added
-				// under the github/deis org, or any third-party apps that increase coverage
-				// or prevent regressions.
-				Entry("Clojure", "https://github.com/deis/example-clojure-ring.git", "",
-					"Powered by Deis"),
-				Entry("Go", "https://github.com/deis/example-go.git", "",
-					"Powered by Deis"),
-				Entry("Java", "https://github.com/deis/example-java-jetty.git", "",
-					"Powered by Deis"),
-				Entry("Multi", "https://github.com/deis/example-multi", "",
-					"Heroku Multipack Test"),
-				Entry("NodeJS", "https://github.com/deis/example-nodejs-express.git", "",
-					"Powered by Deis"),
-				Entry("Perl", "https://github.com/deis/example-perl.git",
-					"https://github.com/miyagawa/heroku-buildpack-perl.git",
-					"Powered by Deis"),
-				Entry("PHP", "https://github.com/deis/example-php.git", "",
-					"Powered by Deis"),
-				Entry("Java (Play)", "https://github.com/deis/example-play.git", "",
-					"Powered by Deis"),
-				Entry("Python (Django)", "https://github.com/deis/example-python-django.git", "",
-					"Powered by Deis"),
-				Entry("Python (Flask)", "https://github.com/deis/example-python-flask.git", "",
-					"Powered by Deis"),
-				Entry("Ruby", "https://github.com/deis/example-ruby-sinatra.git", "",
-					"Powered by Deis"),
-				Entry("Scala", "https://github.com/deis/example-scala.git", "",
-					"Powered by Deis"),
+				// NOTE: Keep this list up-to-date with the buildpack apps in tests/fixtures.
+				Entry("Clojure", fixtures.Clojure),
+				Entry("Go", fixtures.Go),
+				Entry("Java", fixtures.Java),
+				Entry("Java (Play)", fixtures.JavaPlay),
+				Entry("Multi", fixtures.Multipack),
+				Entry("NodeJS", fixtures.NodeJS),
+				Entry("Perl", fixtures.Perl),
+				Entry("PHP", fixtures.PHP),
+				Entry("Python", fixtures.Python),
+				Entry("Python (Django)", fixtures.PythonDjango),
+				Entry("Python (Flask)", fixtures.PythonFlask),
+				Entry("Ruby", fixtures.Ruby),
+				Entry("Scala", fixtures.Scala),
 			)
 
+			Specify("that user cannot deploy an app that no buildpack recognizes", func() {
+				f := fixtures.Undetectable
+				os.Chdir(writeFixture(f))
+				app := apps.Create(user)
+				defer apps.Destroy(user, app)
+				report := git.PushExpectingFailure(user, keyPath)
+				Expect(report.Failure).To(ContainSubstring(f.Manifest.FailsWith))
+			})
+
 		})
 
 	})
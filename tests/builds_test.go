@@ -6,9 +6,10 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
-	"github.com/deis/workflow-e2e/tests/util"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -37,10 +38,7 @@ var _ = Describe("deis builds", func() {
 			bogusAppName := "bogus-app-name"
 
 			Specify("that user cannot create a build for that app", func() {
-				sess, err := cmd.Start("deis builds:create -a %s %s", &user, bogusAppName, builds.ExampleImage)
-				Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(1))
+				builds.CreateExpectingError(user, bogusAppName, deis.ErrNotFound)
 			})
 
 		})
@@ -90,9 +88,7 @@ var _ = Describe("deis builds", func() {
 				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(1))
 
 				// test that the old rc is not deleted after a failed build
-				procsListing := listProcs(user, app, "").Out.Contents()
-				procs := scrapeProcs(app.Name, procsListing)
-				Expect(len(procs)).To(Equal(1))
+				Expect(ps.List(user, app, ps.CmdType)).To(HaveLen(1))
 			})
 
 			Specify("that user can create multiple builds of that app with DEPLOY_BATCHES set to 5", func() {
@@ -147,6 +143,12 @@ var _ = Describe("deis builds", func() {
 				builds.Pull(user, app)
 			})
 
+			Specify("that app remains responsive while a new build of it is deployed", func() {
+				probe.ExpectAvailableDuring(app.URL, func() {
+					builds.Create(user, app)
+				})
+			})
+
 		})
 
 	})
@@ -1,10 +1,8 @@
 package tests
 
 import (
-	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
+	"time"
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/workflow-e2e/tests/cmd"
@@ -13,10 +11,13 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
 	"github.com/deis/workflow-e2e/tests/cmd/certs"
 	"github.com/deis/workflow-e2e/tests/cmd/domains"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
-	"github.com/deis/workflow-e2e/tests/util"
+	"github.com/deis/workflow-e2e/tests/pki"
+	"github.com/deis/workflow-e2e/tests/resolver"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	. "github.com/onsi/gomega/gexec"
@@ -45,12 +46,9 @@ var _ = Describe("deis certs", func() {
 		})
 
 		Specify("that user cannot add a cert with a malformed name", func() {
-			sess, err := cmd.Start("deis certs:add %s %s %s", &user, "bogus.cert.name", cert.CertPath, cert.KeyPath)
-			// TODO: Figure out spacing issues that necessitate this workaround.
-			output := sess.Wait().Err.Contents()
-			Expect(strings.TrimSpace(string(output))).To(Equal(util.PrependError(deis.ErrInvalidName)))
-			Expect(err).NotTo(HaveOccurred())
-			Eventually(sess).Should(Exit(1))
+			malformed := cert
+			malformed.Name = "bogus.cert.name"
+			certs.AddExpectingError(user, malformed, deis.ErrInvalidName)
 		})
 
 		Specify("that user cannot add a cert using a non-existent cert file", func() {
@@ -70,24 +68,53 @@ var _ = Describe("deis certs", func() {
 		})
 
 		Specify("that user cannot add a cert with the key and cert files swapped", func() {
-			sess, err := cmd.Start("deis certs:add %s %s %s", &user, cert.Name, cert.KeyPath, cert.CertPath)
-			Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrInvalidCertificate)))
-			Expect(err).NotTo(HaveOccurred())
-			Eventually(sess).Should(Exit(1))
+			swapped := cert
+			swapped.CertPath, swapped.KeyPath = cert.KeyPath, cert.CertPath
+			certs.AddExpectingError(user, swapped, deis.ErrInvalidCertificate)
 		})
 
+		Specify("that user cannot add a cert that has expired", func() {
+			expired := model.NewCertWith(model.CertOptions{
+				CommonName: model.DefaultCertCommonName,
+				NotBefore:  time.Now().Add(-48 * time.Hour),
+				NotAfter:   time.Now().Add(-24 * time.Hour),
+			})
+			certs.AddExpectingError(user, expired, deis.ErrInvalidCertificate)
+		})
+
+		Specify("that user cannot add a cert whose chain precedes it", func() {
+			reversed := model.NewCertWith(model.CertOptions{
+				CommonName:    model.DefaultCertCommonName,
+				Intermediates: 1,
+				ReverseChain:  true,
+			})
+			certs.AddExpectingError(user, reversed, deis.ErrInvalidCertificate)
+		})
+
+		DescribeTable("that user can add and remove a cert",
+			func(opts model.CertOptions) {
+				c := model.NewCertWith(opts)
+				certs.Add(user, c)
+				certs.Remove(user, c)
+			},
+			Entry("that is not yet valid", model.CertOptions{
+				CommonName: model.DefaultCertCommonName,
+				NotBefore:  time.Now().Add(24 * time.Hour),
+			}),
+			Entry("with an ECDSA key", model.CertOptions{CommonName: model.DefaultCertCommonName, KeyType: pki.ECDSA}),
+			Entry("for a wildcard domain", model.CertOptions{CommonName: "*.foo.com"}),
+			Entry("issued by intermediate authorities", model.CertOptions{
+				CommonName:    model.DefaultCertCommonName,
+				Intermediates: 2,
+			}),
+		)
+
 		Specify("that user cannot get info on a non-existent cert", func() {
-			sess, err := cmd.Start("deis certs:info %s", &user, nonExistentCertName)
-			Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-			Expect(err).NotTo(HaveOccurred())
-			Eventually(sess).Should(Exit(1))
+			certs.InfoExpectingError(user, nonExistentCertName, deis.ErrNotFound)
 		})
 
 		Specify("that user cannot remove a non-existent cert", func() {
-			sess, err := cmd.Start("deis certs:remove %s", &user, nonExistentCertName)
-			Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-			Expect(err).NotTo(HaveOccurred())
-			Eventually(sess).Should(Exit(1))
+			certs.RemoveExpectingError(user, nonExistentCertName, deis.ErrNotFound)
 		})
 
 		Context("who owns an existing app", func() {
@@ -116,17 +143,11 @@ var _ = Describe("deis certs", func() {
 				})
 
 				Specify("that user cannot attach a non-existent cert to that domain", func() {
-					sess, err := cmd.Start("deis certs:attach %s %s", &user, nonExistentCertName, domain)
-					Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(1))
+					certs.AttachExpectingError(user, nonExistentCertName, domain, deis.ErrNotFound)
 				})
 
 				Specify("that user cannot detatch a non-existent cert from that domain", func() {
-					sess, err := cmd.Start("deis certs:detach %s %s", &user, nonExistentCertName, domain)
-					Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(1))
+					certs.DetachExpectingError(user, nonExistentCertName, domain, deis.ErrNotFound)
 				})
 
 			})
@@ -146,17 +167,11 @@ var _ = Describe("deis certs", func() {
 			})
 
 			Specify("that user cannot attach a cert to a non-existent domain", func() {
-				sess, err := cmd.Start("deis certs:attach %s %s", &user, cert.Name, nonExistentDomain)
-				Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(1))
+				certs.AttachExpectingError(user, cert.Name, nonExistentDomain, deis.ErrNotFound)
 			})
 
 			Specify("that user cannot detach a cert from a non-existent domain", func() {
-				sess, err := cmd.Start("deis certs:detach %s %s", &user, cert.Name, nonExistentDomain)
-				Eventually(sess.Err).Should(Say(util.PrependError(deis.ErrNotFound)))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(1))
+				certs.DetachExpectingError(user, cert.Name, nonExistentDomain, deis.ErrNotFound)
 			})
 
 		})
@@ -239,10 +254,60 @@ var _ = Describe("deis certs", func() {
 					})
 
 					Specify("that user can attach/detach that cert to/from that domain", func() {
+						defaultCert := certs.ServedFingerprint(domain)
 						certs.Attach(user, cert, domain)
-						curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -k -H "Host: %s" -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, domain, app.URL)}
-						Eventually(cmd.Retry(curlCmd, strconv.Itoa(http.StatusOK), 60)).Should(BeTrue())
+						// Request the domain itself over https, so that the router must select the cert by SNI, and
+						// check that it did
+						domainURL := resolver.URL("https", domain)
+						probe.ExpectEventually(verifying(domainURL), probe.Expected{StatusCode: http.StatusOK, PeerCommonName: domain})
+						certs.ExpectServed(cert, domain)
 						certs.Detach(user, cert, domain)
+						certs.ExpectNotServed(cert, domain, defaultCert)
+					})
+
+				})
+
+				Context("and that user also owns an existing cert issued by intermediate authorities", func() {
+
+					var chained model.Cert
+
+					BeforeEach(func() {
+						chained = model.NewCertWith(model.CertOptions{CommonName: domain, Intermediates: 2})
+						certs.Add(user, chained)
+					})
+
+					AfterEach(func() {
+						certs.Remove(user, chained)
+					})
+
+					Specify("a client trusting only the root can verify that cert once attached to that domain", func() {
+						defaultCert := certs.ServedFingerprint(domain)
+						certs.Attach(user, chained, domain)
+						probe.ExpectEventually(verifying(resolver.URL("https", domain)), probe.Expected{StatusCode: http.StatusOK, PeerCommonName: domain})
+						certs.ExpectServed(chained, domain)
+						certs.Detach(user, chained, domain)
+						certs.ExpectNotServed(chained, domain, defaultCert)
+					})
+
+				})
+
+				Context("and that user also owns an existing cert for another domain", func() {
+
+					var other model.Cert
+
+					BeforeEach(func() {
+						other = model.NewCertWith(model.CertOptions{CommonName: "bar.com"})
+						certs.Add(user, other)
+					})
+
+					AfterEach(func() {
+						certs.Remove(user, other)
+					})
+
+					Specify("a client refuses that cert once attached to that domain", func() {
+						certs.Attach(user, other, domain)
+						probe.ExpectEventually(verifying(resolver.URL("https", domain)), probe.Expected{Err: "certificate is valid for bar.com"})
+						certs.Detach(user, other, domain)
 					})
 
 				})
@@ -254,3 +319,11 @@ var _ = Describe("deis certs", func() {
 	})
 
 })
+
+// verifying returns a probe that GETs url, verifying the certificate presented against
+// model.CertAuthority() instead of skipping verification.
+func verifying(url string) probe.Probe {
+	p := probe.Get(url)
+	p.RootCAs = model.CertAuthority().Pool()
+	return p
+}
@@ -20,10 +20,47 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis apps` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
-// Create executes `deis apps:create` as the specified user with the specified, arvitrary options.
+// Driver implements the `deis apps` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Create(user model.User, app model.App, options ...string)
+	Destroy(user model.User, app model.App)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Create creates an app as the specified user with the specified, arbitrary `deis apps:create`
+// options. The app is tracked before it is created, so that it is torn down even if creating it
+// fails part way.
 func Create(user model.User, options ...string) model.App {
-	noRemote := false
 	app := model.NewApp()
+	model.Created.Track(model.AppResource(user, app))
+	Current().Create(user, app, options...)
+	return app
+}
+
+// Destroy destroys the specified app as the specified user.
+func Destroy(user model.User, app model.App) {
+	Current().Destroy(user, app)
+	model.Created.Forget(model.AppResource(user, app))
+}
+
+// Create executes `deis apps:create` as the specified user to create the specified app with the
+// specified, arvitrary options.
+func (CLI) Create(user model.User, app model.App, options ...string) {
+	noRemote := false
 	sess, err := cmd.Start("deis apps:create %s %s", &user, app.Name, strings.Join(options, " "))
 	Expect(err).NotTo(HaveOccurred())
 	sess.Wait(settings.MaxEventuallyTimeout)
@@ -42,7 +79,6 @@ func Create(user model.User, options ...string) model.App {
 		Eventually(sess).Should(Say("Git remote deis successfully created for app"))
 	}
 	Eventually(sess).Should(Exit(0))
-	return app
 }
 
 // Open executes `deis apps:open` on the specified app as the specified user. A shim is used to
@@ -61,9 +97,9 @@ func Open(user model.User, app model.App) {
 	defer shims.RemoveShim(myShim)
 
 	// Create custom env with location of open shim prepended to the PATH env var.
-	env := shims.PrependPath(os.Environ(), os.TempDir())
+	env := shims.PrependPath(cmd.Env(&user), os.TempDir())
 
-	sess, err := cmd.StartCmd(model.Cmd{Env: env, CommandLineString: fmt.Sprintf("DEIS_PROFILE=%s deis open -a %s", user.Username, app.Name)})
+	sess, err := cmd.StartCmd(model.Cmd{Env: env, CommandLineString: fmt.Sprintf("deis open -a %s", app.Name)})
 	Expect(err).NotTo(HaveOccurred())
 	Eventually(sess).Should(Exit(0))
 
@@ -73,12 +109,11 @@ func Open(user model.User, app model.App) {
 }
 
 // Destroy executes `deis apps:destroy` on the specified app as the specified user.
-func Destroy(user model.User, app model.App) *Session {
+func (CLI) Destroy(user model.User, app model.App) {
 	sess, err := cmd.Start("deis apps:destroy --app=%s --confirm=%s", &user, app.Name, app.Name)
 	Expect(err).NotTo(HaveOccurred())
 	sess.Wait(settings.MaxEventuallyTimeout)
 	Eventually(sess).Should(Say("Destroying %s...", app.Name))
 	Eventually(sess).Should(Say(`done in `))
 	Eventually(sess).Should(Exit(0))
-	return sess
 }
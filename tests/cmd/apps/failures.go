@@ -0,0 +1,77 @@
+package apps
+
+import (
+	sdkapps "github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for commonly used `deis apps` subcommands.
+// Each expects the subcommand to fail with the specified controller-sdk-go error, such as
+// deis.ErrNotFound, and verifies that it left no trace.
+
+// FailureDriver implements the `deis apps` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	CreateExpectingError(user model.User, appName string, expected error)
+	InfoExpectingError(user model.User, appName string, expected error)
+	DestroyExpectingError(user model.User, appName string, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// CreateExpectingError attempts to create an app with the specified name as the specified user,
+// expecting the expected error, and verifies that no such app was created or altered.
+func CreateExpectingError(user model.User, appName string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(appName))()
+	CurrentFailures().CreateExpectingError(user, appName, expected)
+}
+
+// InfoExpectingError attempts to get information about the specified app as the specified user,
+// expecting the expected error.
+func InfoExpectingError(user model.User, appName string, expected error) {
+	CurrentFailures().InfoExpectingError(user, appName, expected)
+}
+
+// DestroyExpectingError attempts to destroy the specified app as the specified user, expecting
+// the expected error, and verifies that the app was left as it was.
+func DestroyExpectingError(user model.User, appName string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(appName))()
+	CurrentFailures().DestroyExpectingError(user, appName, expected)
+}
+
+// snapshot returns a function that gets the specified app, as model.Admin.
+func snapshot(appName string) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return sdkapps.Get(cmd.AdminClient(), appName)
+	}
+}
+
+// CreateExpectingError executes `deis apps:create` as the specified user, expecting it to fail.
+func (CLI) CreateExpectingError(user model.User, appName string, expected error) {
+	sess, err := cmd.Deis(&user, "apps:create", appName, "--no-remote")
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// InfoExpectingError executes `deis apps:info` as the specified user, expecting it to fail.
+func (CLI) InfoExpectingError(user model.User, appName string, expected error) {
+	sess, err := cmd.Deis(&user, "apps:info", "--app="+appName)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// DestroyExpectingError executes `deis apps:destroy` as the specified user, expecting it to fail.
+func (CLI) DestroyExpectingError(user model.User, appName string, expected error) {
+	sess, err := cmd.Deis(&user, "apps:destroy", "--app="+appName, "--confirm="+appName)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
@@ -0,0 +1,86 @@
+package apps
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/deis/controller-sdk-go/api"
+	sdkapps "github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Create creates the specified app as the specified user. Of the `deis apps:create` options, only
+// --no-remote and --buildpack are understood. Unless --no-remote is specified, a git remote named
+// "deis" is added to the repository in the current directory, just as the CLI would have done.
+func (SDK) Create(user model.User, app model.App, options ...string) {
+	noRemote := false
+	buildpack := ""
+	args := strings.Fields(strings.Join(options, " "))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--no-remote":
+			noRemote = true
+		case args[i] == "--buildpack" && i+1 < len(args):
+			i++
+			buildpack = args[i]
+		case strings.HasPrefix(args[i], "--buildpack="):
+			buildpack = strings.TrimPrefix(args[i], "--buildpack=")
+		default:
+			Fail(fmt.Sprintf("the SDK driver does not support the `deis apps:create` option %q", args[i]))
+		}
+	}
+
+	client := cmd.Client(user)
+	_, err := sdkapps.New(client, app.Name)
+	Expect(err).NotTo(HaveOccurred())
+
+	if buildpack != "" {
+		values := map[string]interface{}{"BUILDPACK_URL": buildpack}
+		_, err = config.Set(client, app.Name, api.Config{Values: values})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	if !noRemote {
+		output, err := cmd.Execute("git remote add deis %s", remoteURL(app))
+		Expect(err).NotTo(HaveOccurred(), output)
+	}
+}
+
+// Destroy destroys the specified app as the specified user.
+func (SDK) Destroy(user model.User, app model.App) {
+	Expect(sdkapps.Delete(cmd.Client(user), app.Name)).To(Succeed())
+}
+
+// remoteURL returns the builder's git URL for the specified app, which the CLI derives from the
+// controller's hostname.
+func remoteURL(app model.App) string {
+	u, err := url.Parse(settings.DeisControllerURL)
+	Expect(err).NotTo(HaveOccurred())
+	hostTokens := strings.Split(strings.Split(u.Host, ":")[0], ".")
+	hostTokens[0] = fmt.Sprintf("%s-builder", hostTokens[0])
+	return fmt.Sprintf("ssh://git@%s:2222/%s.git", strings.Join(hostTokens, "."), app.Name)
+}
+
+// CreateExpectingError attempts to create an app as the specified user, expecting it to fail.
+func (SDK) CreateExpectingError(user model.User, appName string, expected error) {
+	_, err := sdkapps.New(cmd.Client(user), appName)
+	cmd.ExpectSDKError(err, expected)
+}
+
+// InfoExpectingError attempts to get an app as the specified user, expecting it to fail.
+func (SDK) InfoExpectingError(user model.User, appName string, expected error) {
+	_, err := sdkapps.Get(cmd.Client(user), appName)
+	cmd.ExpectSDKError(err, expected)
+}
+
+// DestroyExpectingError attempts to destroy an app as the specified user, expecting it to fail.
+func (SDK) DestroyExpectingError(user model.User, appName string, expected error) {
+	cmd.ExpectSDKError(sdkapps.Delete(cmd.Client(user), appName), expected)
+}
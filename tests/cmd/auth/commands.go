@@ -15,6 +15,30 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis auth` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
+// Driver implements the `deis auth` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly. Either way, a profile for the user is left behind so that specs can
+// continue to execute arbitrary deis commands as that user.
+type Driver interface {
+	Register(user model.User)
+	Login(user model.User)
+	Logout(user model.User)
+	Cancel(user model.User)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
 // RegisterAdmin executes `deis auth:register`, using hard-coded username, password, and email
 // address. When this is executed, it is executed in hopes of registering Workflow's FIRST user,
 // which will automatically have admin permissions. If this should fail, the function proceeds
@@ -41,22 +65,45 @@ func RegisterAdmin() {
 	Expect(err).NotTo(HaveOccurred())
 }
 
-// Register executes `deis auth:register` using a randomized username and returns a model.User.
+// Register registers a user with a randomized username and returns a model.User. The user is
+// given a private home directory in which to keep their profile.
 func Register() model.User {
 	user := model.NewUser()
+	cmd.CreateHome(&user)
+	model.Created.Track(model.UserResource(user))
+	Current().Register(user)
+	return user
+}
+
+// Login logs in as the specified user. In the process, it creates a corresponding profile that
+// contains the user's authentication token. Re-use of this profile for most other actions is what
+// permits multiple test users to act in parallel without impacting one another.
+func Login(user model.User) {
+	Current().Login(user)
+}
+
+// Logout logs out the specified user.
+func Logout(user model.User) {
+	Current().Logout(user)
+}
+
+// Cancel cancels the specified user's account.
+func Cancel(user model.User) {
+	Current().Cancel(user)
+	model.Created.Forget(model.UserResource(user))
+}
+
+// Register executes `deis auth:register` as the specified user.
+func (CLI) Register(user model.User) {
 	sess, err := cmd.Start("deis auth:register %s --username=%s --password=%s --email=%s", &user, settings.DeisControllerURL, user.Username, user.Password, user.Email)
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Exit(0))
 	Expect(err).NotTo(HaveOccurred())
 	Eventually(sess).Should(Say(fmt.Sprintf("Logged in as %s\n", user.Username)))
-	return user
 }
 
-// Login executes `deis auth:login` as the specified user. In the process, it creates the a
-// corresponding profile that contains the user's authentication token. Re-use of this profile is
-// for most other actions is what permits multiple test users to act in parallel without impacting
-// one another.
-func Login(user model.User) {
+// Login executes `deis auth:login` as the specified user.
+func (CLI) Login(user model.User) {
 	sess, err := cmd.Start("deis auth:login %s --username=%s --password=%s", &user, settings.DeisControllerURL, user.Username, user.Password)
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Exit(0))
@@ -93,7 +140,7 @@ func Regenerate(user model.User) {
 }
 
 // Logout executes `deis auth:logout` as the specified user.
-func Logout(user model.User) {
+func (CLI) Logout(user model.User) {
 	sess, err := cmd.Start("deis auth:logout", &user)
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Exit(0))
@@ -102,7 +149,7 @@ func Logout(user model.User) {
 }
 
 // Cancel executes `deis auth:cancel` as the specified user.
-func Cancel(user model.User) {
+func (CLI) Cancel(user model.User) {
 	sess, err := cmd.Start("deis auth:cancel --username=%s --password=%s --yes", &user, user.Username, user.Password)
 	Expect(err).To(BeNil())
 	Eventually(sess).Should(Exit(0))
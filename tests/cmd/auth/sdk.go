@@ -0,0 +1,34 @@
+package auth
+
+import (
+	sdkauth "github.com/deis/controller-sdk-go/auth"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Register registers the specified user and logs in as that user.
+func (d SDK) Register(user model.User) {
+	Expect(sdkauth.Register(cmd.NewClient(), user.Username, user.Password, user.Email)).To(Succeed())
+	d.Login(user)
+}
+
+// Login logs in as the specified user and saves the resulting token to the user's profile.
+func (SDK) Login(user model.User) {
+	token, err := sdkauth.Login(cmd.NewClient(), user.Username, user.Password)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.SaveProfile(user, token)
+}
+
+// Logout removes the specified user's profile. As with `deis auth:logout`, the user's token
+// remains valid.
+func (SDK) Logout(user model.User) {
+	cmd.RemoveProfile(user)
+}
+
+// Cancel cancels the specified user's account and removes the user's profile.
+func (SDK) Cancel(user model.User) {
+	Expect(sdkauth.Delete(cmd.Client(user), "")).To(Succeed())
+	cmd.RemoveProfile(user)
+}
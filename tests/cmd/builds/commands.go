@@ -1,10 +1,13 @@
 package builds
 
 import (
-	"time"
+	"fmt"
+	"strings"
 
 	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/poll"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/gomega"
@@ -15,22 +18,107 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis builds` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
-const ExampleImage = "deis/example-dockerfile-http"
+// ExampleRepository is the repository of the image deployed by Create and Pull.
+const ExampleRepository = "deis/example-dockerfile-http"
+
+// ExampleImage returns the name of the image deployed by Create and Pull. When the suite serves
+// images from a local registry, it is pulled from there instead of Docker Hub.
+func ExampleImage() string {
+	if settings.RegistryHost != "" {
+		return settings.RegistryHost + "/" + ExampleRepository
+	}
+	return ExampleRepository
+}
+
+// Driver implements the `deis builds` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Create(user model.User, app model.App)
+	Pull(user model.User, app model.App)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Create creates a build of ExampleImage() for the specified app as the specified user, and
+// returns the version of the release that it rolled out.
+func Create(user model.User, app model.App) int {
+	Current().Create(user, app)
+	return WaitForRelease(user, app)
+}
+
+// Pull deploys ExampleImage() to the specified app as the specified user, and returns the version
+// of the release that it rolled out.
+func Pull(user model.User, app model.App) int {
+	Current().Pull(user, app)
+	return WaitForRelease(user, app)
+}
 
 // Create executes `deis builds:create` as the specified user.
-func Create(user model.User, app model.App) {
+func (CLI) Create(user model.User, app model.App) {
 	createOrPull(user, app, "builds:create")
 }
 
 // Pull executes the `deis pull` shortcut as the specified user.
-func Pull(user model.User, app model.App) {
+func (CLI) Pull(user model.User, app model.App) {
 	createOrPull(user, app, "pull")
 }
 
 func createOrPull(user model.User, app model.App, command string) {
-	sess, err := cmd.Start("deis %s --app=%s %s", &user, command, app.Name, ExampleImage)
+	sess, err := cmd.Start("deis %s --app=%s %s", &user, command, app.Name, ExampleImage())
 	Expect(err).NotTo(HaveOccurred())
 	Eventually(sess).Should(Say("Creating build..."))
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
-	time.Sleep(10 * time.Second)
+}
+
+// WaitForRelease waits for the specified app's latest release to be rolled out, which is when
+// `deis ps:list` shows, twice in a row, that every one of its processes is up at that release;
+// processes that crash soon after they start are thus not mistaken for a rollout. It fails the
+// current spec with a description of every attempt if that does not happen within
+// settings.MaxEventuallyTimeout. It returns the version of that release.
+func WaitForRelease(user model.User, app model.App) int {
+	output, err := cmd.ExecuteCmd(cmd.Command(&user, "deis", "releases:info", "--app="+app.Name))
+	Expect(err).NotTo(HaveOccurred(), output)
+	release, err := parse.ReleasesInfo([]byte(output))
+	Expect(err).NotTo(HaveOccurred(), output)
+
+	psList := cmd.Command(&user, "deis", "ps:list", "--app="+app.Name)
+	_, err = cmd.PollCmd(psList, poll.New(settings.MaxEventuallyTimeout), poll.ExitCode(0), poll.Stable(2, upAt(release.Version)))
+	Expect(err).NotTo(HaveOccurred(), "%s v%d was not rolled out", app.Name, release.Version)
+	return release.Version
+}
+
+// upAt holds for the output of `deis ps:list` when it lists processes, every one of which is up at
+// the specified release.
+func upAt(version int) poll.Predicate {
+	return func(r poll.Result) (bool, string) {
+		procs, err := parse.PsList(r.Out)
+		if err != nil {
+			return false, err.Error()
+		}
+		if len(procs) == 0 {
+			return false, "no processes are listed"
+		}
+		var pending []string
+		for _, proc := range procs {
+			if proc.State != "up" || proc.Release != version {
+				pending = append(pending, fmt.Sprintf("%s is %s at v%d", proc.Name, proc.State, proc.Release))
+			}
+		}
+		if len(pending) > 0 {
+			return false, fmt.Sprintf("waiting for v%d: %s", version, strings.Join(pending, ", "))
+		}
+		return true, ""
+	}
 }
@@ -0,0 +1,50 @@
+package builds
+
+import (
+	sdkbuilds "github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for commonly used `deis builds` subcommands.
+// Each expects the subcommand to fail with the specified controller-sdk-go error, such as
+// deis.ErrNotFound, and verifies that it left no trace.
+
+// FailureDriver implements the `deis builds` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	CreateExpectingError(user model.User, appName string, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// CreateExpectingError attempts to create a build of ExampleImage() for the specified app as the
+// specified user, expecting the expected error, and verifies that the app's builds were left as
+// they were.
+func CreateExpectingError(user model.User, appName string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(appName))()
+	CurrentFailures().CreateExpectingError(user, appName, expected)
+}
+
+// snapshot returns a function that lists the specified app's builds, as model.Admin.
+func snapshot(appName string) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		builds, _, err := sdkbuilds.List(cmd.AdminClient(), appName, 0)
+		return builds, err
+	}
+}
+
+// CreateExpectingError executes `deis builds:create` as the specified user, expecting it to fail.
+func (CLI) CreateExpectingError(user model.User, appName string, expected error) {
+	sess, err := cmd.Deis(&user, "builds:create", "--app="+appName, ExampleImage())
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
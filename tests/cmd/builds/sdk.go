@@ -0,0 +1,28 @@
+package builds
+
+import (
+	sdkbuilds "github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Create creates a build of ExampleImage() for the specified app as the specified user.
+func (SDK) Create(user model.User, app model.App) {
+	_, err := sdkbuilds.New(cmd.Client(user), app.Name, ExampleImage(), nil)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Pull deploys ExampleImage() to the specified app as the specified user. `deis pull` is merely a
+// shortcut for `deis builds:create`, so this is identical to Create.
+func (d SDK) Pull(user model.User, app model.App) {
+	d.Create(user, app)
+}
+
+// CreateExpectingError attempts to create a build of ExampleImage() for the specified app as the
+// specified user, expecting it to fail.
+func (SDK) CreateExpectingError(user model.User, appName string, expected error) {
+	_, err := sdkbuilds.New(cmd.Client(user), appName, ExampleImage(), nil)
+	cmd.ExpectSDKError(err, expected)
+}
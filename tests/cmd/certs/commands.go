@@ -15,6 +15,51 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis certs` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
+// Driver implements the `deis certs` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Add(user model.User, cert model.Cert)
+	Remove(user model.User, cert model.Cert)
+	Attach(user model.User, cert model.Cert, domain string)
+	Detach(user model.User, cert model.Cert, domain string)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Add adds the specified cert as the specified user.
+func Add(user model.User, cert model.Cert) {
+	model.Created.Track(model.CertResource(user, cert))
+	Current().Add(user, cert)
+}
+
+// Remove removes the specified cert as the specified user.
+func Remove(user model.User, cert model.Cert) {
+	Current().Remove(user, cert)
+	model.Created.Forget(model.CertResource(user, cert))
+}
+
+// Attach attaches the specified cert to the specified domain as the specified user.
+func Attach(user model.User, cert model.Cert, domain string) {
+	Current().Attach(user, cert, domain)
+}
+
+// Detach detaches the specified cert from the specified domain as the specified user.
+func Detach(user model.User, cert model.Cert, domain string) {
+	Current().Detach(user, cert, domain)
+}
+
 // List executes `deis certs:list` as the specified user.
 func List(user model.User) *Session {
 	sess, err := cmd.Start("deis certs:list", &user)
@@ -24,7 +69,7 @@ func List(user model.User) *Session {
 }
 
 // Add executes `deis certs:add` as the specified user to add the specified cert.
-func Add(user model.User, cert model.Cert) {
+func (CLI) Add(user model.User, cert model.Cert) {
 	sess, err := cmd.Start("deis certs:add %s %s %s", &user, cert.Name, cert.CertPath, cert.KeyPath)
 	Eventually(sess).Should(Say("Adding SSL endpoint..."))
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("done"))
@@ -34,7 +79,7 @@ func Add(user model.User, cert model.Cert) {
 }
 
 // Remove executes `deis certs:remove` as the specified user to remove the specified cert.
-func Remove(user model.User, cert model.Cert) {
+func (CLI) Remove(user model.User, cert model.Cert) {
 	sess, err := cmd.Start("deis certs:remove %s", &user, cert.Name)
 	Eventually(sess).Should(Say("Removing %s...", cert.Name))
 	Eventually(sess).Should(Say("done"))
@@ -45,7 +90,7 @@ func Remove(user model.User, cert model.Cert) {
 
 // Attach executes `deis certs:attach` as the specified user to attach the specified cert to the
 // specified domain.
-func Attach(user model.User, cert model.Cert, domain string) {
+func (CLI) Attach(user model.User, cert model.Cert, domain string) {
 	sess, err := cmd.Start("deis certs:attach %s %s", &user, cert.Name, domain)
 	// Explicitly build literal substring since 'domain' may be a wildcard domain ('*.foo.com') and
 	// we don't want Gomega interpreting this string as a regexp
@@ -55,9 +100,9 @@ func Attach(user model.User, cert model.Cert, domain string) {
 	Eventually(sess).Should(Exit(0))
 }
 
-// Detatch executes `deis certs:detach` as the specified user to detach the specified cert from
+// Detach executes `deis certs:detach` as the specified user to detach the specified cert from
 // the specified domain.
-func Detach(user model.User, cert model.Cert, domain string) {
+func (CLI) Detach(user model.User, cert model.Cert, domain string) {
 	sess, err := cmd.Start("deis certs:detach %s %s", &user, cert.Name, domain)
 	// Explicitly build literal substring since 'domain' may be a wildcard domain ('*.foo.com') and
 	// we don't want Gomega interpreting this string as a regexp
@@ -0,0 +1,107 @@
+package certs
+
+import (
+	sdkcerts "github.com/deis/controller-sdk-go/certs"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for commonly used `deis certs` subcommands.
+// Each expects the subcommand to fail with the specified controller-sdk-go error, such as
+// deis.ErrNotFound, and verifies that it left no trace.
+
+// FailureDriver implements the `deis certs` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	AddExpectingError(user model.User, cert model.Cert, expected error)
+	InfoExpectingError(user model.User, certName string, expected error)
+	RemoveExpectingError(user model.User, certName string, expected error)
+	AttachExpectingError(user model.User, certName string, domain string, expected error)
+	DetachExpectingError(user model.User, certName string, domain string, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// AddExpectingError attempts to add the specified cert as the specified user, expecting the
+// expected error, and verifies that no cert of that name was added or altered.
+func AddExpectingError(user model.User, cert model.Cert, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(cert.Name))()
+	CurrentFailures().AddExpectingError(user, cert, expected)
+}
+
+// InfoExpectingError attempts to get information about the specified cert as the specified user,
+// expecting the expected error.
+func InfoExpectingError(user model.User, certName string, expected error) {
+	CurrentFailures().InfoExpectingError(user, certName, expected)
+}
+
+// RemoveExpectingError attempts to remove the specified cert as the specified user, expecting the
+// expected error, and verifies that the cert was left as it was.
+func RemoveExpectingError(user model.User, certName string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(certName))()
+	CurrentFailures().RemoveExpectingError(user, certName, expected)
+}
+
+// AttachExpectingError attempts to attach the specified cert to the specified domain as the
+// specified user, expecting the expected error, and verifies that the cert was left as it was.
+func AttachExpectingError(user model.User, certName string, domain string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(certName))()
+	CurrentFailures().AttachExpectingError(user, certName, domain, expected)
+}
+
+// DetachExpectingError attempts to detach the specified cert from the specified domain as the
+// specified user, expecting the expected error, and verifies that the cert was left as it was.
+func DetachExpectingError(user model.User, certName string, domain string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(certName))()
+	CurrentFailures().DetachExpectingError(user, certName, domain, expected)
+}
+
+// snapshot returns a function that gets the specified cert, as model.Admin.
+func snapshot(certName string) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return sdkcerts.Get(cmd.AdminClient(), certName)
+	}
+}
+
+// AddExpectingError executes `deis certs:add` as the specified user, expecting it to fail.
+func (CLI) AddExpectingError(user model.User, cert model.Cert, expected error) {
+	sess, err := cmd.Deis(&user, "certs:add", cert.Name, cert.CertPath, cert.KeyPath)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// InfoExpectingError executes `deis certs:info` as the specified user, expecting it to fail.
+func (CLI) InfoExpectingError(user model.User, certName string, expected error) {
+	sess, err := cmd.Deis(&user, "certs:info", certName)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// RemoveExpectingError executes `deis certs:remove` as the specified user, expecting it to fail.
+func (CLI) RemoveExpectingError(user model.User, certName string, expected error) {
+	sess, err := cmd.Deis(&user, "certs:remove", certName)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// AttachExpectingError executes `deis certs:attach` as the specified user, expecting it to fail.
+func (CLI) AttachExpectingError(user model.User, certName string, domain string, expected error) {
+	sess, err := cmd.Deis(&user, "certs:attach", certName, domain)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// DetachExpectingError executes `deis certs:detach` as the specified user, expecting it to fail.
+func (CLI) DetachExpectingError(user model.User, certName string, domain string, expected error) {
+	sess, err := cmd.Deis(&user, "certs:detach", certName, domain)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
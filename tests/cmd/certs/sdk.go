@@ -0,0 +1,72 @@
+package certs
+
+import (
+	"io/ioutil"
+
+	sdkcerts "github.com/deis/controller-sdk-go/certs"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Add adds the specified cert as the specified user.
+func (SDK) Add(user model.User, cert model.Cert) {
+	certificate, err := ioutil.ReadFile(cert.CertPath)
+	Expect(err).NotTo(HaveOccurred())
+	key, err := ioutil.ReadFile(cert.KeyPath)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = sdkcerts.New(cmd.Client(user), string(certificate), string(key), cert.Name)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Remove removes the specified cert as the specified user.
+func (SDK) Remove(user model.User, cert model.Cert) {
+	Expect(sdkcerts.Delete(cmd.Client(user), cert.Name)).To(Succeed())
+}
+
+// Attach attaches the specified cert to the specified domain as the specified user.
+func (SDK) Attach(user model.User, cert model.Cert, domain string) {
+	Expect(sdkcerts.Attach(cmd.Client(user), cert.Name, domain)).To(Succeed())
+}
+
+// Detach detaches the specified cert from the specified domain as the specified user.
+func (SDK) Detach(user model.User, cert model.Cert, domain string) {
+	Expect(sdkcerts.Detach(cmd.Client(user), cert.Name, domain)).To(Succeed())
+}
+
+// AddExpectingError attempts to add the specified cert as the specified user, expecting it to
+// fail.
+func (SDK) AddExpectingError(user model.User, cert model.Cert, expected error) {
+	certificate, err := ioutil.ReadFile(cert.CertPath)
+	Expect(err).NotTo(HaveOccurred())
+	key, err := ioutil.ReadFile(cert.KeyPath)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = sdkcerts.New(cmd.Client(user), string(certificate), string(key), cert.Name)
+	cmd.ExpectSDKError(err, expected)
+}
+
+// InfoExpectingError attempts to get the specified cert as the specified user, expecting it to
+// fail.
+func (SDK) InfoExpectingError(user model.User, certName string, expected error) {
+	_, err := sdkcerts.Get(cmd.Client(user), certName)
+	cmd.ExpectSDKError(err, expected)
+}
+
+// RemoveExpectingError attempts to remove the specified cert as the specified user, expecting it
+// to fail.
+func (SDK) RemoveExpectingError(user model.User, certName string, expected error) {
+	cmd.ExpectSDKError(sdkcerts.Delete(cmd.Client(user), certName), expected)
+}
+
+// AttachExpectingError attempts to attach the specified cert to the specified domain as the
+// specified user, expecting it to fail.
+func (SDK) AttachExpectingError(user model.User, certName string, domain string, expected error) {
+	cmd.ExpectSDKError(sdkcerts.Attach(cmd.Client(user), certName, domain), expected)
+}
+
+// DetachExpectingError attempts to detach the specified cert from the specified domain as the
+// specified user, expecting it to fail.
+func (SDK) DetachExpectingError(user model.User, certName string, domain string, expected error) {
+	cmd.ExpectSDKError(sdkcerts.Detach(cmd.Client(user), certName, domain), expected)
+}
@@ -0,0 +1,56 @@
+package certs
+
+import (
+	"crypto/x509"
+
+	probe "github.com/deis/workflow-e2e/tests/http"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file verify, by TLS handshakes with the router, which cert it serves for a
+// domain, since `deis certs:attach` and `deis certs:detach` return before the router has been
+// reconfigured.
+
+// ExpectServed verifies that the router eventually serves the specified cert for the specified
+// domain, followed by its complete chain, and that a client trusting only model.CertAuthority()
+// accepts it.
+func ExpectServed(cert model.Cert, domain string) {
+	chain := append([]*x509.Certificate{}, cert.Chain...)
+	expectServedEventually(domain, probe.ExpectedCert{
+		Fingerprint: probe.Fingerprint(cert.Leaf),
+		Chain:       chain,
+		Roots:       model.CertAuthority().Pool(),
+	})
+}
+
+// ServedFingerprint returns the fingerprint of the cert that the router serves for the specified
+// domain, once a handshake succeeds. Specs call it before attaching a cert to the domain, so that
+// ExpectNotServed can verify that the router reverts to that cert once theirs is detached.
+func ServedFingerprint(domain string) string {
+	poller := poll.New(settings.DefaultEventuallyTimeout)
+	served, err := probe.UntilServed(probe.HandshakeWith(domain), poller, probe.Serving(probe.ExpectedCert{}))
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return probe.Fingerprint(served.Certificates[0])
+}
+
+// ExpectNotServed verifies that the router eventually stops serving the specified cert for the
+// specified domain, and serves the cert with the specified fingerprint, as returned by
+// ServedFingerprint before the cert was attached, instead.
+func ExpectNotServed(cert model.Cert, domain string, fingerprint string) {
+	expectServedEventually(domain, probe.ExpectedCert{
+		Fingerprint:    fingerprint,
+		NotFingerprint: probe.Fingerprint(cert.Leaf),
+	})
+}
+
+// expectServedEventually is probe.ExpectServedEventually, but reports failures at the caller of
+// ExpectServed or ExpectNotServed.
+func expectServedEventually(domain string, expected probe.ExpectedCert) {
+	poller := poll.New(settings.DefaultEventuallyTimeout)
+	_, err := probe.UntilServed(probe.HandshakeWith(domain), poller, probe.Serving(expected))
+	ExpectWithOffset(2, err).NotTo(HaveOccurred())
+}
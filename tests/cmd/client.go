@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"github.com/onsi/gomega"
+)
+
+// The functions in this file support the controller-sdk-go (SDK) driver implemented by each of the
+// tests/cmd/<resource> packages. State is shared with the deis CLI by way of the user's profile,
+// so that specs may freely mix driver calls with commands started via Start(...).
+
+// profile mirrors the format of the profiles written by `deis auth:login` to
+// $HOME/.deis/<profile>.json.
+type profile struct {
+	Username      string `json:"username"`
+	SSLVerify     bool   `json:"ssl_verify"`
+	Controller    string `json:"controller"`
+	Token         string `json:"token"`
+	ResponseLimit int    `json:"response_limit"`
+}
+
+// UseSDK returns true if the helpers in the tests/cmd/<resource> packages should call the
+// controller directly using controller-sdk-go instead of executing the deis CLI.
+func UseSDK() bool {
+	return settings.Driver == settings.SDKDriver
+}
+
+// NewClient returns a controller-sdk-go client that has not been authenticated as any user.
+func NewClient() *deis.Client {
+	client, err := deis.New(false, settings.DeisControllerURL, "")
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	return client
+}
+
+// Client returns a controller-sdk-go client authenticated as the specified user, using the token
+// from that user's profile.
+func Client(user model.User) *deis.Client {
+	client, err := ProfileClient(user)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	return client
+}
+
+// ProfileClient is like Client, but returns an error instead of failing the current spec. It is
+// safe to use outside of specs.
+func ProfileClient(user model.User) (*deis.Client, error) {
+	contents, err := ioutil.ReadFile(profilePath(user))
+	if err != nil {
+		return nil, err
+	}
+	p := profile{}
+	if err := json.Unmarshal(contents, &p); err != nil {
+		return nil, err
+	}
+	return deis.New(p.SSLVerify, p.Controller, p.Token)
+}
+
+// SaveProfile writes a profile for the specified user containing the specified token, exactly as
+// `deis auth:login` would have.
+func SaveProfile(user model.User, token string) {
+	p := profile{
+		Username:      user.Username,
+		Controller:    settings.DeisControllerURL,
+		Token:         token,
+		ResponseLimit: 0,
+	}
+	contents, err := json.Marshal(p)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	gomega.Expect(os.MkdirAll(path.Dir(profilePath(user)), 0775)).To(gomega.Succeed())
+	gomega.Expect(ioutil.WriteFile(profilePath(user), contents, 0600)).To(gomega.Succeed())
+}
+
+// RemoveProfile deletes the specified user's profile, exactly as `deis auth:logout` would have.
+func RemoveProfile(user model.User) {
+	err := os.Remove(profilePath(user))
+	if !os.IsNotExist(err) {
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+}
+
+func profilePath(user model.User) string {
+	return path.Join(Home(user), ".deis", user.Username+".json")
+}
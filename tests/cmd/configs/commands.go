@@ -13,12 +13,36 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis config` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
+// Driver implements the `deis config` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Set(user model.User, app model.App, key string, value string)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Set sets the specified config var on the specified app as the specified user.
+func Set(user model.User, app model.App, key string, value string) {
+	Current().Set(user, app, key, value)
+}
+
 // Set executes `deis config:set` on the specified app as the specified user.
-func Set(user model.User, app model.App, key string, value string) *Session {
+func (CLI) Set(user model.User, app model.App, key string, value string) {
 	sess, err := cmd.Start("deis config:set %s=%s --app=%s", &user, key, value, app.Name)
 	Expect(err).NotTo(HaveOccurred())
 	sess.Wait(settings.MaxEventuallyTimeout)
 	Eventually(sess).Should(Say("Creating config..."))
 	Eventually(sess).Should(Exit(0))
-	return sess
 }
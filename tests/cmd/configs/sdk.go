@@ -0,0 +1,17 @@
+package configs
+
+import (
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Set sets the specified config var on the specified app as the specified user.
+func (SDK) Set(user model.User, app model.App, key string, value string) {
+	values := map[string]interface{}{key: value}
+	_, err := config.Set(cmd.Client(user), app.Name, api.Config{Values: values})
+	Expect(err).NotTo(HaveOccurred())
+}
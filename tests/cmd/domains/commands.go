@@ -15,9 +15,42 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis domains` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
+// Driver implements the `deis domains` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Add(user model.User, app model.App, domain string)
+	Remove(user model.User, app model.App, domain string)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Add adds the specified domain to the specified app as the specified user.
+func Add(user model.User, app model.App, domain string) {
+	model.Created.Track(model.DomainResource(user, app, domain))
+	Current().Add(user, app, domain)
+}
+
+// Remove removes the specified domain from the specified app as the specified user.
+func Remove(user model.User, app model.App, domain string) {
+	Current().Remove(user, app, domain)
+	model.Created.Forget(model.DomainResource(user, app, domain))
+}
+
 // Add executes `deis domains:add` as the specified user to add the specified domain to the
 // specified app.
-func Add(user model.User, app model.App, domain string) {
+func (CLI) Add(user model.User, app model.App, domain string) {
 	sess, err := cmd.Start("deis domains:add %s --app=%s", &user, domain, app.Name)
 	// Explicitly build literal substring since 'domain' may be a wildcard domain ('*.foo.com') and
 	// we don't want Gomega interpreting this string as a regexp
@@ -29,7 +62,7 @@ func Add(user model.User, app model.App, domain string) {
 
 // Remove executes `deis domains:remove` as the specified user to remove the specified domain from
 // the specified app.
-func Remove(user model.User, app model.App, domain string) {
+func (CLI) Remove(user model.User, app model.App, domain string) {
 	sess, err := cmd.Start("deis domains:remove %s --app=%s", &user, domain, app.Name)
 	// Explicitly build literal substring since 'domain' may be a wildcard domain ('*.foo.com') and
 	// we don't want Gomega interpreting this string as a regexp
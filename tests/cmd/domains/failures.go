@@ -0,0 +1,65 @@
+package domains
+
+import (
+	sdkdomains "github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for commonly used `deis domains` subcommands.
+// Each expects the subcommand to fail with the specified controller-sdk-go error, such as
+// deis.ErrNotFound, and verifies that it left no trace.
+
+// FailureDriver implements the `deis domains` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	AddExpectingError(user model.User, app model.App, domain string, expected error)
+	RemoveExpectingError(user model.User, app model.App, domain string, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// AddExpectingError attempts to add the specified domain to the specified app as the specified
+// user, expecting the expected error, and verifies that the app's domains were left as they were.
+func AddExpectingError(user model.User, app model.App, domain string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(app))()
+	CurrentFailures().AddExpectingError(user, app, domain, expected)
+}
+
+// RemoveExpectingError attempts to remove the specified domain from the specified app as the
+// specified user, expecting the expected error, and verifies that the app's domains were left as
+// they were.
+func RemoveExpectingError(user model.User, app model.App, domain string, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(app))()
+	CurrentFailures().RemoveExpectingError(user, app, domain, expected)
+}
+
+// snapshot returns a function that lists the specified app's domains, as model.Admin.
+func snapshot(app model.App) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		domains, _, err := sdkdomains.List(cmd.AdminClient(), app.Name, 0)
+		return domains, err
+	}
+}
+
+// AddExpectingError executes `deis domains:add` as the specified user, expecting it to fail.
+func (CLI) AddExpectingError(user model.User, app model.App, domain string, expected error) {
+	sess, err := cmd.Deis(&user, "domains:add", domain, "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// RemoveExpectingError executes `deis domains:remove` as the specified user, expecting it to fail.
+func (CLI) RemoveExpectingError(user model.User, app model.App, domain string, expected error) {
+	sess, err := cmd.Deis(&user, "domains:remove", domain, "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
@@ -0,0 +1,33 @@
+package domains
+
+import (
+	sdkdomains "github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Add adds the specified domain to the specified app as the specified user.
+func (SDK) Add(user model.User, app model.App, domain string) {
+	_, err := sdkdomains.New(cmd.Client(user), app.Name, domain)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Remove removes the specified domain from the specified app as the specified user.
+func (SDK) Remove(user model.User, app model.App, domain string) {
+	Expect(sdkdomains.Delete(cmd.Client(user), app.Name, domain)).To(Succeed())
+}
+
+// AddExpectingError attempts to add the specified domain to the specified app as the specified
+// user, expecting it to fail.
+func (SDK) AddExpectingError(user model.User, app model.App, domain string, expected error) {
+	_, err := sdkdomains.New(cmd.Client(user), app.Name, domain)
+	cmd.ExpectSDKError(err, expected)
+}
+
+// RemoveExpectingError attempts to remove the specified domain from the specified app as the
+// specified user, expecting it to fail.
+func (SDK) RemoveExpectingError(user model.User, app model.App, domain string, expected error) {
+	cmd.ExpectSDKError(sdkdomains.Delete(cmd.Client(user), app.Name, domain), expected)
+}
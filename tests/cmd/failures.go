@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+	"github.com/deis/workflow-e2e/tests/util"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+// The functions in this file support the FAILURE CASES implemented by the tests/cmd/<resource>
+// packages. The error a failure case expects is one of the controller-sdk-go error values, such as
+// deis.ErrNotFound, which the SDK driver returns as is, and which the CLI prints to stderr.
+
+// ExpectCLIError waits for the specified deis CLI session to exit 1 having printed the expected
+// error, exactly as the CLI prints controller-sdk-go errors.
+func ExpectCLIError(sess *gexec.Session, expected error) {
+	gomega.EventuallyWithOffset(1, sess, settings.MaxEventuallyTimeout).Should(gexec.Exit())
+	gomega.ExpectWithOffset(1, string(sess.Err.Contents())).To(gomega.ContainSubstring(util.PrependError(expected)))
+	gomega.ExpectWithOffset(1, sess.ExitCode()).To(gomega.Equal(1))
+}
+
+// ExpectSDKError asserts that the error returned by a controller-sdk-go call is the expected one.
+func ExpectSDKError(err error, expected error) {
+	gomega.ExpectWithOffset(1, err).To(gomega.MatchError(expected))
+}
+
+// ExpectUnchanged calls get, and returns a function that calls it again and asserts that its
+// results are the same, so that a failure case can verify it left no trace. It is used thus:
+//
+//	defer cmd.ExpectUnchanged(func() (interface{}, error) { ... })()
+func ExpectUnchanged(get func() (interface{}, error)) func() {
+	before, beforeErr := get()
+	return func() {
+		after, afterErr := get()
+		gomega.ExpectWithOffset(1, after).To(gomega.Equal(before), "the state changed despite the error")
+		gomega.ExpectWithOffset(1, afterErr).To(gomega.Equal(beforeErr), "the state changed despite the error")
+	}
+}
+
+// AdminClient returns a controller-sdk-go client authenticated as model.Admin, with which failure
+// cases inspect state that the user they act as may not be permitted to see.
+func AdminClient() *deis.Client {
+	return Client(model.Admin)
+}
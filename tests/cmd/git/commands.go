@@ -3,14 +3,16 @@ package git
 import (
 	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/deis/workflow-e2e/tests/cmd"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/poll"
 	"github.com/deis/workflow-e2e/tests/settings"
 
+	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	. "github.com/onsi/gomega/gexec"
@@ -23,26 +25,34 @@ const (
 	pushCommandLineString = "GIT_SSH=%s GIT_KEY=%s git push deis master"
 )
 
-// Push executes a `git push deis master` from the current directory using the provided key.
-func Push(user model.User, keyPath string, app model.App, banner string) {
-	sess := StartPush(user, keyPath)
-	// sess.Wait(settings.MaxEventuallyTimeout)
-	// output := string(sess.Out.Contents())
-	// Expect(output).To(MatchRegexp(`Done, %s:v\d deployed to Deis`, app.Name))
+// Push executes a `git push deis master` from the current directory using the provided key, and
+// returns the report of the build, which is also recorded by cmd.RecordBuild.
+func Push(user model.User, keyPath string, app model.App, banner string) parse.BuildReport {
+	sess, log := StartPushWithLog(user, keyPath)
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+	report := log.Finish(time.Now())
+	cmd.RecordBuild(report)
+	fmt.Fprintf(GinkgoWriter, "%s\n", report)
 	Curl(app, banner)
+	return report
+}
+
+// PushExpectingFailure executes a `git push deis master` from the current directory using the
+// provided key, expecting the build to fail, and returns the report of the build, which is also
+// recorded by cmd.RecordBuild.
+func PushExpectingFailure(user model.User, keyPath string) parse.BuildReport {
+	sess, log := StartPushWithLog(user, keyPath)
+	Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(1))
+	report := log.Finish(time.Now())
+	cmd.RecordBuild(report)
+	fmt.Fprintf(GinkgoWriter, "%s\n", report)
+	Expect(report.Succeeded).To(BeFalse())
+	return report
 }
 
 // Curl polls an app over HTTP until it returns the expected "Powered by" banner.
 func Curl(app model.App, banner string) {
-	// curl the app's root URL and print just the HTTP response code
-	cmdRetryTimeout := 60
-	curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(
-		`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-	Eventually(cmd.Retry(curlCmd, strconv.Itoa(http.StatusOK), cmdRetryTimeout)).Should(BeTrue())
-	// verify that the response contains "Powered by" as all the example apps do
-	curlCmd = model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL "%s"`, app.URL)}
-	Eventually(cmd.Retry(curlCmd, banner, cmdRetryTimeout)).Should(BeTrue())
+	probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: http.StatusOK, Body: banner})
 }
 
 // PushWithInterrupt executes a `git push deis master` from the current
@@ -62,14 +72,13 @@ func PushWithInterrupt(user model.User, keyPath string) {
 // PushUntilResult executes a `git push deis master` from the current
 // directory using the provided key, until the command result satisfies
 // expectedCmdResult of type model.CmdResult, failing if
-// settings.DefaultEventuallyTimeout is reached first.
+// settings.MaxEventuallyTimeout is reached first.
 func PushUntilResult(user model.User, keyPath string, expectedCmdResult model.CmdResult) {
-	envVars := append(os.Environ(), fmt.Sprintf("DEIS_PROFILE=%s", user.Username))
-	pushCmd := model.Cmd{Env: envVars, CommandLineString: fmt.Sprintf(
+	pushCmd := model.Cmd{Env: cmd.Env(&user), CommandLineString: fmt.Sprintf(
 		pushCommandLineString, settings.GitSSH, keyPath)}
 
-	Eventually(cmd.RetryUntilResult(pushCmd, expectedCmdResult, 5*time.Second,
-		settings.MaxEventuallyTimeout)).Should(BeTrue())
+	_, err := cmd.PollCmd(pushCmd, poll.New(settings.MaxEventuallyTimeout), cmd.Satisfies(expectedCmdResult))
+	Expect(err).NotTo(HaveOccurred())
 }
 
 // StartPush starts a `git push deis master` command and returns the command session.
@@ -78,3 +87,14 @@ func StartPush(user model.User, keyPath string) *Session {
 	Expect(err).NotTo(HaveOccurred())
 	return sess
 }
+
+// StartPushWithLog starts a `git push deis master` command and returns the command session, along
+// with a log that follows the progress of the build as the push runs.
+func StartPushWithLog(user model.User, keyPath string) (*Session, *parse.BuildLog) {
+	log := parse.NewBuildLog(time.Now())
+	pushCmd := model.Cmd{Env: cmd.Env(&user), CommandLineString: fmt.Sprintf(
+		pushCommandLineString, settings.GitSSH, keyPath), Stderr: log}
+	sess, err := cmd.StartCmd(pushCmd)
+	Expect(err).NotTo(HaveOccurred())
+	return sess, log
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/fake"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// hooks let the holder of a single key push to any app, and number each push as the next release
+// of the app.
+type hooks struct {
+	key     ssh.PublicKey
+	mu      sync.Mutex
+	version int
+}
+
+func (h *hooks) UserForKey(key ssh.PublicKey) (string, bool) {
+	return "test-1", bytes.Equal(key.Marshal(), h.key.Marshal())
+}
+
+func (h *hooks) CanPush(username, app string) bool {
+	return true
+}
+
+func (h *hooks) Deploy(username, app, sha string, procfile map[string]string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.version++
+	return h.version + 1, nil
+}
+
+var _ = Describe("git push helpers", func() {
+
+	var (
+		b       *fake.Builder
+		dir     string
+		keyPath string
+		wd      string
+		saved   struct{ home, gitSSH string }
+		user    = model.User{Username: "test-1"}
+	)
+
+	// git executes git in the app's working copy, failing the spec if it fails.
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=e2e", "-c", "user.email=e2e@deis.io"}, args...)...)
+		cmd.Dir = filepath.Join(dir, "app")
+		out, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "git-helpers-test")
+		Expect(err).NotTo(HaveOccurred())
+		saved.home, saved.gitSSH = settings.TestHome, settings.GitSSH
+		settings.TestHome = dir
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		keyPath = filepath.Join(dir, "id_ecdsa")
+		Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+		public, err := ssh.NewPublicKey(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The same wrapper as the suite writes, less the flags for reaching a real builder.
+		settings.GitSSH = filepath.Join(dir, "git-ssh")
+		script := "#!/bin/sh\nexec ssh -o BatchMode=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null " +
+			"-o IdentitiesOnly=yes -i \"$GIT_KEY\" \"$@\"\n"
+		Expect(ioutil.WriteFile(settings.GitSSH, []byte(script), 0777)).To(Succeed())
+
+		b, err = fake.NewBuilder("127.0.0.1:0", &hooks{key: public})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := exec.Command("git", "init", "--quiet", filepath.Join(dir, "app")).CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+		git("checkout", "--quiet", "-b", "master")
+		Expect(ioutil.WriteFile(filepath.Join(dir, "app", "index.html"), []byte("Powered by Deis"), 0644)).To(Succeed())
+		git("add", "index.html")
+		git("commit", "--quiet", "-m", "add index.html")
+		git("remote", "add", "deis", fmt.Sprintf("ssh://git@%s/myapp.git", b.Addr))
+
+		// The helpers push from the current directory, as the specs do once they have cloned an app.
+		wd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(filepath.Join(dir, "app"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(wd)
+		b.Close()
+		settings.TestHome, settings.GitSSH = saved.home, saved.gitSSH
+		os.RemoveAll(dir)
+	})
+
+	Specify("a push interrupted during its build holds up the next until the build ends", func() {
+		b.BuildTimeout = 2 * time.Second
+		b.Script("myapp", fake.Build{Failure: fake.Timeout})
+		PushWithInterrupt(user, keyPath)
+		Expect(b.Pushing("myapp")).To(BeTrue())
+
+		// The interrupted push was received before its build began, so there is nothing left to push.
+		b.Script("myapp", fake.DefaultBuild)
+		PushUntilResult(user, keyPath, model.CmdResult{Err: []byte("Everything up-to-date"), ExitCode: 0})
+		Expect(b.Pushing("myapp")).To(BeFalse())
+	})
+
+	Specify("a push made while another is being built is retried until that build is done", func() {
+		b.Script("myapp", fake.Build{Output: []string{"-----> one", "-----> two", "-----> three"}, Interval: time.Second})
+		sess := StartPush(user, keyPath)
+		Eventually(sess.Err).Should(Say(fake.BuildStartMessage))
+
+		second := StartPush(user, keyPath)
+		Eventually(second.Err).Should(Say("fatal: remote error: " + fake.PushOngoingMessage))
+		Eventually(second).Should(Exit(128))
+
+		PushUntilResult(user, keyPath, model.CmdResult{Err: []byte("Everything up-to-date"), ExitCode: 0})
+		Eventually(sess).Should(Exit(0))
+		Expect(sess.Err).To(Say(`Done, myapp:v2 deployed to Workflow`))
+	})
+
+})
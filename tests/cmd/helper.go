@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,28 +11,65 @@ import (
 	"time"
 
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/poll"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	"github.com/onsi/ginkgo"
-	"github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
 )
 
 // The functions in this file implement a generic command execution and inspection framework.
 
 // Execute executes the command generated by fmt.Sprintf(cmdLine, args...) and returns its output.
+// The command is killed if it runs for longer than settings.DefaultEventuallyTimeout.
 func Execute(cmdLine string, args ...interface{}) (string, error) {
-	var cmd *exec.Cmd
-	shCommand := fmt.Sprintf(cmdLine, args...)
+	return ExecuteCmd(model.Cmd{CommandLineString: fmt.Sprintf(cmdLine, args...)})
+}
+
+// ExecuteContext is like Execute, but the command is killed when ctx expires.
+func ExecuteContext(ctx context.Context, cmdLine string, args ...interface{}) (string, error) {
+	return ExecuteCmdContext(ctx, model.Cmd{CommandLineString: fmt.Sprintf(cmdLine, args...)})
+}
+
+// ExecuteCmd executes the provided model.Cmd to completion and returns its combined output. The
+// command is killed if it runs for longer than settings.DefaultEventuallyTimeout.
+func ExecuteCmd(command model.Cmd) (string, error) {
+	ctx, cancel := DefaultContext()
+	defer cancel()
+	return ExecuteCmdContext(ctx, command)
+}
 
+// ExecuteCmdContext executes the provided model.Cmd to completion and returns its combined output.
+// If ctx expires first, the command's process group is killed and a *TimeoutError is returned
+// along with whatever output the command had written.
+func ExecuteCmdContext(ctx context.Context, command model.Cmd) (string, error) {
 	if settings.Debug {
-		fmt.Println(shCommand)
+		fmt.Println(command.String())
 	}
 
-	cmd = exec.Command("/bin/sh", "-c", shCommand)
-	outputBytes, err := cmd.CombinedOutput()
+	cmd := execCommand(command)
+	var outputBuf bytes.Buffer
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &outputBuf
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan struct{})
+		timedOut := make(chan *TimeoutError, 1)
+		watch(ctx, cmd, command.String(), start, done, func(timeoutErr *TimeoutError) {
+			timedOut <- timeoutErr
+		})
+		err = cmd.Wait()
+		close(done)
+		select {
+		case timeoutErr := <-timedOut:
+			err = timeoutErr
+		default:
+		}
+	}
+	recordExecution(command.String(), cmd, start, outputBuf.String(), err)
 
-	output := string(outputBytes)
+	output := outputBuf.String()
 
 	if settings.Debug {
 		fmt.Println(output)
@@ -40,80 +79,150 @@ func Execute(cmdLine string, args ...interface{}) (string, error) {
 }
 
 // Start executes the provided command (often a `deis` command of some sort) as the specified user
-// (by selecting the corresponding profile). Optional arguments may also be supplied that will be
-// substituted into the provided command using fmt.Sprintf(...).
+// (by selecting the corresponding profile within that user's home directory). Optional arguments
+// may also be supplied that will be substituted into the provided command using fmt.Sprintf(...).
+// The command is killed if it runs for longer than settings.MaxEventuallyTimeout.
 func Start(cmdLine string, user *model.User, args ...interface{}) (*gexec.Session, error) {
-	if user != nil {
-		envVars := append(os.Environ(), fmt.Sprintf("DEIS_PROFILE=%s", user.Username))
-		ourCommand := model.Cmd{Env: envVars, CommandLineString: fmt.Sprintf(cmdLine, args...)}
-		return StartCmd(ourCommand)
-	}
-	ourCommand := model.Cmd{Env: os.Environ(), CommandLineString: fmt.Sprintf(cmdLine, args...)}
+	ourCommand := model.Cmd{Env: Env(user), CommandLineString: fmt.Sprintf(cmdLine, args...)}
 	return StartCmd(ourCommand)
 }
 
+// StartContext is like Start, but the command is killed when ctx expires.
+func StartContext(ctx context.Context, cmdLine string, user *model.User, args ...interface{}) (*gexec.Session, error) {
+	ourCommand := model.Cmd{Env: Env(user), CommandLineString: fmt.Sprintf(cmdLine, args...)}
+	return StartCmdContext(ctx, ourCommand)
+}
+
+// Command returns a model.Cmd that executes the named binary with the specified arguments as the
+// specified user, without interpretation by a shell.
+func Command(user *model.User, name string, args ...string) model.Cmd {
+	return model.Cmd{Env: Env(user), Path: name, Args: args}
+}
+
+// Deis starts the deis CLI with the specified arguments as the specified user. Unlike Start, it
+// passes each argument to the CLI exactly as given, so values containing spaces, quotes, dollar
+// signs or wildcards need no escaping.
+func Deis(user *model.User, args ...string) (*gexec.Session, error) {
+	return StartCmd(Command(user, "deis", args...))
+}
+
 // StartCmd executes the provided model.Command. It is used primarily by the Start function
 // (above), but is also used directly in scenarios where tests must execute fine-grained control
-// over the environment in which the command will be executed.
+// over the environment in which the command will be executed. The command is killed if it runs
+// for longer than settings.MaxEventuallyTimeout.
 func StartCmd(command model.Cmd) (*gexec.Session, error) {
-	execCmd := exec.Command("/bin/sh", "-c", command.CommandLineString)
-	execCmd.Env = command.Env
-	io.WriteString(ginkgo.GinkgoWriter, fmt.Sprintf("$ %s\n", command.CommandLineString))
-	return gexec.Start(execCmd, ginkgo.GinkgoWriter, ginkgo.GinkgoWriter)
+	ctx, cancel := MaxContext()
+	sess, err := StartCmdContext(ctx, command)
+	if err != nil {
+		cancel()
+		return sess, err
+	}
+	go func() {
+		<-sess.Exited
+		cancel()
+	}()
+	return sess, err
 }
 
-// Retry runs the provided <cmd> repeatedly, once a second up to the
-// supplied <timeout> until the <cmd> result contains the <expectedResult>
-// An example use of this utility would be curl-ing a url and waiting
-// until the response code matches the expected response.
-// TODO: https://github.com/deis/workflow-e2e/issues/240
-func Retry(command model.Cmd, expectedResult string, timeout int) bool {
-	var result string
-	fmt.Fprintf(ginkgo.GinkgoWriter, "Waiting up to %d seconds for `%s` to return %s...\n", timeout, command.CommandLineString, expectedResult)
-	for i := 0; i < timeout; i++ {
-		sess, err := StartCmd(command)
-		gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		result = string(sess.Wait().Out.Contents())
-		if strings.Contains(result, expectedResult) {
-			return true
+// StartCmdContext is like StartCmd, but if ctx expires before the command exits, the command's
+// process group is killed, and the resulting *TimeoutError is written to the session's stderr
+// and recorded in the report.
+func StartCmdContext(ctx context.Context, command model.Cmd) (*gexec.Session, error) {
+	execCmd := execCommand(command)
+	io.WriteString(ginkgo.GinkgoWriter, fmt.Sprintf("$ %s\n", command.String()))
+	start := time.Now()
+	var errWriter io.Writer = ginkgo.GinkgoWriter
+	if command.Stderr != nil {
+		errWriter = io.MultiWriter(ginkgo.GinkgoWriter, command.Stderr)
+	}
+	sess, err := gexec.Start(execCmd, ginkgo.GinkgoWriter, errWriter)
+	recordSession(command.String(), execCmd.Env, sess, err)
+	if err != nil {
+		return sess, err
+	}
+	watch(ctx, execCmd, command.String(), start, sess.Exited, func(timeoutErr *TimeoutError) {
+		fmt.Fprintf(ginkgo.GinkgoWriter, "%s\n", timeoutErr)
+		fmt.Fprintf(sess.Err, "%s\n", timeoutErr)
+		recordTimeout(sess, timeoutErr)
+	})
+	return sess, err
+}
+
+// execCommand returns an *exec.Cmd that executes the provided model.Cmd: directly if it names a
+// binary, or else by way of /bin/sh.
+func execCommand(command model.Cmd) *exec.Cmd {
+	var execCmd *exec.Cmd
+	if command.Path != "" {
+		execCmd = exec.Command(command.Path, command.Args...)
+	} else {
+		execCmd = exec.Command("/bin/sh", "-c", command.CommandLineString)
+	}
+	execCmd.Env = command.Env
+	if len(command.EnvOverrides) > 0 {
+		if execCmd.Env == nil {
+			execCmd.Env = os.Environ()
+		}
+		for _, v := range command.EnvOverrides {
+			kv := strings.SplitN(v, "=", 2)
+			if len(kv) == 2 {
+				execCmd.Env = SetEnv(execCmd.Env, kv[0], kv[1])
+			}
 		}
-		time.Sleep(1 * time.Second)
 	}
-	fmt.Fprintf(ginkgo.GinkgoWriter, "FAIL: '%s' does not match expected result of '%s'\n", result, expectedResult)
-	return false
+	execCmd.Dir = command.Dir
+	if command.Stdin != nil {
+		execCmd.Stdin = bytes.NewReader(command.Stdin)
+	}
+	setProcessGroup(execCmd)
+	return execCmd
 }
 
-// RetryUntilResult runs the provided cmd repeatedly, once every period,
-// up to the supplied timeout until the cmd result matches the supplied
-// expectedCmdResult
-func RetryUntilResult(command model.Cmd, expectedCmdResult model.CmdResult, period, timeout time.Duration) bool {
-	var actualCmdResult model.CmdResult
+// PollCmd runs the provided command repeatedly, as the poller dictates, until its result satisfies
+// every one of preds, and returns that result. No run may outlive the poller's timeout. If the
+// poller gives up first, it returns the last result and a *poll.Failure describing every run. The
+// stdout, stderr and exit code of each run are the Out, Err and Code of its poll.Result.
+func PollCmd(command model.Cmd, poller poll.Poller, preds ...poll.Predicate) (poll.Result, error) {
+	if poller.Log == nil {
+		poller.Log = ginkgo.GinkgoWriter
+	}
+	return poller.Until(fmt.Sprintf("`%s`", command.String()), func(ctx context.Context) poll.Result {
+		sess, err := StartCmdContext(ctx, command)
+		if err != nil {
+			return poll.Result{Error: err}
+		}
+		<-sess.Exited
+		return poll.Result{Out: sess.Out.Contents(), Err: sess.Err.Contents(), Code: sess.ExitCode()}
+	}, preds...)
+}
 
-	fmt.Fprintf(ginkgo.GinkgoWriter,
-		"Waiting up to %d seconds for `%s` to return expected cmdResult %s...\n",
-		int(timeout.Seconds()), command.CommandLineString, expectedCmdResult.String())
+// Satisfies returns a predicate that holds for results that satisfy the expected model.CmdResult
+// (see model.CmdResult.Satisfies).
+func Satisfies(expected model.CmdResult) poll.Predicate {
+	return poll.All(
+		poll.ExitCode(expected.ExitCode),
+		poll.Contains(string(expected.Out)),
+		poll.StderrContains(string(expected.Err)),
+	)
+}
 
-	tck := time.NewTicker(period)
-	tmr := time.NewTimer(timeout)
-	defer tck.Stop()
-	defer tmr.Stop()
-	for {
-		select {
-		case <-tck.C:
-			sess, err := StartCmd(command)
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-			sessWait := sess.Wait()
-			actualCmdResult = model.CmdResult{
-				Out:      sessWait.Out.Contents(),
-				Err:      sessWait.Err.Contents(),
-				ExitCode: sessWait.ExitCode(),
-			}
-			if actualCmdResult.Satisfies(expectedCmdResult) {
-				return true
-			}
-		case <-tmr.C:
-			fmt.Fprintf(ginkgo.GinkgoWriter, "FAIL: Actual cmdResult '%v' does not match expected cmdResult '%v'\n", actualCmdResult, expectedCmdResult)
-			return false
-		}
+// Retry runs the provided command repeatedly, once a second up to the supplied timeout, until its
+// stdout contains expectedResult. It is a shorthand for PollCmd, and so every run is recorded in
+// the report.
+func Retry(command model.Cmd, expectedResult string, timeout time.Duration) bool {
+	_, err := PollCmd(command, poll.Every(time.Second, timeout), poll.Contains(expectedResult))
+	if err != nil {
+		fmt.Fprintf(ginkgo.GinkgoWriter, "FAIL: %s\n", err)
+	}
+	return err == nil
+}
+
+// RetryUntilResult runs the provided command repeatedly, once every period up to the supplied
+// timeout, until its result satisfies expectedCmdResult (see Satisfies). It is a shorthand for
+// PollCmd, and so every run is recorded in the report.
+func RetryUntilResult(command model.Cmd, expectedCmdResult model.CmdResult, period, timeout time.Duration) bool {
+	_, err := PollCmd(command, poll.Every(period, timeout), Satisfies(expectedCmdResult))
+	if err != nil {
+		fmt.Fprintf(ginkgo.GinkgoWriter, "FAIL: %s\n", err)
 	}
+	return err == nil
 }
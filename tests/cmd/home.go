@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"github.com/onsi/gomega"
+)
+
+// The functions in this file give the users registered by each spec private home directories, so
+// that specs running in parallel can never clobber one another's profiles, SSH keys or git
+// configuration.
+
+// Home returns the directory used as $HOME when executing commands as the specified user. Users
+// that have no home of their own, such as model.Admin, share settings.TestHome.
+func Home(user model.User) string {
+	if user.Home != "" {
+		return user.Home
+	}
+	return settings.TestHome
+}
+
+// CreateHome creates a private home directory for the specified user beneath settings.SpecHome,
+// containing an empty profile store and SSH directory, and a git configuration that names the
+// user. Outside of a spec, when there is no settings.SpecHome, the user is left to share
+// settings.TestHome.
+func CreateHome(user *model.User) {
+	if settings.SpecHome == "" {
+		return
+	}
+	home := path.Join(settings.SpecHome, user.Username)
+	gomega.Expect(os.MkdirAll(path.Join(home, ".deis"), 0700)).To(gomega.Succeed())
+	gomega.Expect(os.MkdirAll(path.Join(home, ".ssh"), 0700)).To(gomega.Succeed())
+	gitConfig := fmt.Sprintf("[user]\n\tname = %s\n\temail = %s\n", user.Username, user.Email)
+	gomega.Expect(ioutil.WriteFile(path.Join(home, ".gitconfig"), []byte(gitConfig), 0644)).To(gomega.Succeed())
+	user.Home = home
+}
+
+// Env returns the environment in which to execute commands as the specified user: that of this
+// process, with $HOME set to the user's home and $DEIS_PROFILE naming the user's profile. If user
+// is nil, it returns the environment of this process unchanged.
+func Env(user *model.User) []string {
+	env := os.Environ()
+	if user == nil {
+		return env
+	}
+	env = SetEnv(env, "HOME", Home(*user))
+	return SetEnv(env, "DEIS_PROFILE", user.Username)
+}
+
+// SetEnv returns a copy of env, a list of "key=value" strings, in which key is set to value.
+func SetEnv(env []string, key, value string) []string {
+	result := []string{}
+	for _, v := range env {
+		if !strings.HasPrefix(v, key+"=") {
+			result = append(result, v)
+		}
+	}
+	return append(result, key+"="+value)
+}
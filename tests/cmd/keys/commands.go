@@ -1,14 +1,14 @@
 package keys
 
 import (
-	"fmt"
-	"math/rand"
 	"os"
 	"path"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/poll"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/gomega"
@@ -19,29 +19,108 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis keys` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
-// Add executes `deis keys:add` as the specified user to add a new key to that user's account.
+// Driver implements the `deis keys` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Add(user model.User, keyName string, keyPath string)
+	Remove(user model.User, keyName string)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Add generates a new key and adds it to the specified user's account. It returns the name of the
+// key and the path to its private half.
 func Add(user model.User) (string, string) {
-	keyName, keyPath := createKey()
+	keyName, keyPath := createKey(user)
+	model.Created.Track(model.KeyResource(user, keyName))
+	Current().Add(user, keyName, keyPath)
+	WaitForPropagation(user, keyName, keyPath)
+	return keyName, keyPath
+}
+
+// Remove removes the specified key from the specified user's account.
+func Remove(user model.User, keyName string) {
+	Current().Remove(user, keyName)
+	model.Created.Forget(model.KeyResource(user, keyName))
+}
+
+// Add executes `deis keys:add` as the specified user to add the specified key to that user's
+// account.
+func (CLI) Add(user model.User, keyName string, keyPath string) {
 	sess, err := cmd.Start("deis keys:add %s.pub", &user, keyPath)
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("Uploading %s.pub to deis... done", keyName))
 	Expect(err).NotTo(HaveOccurred())
 	Eventually(sess).Should(Exit(0))
-	time.Sleep(5 * time.Second) // Wait for the key to propagate before continuing
-	return keyName, keyPath
 }
 
 // Remove executes `deis keys:remove` as the specified user to remove the specified key from that
 // user's account.
-func Remove(user model.User, keyName string) {
+func (CLI) Remove(user model.User, keyName string) {
 	sess, err := cmd.Start("deis keys:remove %s", &user, keyName)
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("Removing %s SSH Key... done", keyName))
 	Eventually(sess).Should(Exit(0))
 	Expect(err).NotTo(HaveOccurred())
 }
 
-func createKey() (string, string) {
-	keyName := fmt.Sprintf("deiskey-%v", rand.Intn(1000))
-	sshHome := path.Join(settings.TestHome, ".ssh")
+// WaitForPropagation waits for the builder to accept the specified key, which is when an SSH
+// handshake with it succeeds, failing the current spec with a description of every attempt if
+// that does not happen within settings.DefaultEventuallyTimeout.
+func WaitForPropagation(user model.User, keyName string, keyPath string) {
+	// The builder has no shell to offer, so once the key is accepted, it refuses ssh's request for
+	// one, and ssh reports as much on stderr.
+	handshake := cmd.Command(&user, settings.GitSSH, "-T", "-p", strconv.Itoa(model.BuilderSSHPort),
+		"-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "git@"+model.BuilderHost())
+	handshake.EnvOverrides = []string{"GIT_KEY=" + keyPath}
+	_, err := cmd.PollCmd(handshake, poll.New(settings.DefaultEventuallyTimeout), authenticated)
+	Expect(err).NotTo(HaveOccurred(), "key %s did not propagate to the builder", keyName)
+}
+
+// shellRefused is how ssh reports that the builder refused its request for a shell, which it only
+// makes once it has authenticated.
+const shellRefused = "shell request failed on channel"
+
+// unreachable are the prefixes of the lines with which ssh reports that it never got as far as authenticating
+// with the server.
+var unreachable = []string{"ssh: ", "kex_exchange_identification", "Connection closed by", "Connection reset by"}
+
+// authenticated holds for the results of ssh sessions that authenticated with the server, in which
+// the builder went on to refuse ssh's request for a shell. The reason it gives otherwise is why
+// ssh did not get that far, if it said.
+func authenticated(r poll.Result) (bool, string) {
+	stderr := strings.TrimSpace(string(r.Err))
+	if strings.Contains(stderr, shellRefused) {
+		return true, ""
+	}
+	lines := strings.Split(stderr, "\n")
+	last := lines[len(lines)-1]
+	if strings.Contains(stderr, "Permission denied (publickey") {
+		return false, "the builder rejected the key: " + last
+	}
+	for _, line := range lines {
+		for _, prefix := range unreachable {
+			if strings.HasPrefix(line, prefix) {
+				return false, "ssh could not reach the builder: " + line
+			}
+		}
+	}
+	return false, "the builder did not refuse a shell: " + last
+}
+
+func createKey(user model.User) (string, string) {
+	keyName := model.NewKeyName()
+	sshHome := path.Join(cmd.Home(user), ".ssh")
 	os.MkdirAll(sshHome, 0777)
 	keyPath := path.Join(sshHome, keyName)
 	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
@@ -0,0 +1,25 @@
+package keys
+
+import (
+	"io/ioutil"
+	"strings"
+
+	sdkkeys "github.com/deis/controller-sdk-go/keys"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Add uploads the public half of the specified key to the specified user's account.
+func (SDK) Add(user model.User, keyName string, keyPath string) {
+	pubKey, err := ioutil.ReadFile(keyPath + ".pub")
+	Expect(err).NotTo(HaveOccurred())
+	_, err = sdkkeys.New(cmd.Client(user), keyName, strings.TrimSpace(string(pubKey)))
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Remove removes the specified key from the specified user's account.
+func (SDK) Remove(user model.User, keyName string) {
+	Expect(sdkkeys.Delete(cmd.Client(user), keyName)).To(Succeed())
+}
@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/cmd/run"
+	probe "github.com/deis/workflow-e2e/tests/http"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// The functions in this file implement SUCCESS CASES for `deis logs`, and inject markers into an
+// app's logs so that specs can follow them through the logging pipeline.
+
+// Driver implements the `deis logs` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Get(user model.User, app model.App, lines int) []parse.LogLine
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Get returns the last lines of the specified app's logs as the specified user, or as many as the
+// logger keeps if lines is 0.
+func Get(user model.User, app model.App, lines int) []parse.LogLine {
+	return Current().Get(user, app, lines)
+}
+
+// Get executes `deis logs` as the specified user.
+func (CLI) Get(user model.User, app model.App, lines int) []parse.LogLine {
+	sess, err := cmd.Deis(&user, logsArgs(app.Name, lines)...)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess).Should(Exit(0))
+	return parse.Logs(sess.Out.Contents())
+}
+
+func logsArgs(appName string, lines int) []string {
+	args := []string{"logs", "--app=" + appName}
+	if lines > 0 {
+		args = append(args, "--lines="+strconv.Itoa(lines))
+	}
+	return args
+}
+
+// Marker is a line written to an app's logs by a spec.
+type Marker struct {
+	Message string
+	// ProcessType is the type of the process expected to write the line.
+	ProcessType string
+	// Written is when the line was written, or just before.
+	Written time.Time
+}
+
+// Received describes a Marker that was found in an app's logs.
+type Received struct {
+	Marker Marker
+	Line   parse.LogLine
+	// Latency is how long after the marker was written it was first seen in the output of
+	// `deis logs`. It overstates the true latency by up to a second, since the logs are polled once
+	// a second.
+	Latency time.Duration
+}
+
+// NewMarker returns a message that is unique to this run of the suite, and so will be found in an
+// app's logs only if a spec put it there.
+func NewMarker() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	Expect(err).NotTo(HaveOccurred())
+	return "e2e-marker-" + hex.EncodeToString(b)
+}
+
+// EmitByRun writes a new marker to the specified app's logs by running echo in its environment as
+// the specified user (see run.Run). The marker is written by a one-off "run" process.
+func EmitByRun(user model.User, app model.App) Marker {
+	marker := Marker{Message: NewMarker(), ProcessType: "run", Written: time.Now()}
+	result := run.Run(user, app, "echo "+marker.Message)
+	Expect(result.ExitCode).To(Equal(0), string(result.Output))
+	return marker
+}
+
+// EmitByRequest writes a new marker to the specified app's logs by requesting its root URL with the
+// marker in the log query parameter, which fixtures whose Manifest has LogsRequests set write to
+// stdout. The marker is written by whichever web process serves the request.
+func EmitByRequest(app model.App) Marker {
+	marker := Marker{Message: NewMarker(), ProcessType: "web", Written: time.Now()}
+	get := probe.Get(app.URL + "/?log=" + url.QueryEscape(marker.Message))
+	probe.ExpectEventually(get, probe.Expected{StatusCode: http.StatusOK})
+	return marker
+}
+
+// ExpectEventually polls `deis logs` with the specified --lines as the specified user until the
+// marker appears in the specified app's logs, written by a process of the marker's type, and
+// returns what was received. It fails the current spec with a description of every attempt if the
+// marker does not appear within settings.MaxEventuallyTimeout, and fails it if the marker appeared
+// later than settings.LogsMaxLatency after it was written.
+func ExpectEventually(user model.User, app model.App, marker Marker, lines int) Received {
+	logs := cmd.Command(&user, "deis", logsArgs(app.Name, lines)...)
+	poller := poll.Every(time.Second, settings.MaxEventuallyTimeout)
+	result, err := cmd.PollCmd(logs, poller, poll.ExitCode(0), containing(marker.Message))
+	seen := time.Now()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred(), "%s never appeared in the logs of %s", marker.Message, app.Name)
+
+	received := Received{Marker: marker, Latency: seen.Sub(marker.Written)}
+	for _, line := range parse.Logs(result.Out) {
+		if line.Message == marker.Message {
+			received.Line = line
+		}
+	}
+	fmt.Fprintf(GinkgoWriter, "%s appeared in the logs of %s after %s, written by %s\n",
+		marker.Message, app.Name, received.Latency, received.Line.Process)
+	ExpectWithOffset(1, received.Line.ProcessType(app.Name)).To(Equal(marker.ProcessType),
+		"%s was written by %s, not a %s process", marker.Message, received.Line.Process, marker.ProcessType)
+	ExpectWithOffset(1, received.Latency).To(BeNumerically("<=", settings.LogsMaxLatency),
+		"%s took longer than LOGS_MAX_LATENCY to reach the logs of %s", marker.Message, app.Name)
+	return received
+}
+
+// containing holds for the output of `deis logs` when one of its lines is exactly the specified
+// message.
+func containing(message string) poll.Predicate {
+	return func(r poll.Result) (bool, string) {
+		for _, line := range parse.Logs(r.Out) {
+			if line.Message == message {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("%s is not among %d lines", message, strings.Count(string(r.Out), "\n"))
+	}
+}
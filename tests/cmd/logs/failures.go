@@ -0,0 +1,39 @@
+package logs
+
+import (
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for `deis logs`. Each expects the subcommand
+// to fail with the specified controller-sdk-go error, such as deis.ErrNotFound.
+
+// FailureDriver implements the `deis logs` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	GetExpectingError(user model.User, appName string, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// GetExpectingError attempts to get the logs of the specified app as the specified user, expecting
+// the expected error. The logs of an app that has been destroyed cannot be retrieved, since the
+// controller no longer knows the app.
+func GetExpectingError(user model.User, appName string, expected error) {
+	CurrentFailures().GetExpectingError(user, appName, expected)
+}
+
+// GetExpectingError executes `deis logs` as the specified user, expecting it to fail.
+func (CLI) GetExpectingError(user model.User, appName string, expected error) {
+	sess, err := cmd.Deis(&user, logsArgs(appName, 0)...)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
@@ -0,0 +1,23 @@
+package logs
+
+import (
+	sdkapps "github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+
+	. "github.com/onsi/gomega"
+)
+
+// Get returns the last lines of the specified app's logs as the specified user.
+func (SDK) Get(user model.User, app model.App, lines int) []parse.LogLine {
+	logs, err := sdkapps.Logs(cmd.Client(user), app.Name, lines)
+	Expect(err).NotTo(HaveOccurred())
+	return parse.Logs([]byte(logs))
+}
+
+// GetExpectingError attempts to get an app's logs as the specified user, expecting it to fail.
+func (SDK) GetExpectingError(user model.User, appName string, expected error) {
+	_, err := sdkapps.Logs(cmd.Client(user), appName, 0)
+	cmd.ExpectSDKError(err, expected)
+}
@@ -13,9 +13,42 @@ import (
 // The functions in this file implement SUCCESS CASES for commonly used `deis perms` subcommands.
 // This allows each of these to be re-used easily in multiple contexts.
 
+// Driver implements the `deis perms` SUCCESS CASES either by executing the deis CLI or by calling
+// the controller directly.
+type Driver interface {
+	Create(user model.User, app model.App, grantUser model.User)
+	Delete(user model.User, app model.App, revokeUser model.User)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Create grants permissions on the specified app to a second user, as the specified user.
+func Create(user model.User, app model.App, grantUser model.User) {
+	model.Created.Track(model.PermResource(user, app, grantUser))
+	Current().Create(user, app, grantUser)
+}
+
+// Delete revokes permissions on the specified app from a second user, as the specified user.
+func Delete(user model.User, app model.App, revokeUser model.User) {
+	Current().Delete(user, app, revokeUser)
+	model.Created.Forget(model.PermResource(user, app, revokeUser))
+}
+
 // Create executes `deis perms:create` as the specified user to grant permissions on the specified
 // app to a second user.
-func Create(user model.User, app model.App, grantUser model.User) {
+func (CLI) Create(user model.User, app model.App, grantUser model.User) {
 	sess, err := cmd.Start("deis perms:create %s --app=%s", &user, grantUser.Username, app.Name)
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("Adding %s to %s collaborators... done\n", grantUser.Username, app.Name))
 	Expect(err).NotTo(HaveOccurred())
@@ -24,7 +57,7 @@ func Create(user model.User, app model.App, grantUser model.User) {
 
 // Delete executes `deis perms:delete` as the specified user to revoke permissions on the specified
 // app from a second user.
-func Delete(user model.User, app model.App, revokeUser model.User) {
+func (CLI) Delete(user model.User, app model.App, revokeUser model.User) {
 	sess, err := cmd.Start("deis perms:delete %s --app=%s", &user, revokeUser.Username, app.Name)
 	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("Removing %s from %s collaborators... done", revokeUser.Username, app.Name))
 	Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,65 @@
+package perms
+
+import (
+	sdkperms "github.com/deis/controller-sdk-go/perms"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file implement FAILURE CASES for commonly used `deis perms` subcommands.
+// Each expects the subcommand to fail with the specified controller-sdk-go error, such as
+// deis.ErrNotFound, and verifies that it left no trace.
+
+// FailureDriver implements the `deis perms` FAILURE CASES either by executing the deis CLI or by
+// calling the controller directly.
+type FailureDriver interface {
+	CreateExpectingError(user model.User, app model.App, grantUser model.User, expected error)
+	DeleteExpectingError(user model.User, app model.App, revokeUser model.User, expected error)
+}
+
+// CurrentFailures returns the FailureDriver selected by $E2E_DRIVER.
+func CurrentFailures() FailureDriver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// CreateExpectingError attempts to grant permissions on the specified app to a second user, as
+// the specified user, expecting the expected error, and verifies that the app's collaborators
+// were left as they were.
+func CreateExpectingError(user model.User, app model.App, grantUser model.User, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(app))()
+	CurrentFailures().CreateExpectingError(user, app, grantUser, expected)
+}
+
+// DeleteExpectingError attempts to revoke permissions on the specified app from a second user, as
+// the specified user, expecting the expected error, and verifies that the app's collaborators
+// were left as they were.
+func DeleteExpectingError(user model.User, app model.App, revokeUser model.User, expected error) {
+	defer cmd.ExpectUnchanged(snapshot(app))()
+	CurrentFailures().DeleteExpectingError(user, app, revokeUser, expected)
+}
+
+// snapshot returns a function that lists the specified app's collaborators, as model.Admin.
+func snapshot(app model.App) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return sdkperms.List(cmd.AdminClient(), app.Name)
+	}
+}
+
+// CreateExpectingError executes `deis perms:create` as the specified user, expecting it to fail.
+func (CLI) CreateExpectingError(user model.User, app model.App, grantUser model.User, expected error) {
+	sess, err := cmd.Deis(&user, "perms:create", grantUser.Username, "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
+
+// DeleteExpectingError executes `deis perms:delete` as the specified user, expecting it to fail.
+func (CLI) DeleteExpectingError(user model.User, app model.App, revokeUser model.User, expected error) {
+	sess, err := cmd.Deis(&user, "perms:delete", revokeUser.Username, "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	cmd.ExpectCLIError(sess, expected)
+}
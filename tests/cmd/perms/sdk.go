@@ -0,0 +1,31 @@
+package perms
+
+import (
+	sdkperms "github.com/deis/controller-sdk-go/perms"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/gomega"
+)
+
+// Create grants permissions on the specified app to a second user, as the specified user.
+func (SDK) Create(user model.User, app model.App, grantUser model.User) {
+	Expect(sdkperms.New(cmd.Client(user), app.Name, grantUser.Username)).To(Succeed())
+}
+
+// Delete revokes permissions on the specified app from a second user, as the specified user.
+func (SDK) Delete(user model.User, app model.App, revokeUser model.User) {
+	Expect(sdkperms.Delete(cmd.Client(user), app.Name, revokeUser.Username)).To(Succeed())
+}
+
+// CreateExpectingError attempts to grant permissions on the specified app to a second user, as
+// the specified user, expecting it to fail.
+func (SDK) CreateExpectingError(user model.User, app model.App, grantUser model.User, expected error) {
+	cmd.ExpectSDKError(sdkperms.New(cmd.Client(user), app.Name, grantUser.Username), expected)
+}
+
+// DeleteExpectingError attempts to revoke permissions on the specified app from a second user, as
+// the specified user, expecting it to fail.
+func (SDK) DeleteExpectingError(user model.User, app model.App, revokeUser model.User, expected error) {
+	cmd.ExpectSDKError(sdkperms.Delete(cmd.Client(user), app.Name, revokeUser.Username), expected)
+}
@@ -0,0 +1,232 @@
+package ps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdkbuilds "github.com/deis/controller-sdk-go/builds"
+	sdkreleases "github.com/deis/controller-sdk-go/releases"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// The functions in this file implement SUCCESS CASES for commonly used `deis ps` subcommands.
+// This allows each of these to be re-used easily in multiple contexts.
+
+// CmdType is the type of the single process run by a release whose build has no Procfile, such as
+// one created by `deis pull` or by a git push of a Dockerfile app without one.
+const CmdType = "cmd"
+
+// Driver implements the `deis ps` SUCCESS CASES either by executing the deis CLI or by calling the
+// controller directly.
+type Driver interface {
+	List(user model.User, app model.App) []parse.Process
+	Scale(user model.User, app model.App, procType string, count int)
+	Restart(user model.User, app model.App, procType, name string) []parse.Process
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Types returns the process types of the specified app's current release, sorted: the types
+// declared by the Procfile of the build it runs or, if that build has none, CmdType. After a
+// rollback, that is the build of the release rolled back to, not the latest build.
+func Types(user model.User, app model.App) []string {
+	client := cmd.Client(user)
+	releases, _, err := sdkreleases.List(client, app.Name, 1)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(releases).NotTo(BeEmpty(), "%s has no releases", app.Name)
+	current := releases[0]
+	Expect(current.Build).NotTo(BeEmpty(), "%s has not been deployed", app.Name)
+
+	builds, _, err := sdkbuilds.List(client, app.Name, 0)
+	Expect(err).NotTo(HaveOccurred())
+	for _, build := range builds {
+		if build.UUID != current.Build {
+			continue
+		}
+		if len(build.Procfile) == 0 {
+			return []string{CmdType}
+		}
+		var types []string
+		for procType := range build.Procfile {
+			types = append(types, procType)
+		}
+		sort.Strings(types)
+		return types
+	}
+	Fail(fmt.Sprintf("the build of %s v%d is not listed", app.Name, current.Version))
+	return nil
+}
+
+// List returns the specified app's processes of the specified type, or of every type if procType
+// is empty, that are up, sorted by name.
+func List(user model.User, app model.App, procType string) []parse.Process {
+	var procs []parse.Process
+	for _, proc := range Current().List(user, app) {
+		if proc.State == "up" && strings.HasPrefix(proc.Name, app.Name+"-") &&
+			(procType == "" || proc.Type == procType) {
+			procs = append(procs, proc)
+		}
+	}
+	sort.Sort(byName(procs))
+	return procs
+}
+
+type byName []parse.Process
+
+func (p byName) Len() int           { return len(p) }
+func (p byName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+func (p byName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Names returns the names of the specified processes, in the same order.
+func Names(procs []parse.Process) []string {
+	names := []string{}
+	for _, proc := range procs {
+		names = append(names, proc.Name)
+	}
+	return names
+}
+
+// Scale scales the specified app's processes of the specified type to the specified count as the
+// specified user, and waits for exactly that many of them to be up.
+func Scale(user model.User, app model.App, procType string, count int) {
+	Current().Scale(user, app, procType, count)
+	ExpectEventually(user, app, Expected{Counts: map[string]int{procType: count}})
+}
+
+// Restart restarts the specified app's processes as the specified user, and returns those that
+// replaced them. If name is set, only the process of that name is restarted; otherwise, if procType
+// is set, only those of that type are; otherwise every process is.
+func Restart(user model.User, app model.App, procType, name string) []parse.Process {
+	return Current().Restart(user, app, procType, name)
+}
+
+// List executes `deis ps:list` as the specified user.
+func (CLI) List(user model.User, app model.App) []parse.Process {
+	sess, err := cmd.Deis(&user, "ps:list", "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess).Should(Say("=== %s Processes", app.Name))
+	Eventually(sess).Should(Exit(0))
+	procs, err := parse.PsList(sess.Out.Contents())
+	Expect(err).NotTo(HaveOccurred())
+	return procs
+}
+
+// Scale executes `deis ps:scale` as the specified user.
+func (CLI) Scale(user model.User, app model.App, procType string, count int) {
+	sess, err := cmd.Deis(&user, "ps:scale", fmt.Sprintf("%s=%d", procType, count), "--app="+app.Name)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess).Should(Say("Scaling processes... but first,"))
+	Eventually(sess, settings.MaxEventuallyTimeout).Should(Say(`done in \d+s`))
+	Eventually(sess).Should(Say("=== %s Processes", app.Name))
+	Eventually(sess).Should(Exit(0))
+}
+
+// Restart executes `deis ps:restart` as the specified user.
+func (CLI) Restart(user model.User, app model.App, procType, name string) []parse.Process {
+	args := []string{"ps:restart"}
+	if name != "" {
+		args = append(args, name)
+	} else if procType != "" {
+		args = append(args, procType)
+	}
+	sess, err := cmd.Deis(&user, append(args, "--app="+app.Name)...)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess).Should(Say("Restarting processes... but first,"))
+	Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+	if strings.Contains(string(sess.Out.Contents()), "Could not find any processes to restart") {
+		return nil
+	}
+	Expect(sess.Out).To(Say(`done in \d+s`))
+	procs, err := parse.PsList(sess.Out.Contents())
+	Expect(err).NotTo(HaveOccurred())
+	return procs
+}
+
+// Expected describes the processes that an app is expected to have.
+type Expected struct {
+	// Counts are how many processes of each type must be listed. Every one of them must be up.
+	// Types that are not listed are not checked.
+	Counts map[string]int
+	// Version, if set, is the release at which every process listed must be.
+	Version int
+}
+
+// String returns the Expected in printable form.
+func (e Expected) String() string {
+	var types []string
+	for procType := range e.Counts {
+		types = append(types, procType)
+	}
+	sort.Strings(types)
+	counts := make([]string, len(types))
+	for i, procType := range types {
+		counts[i] = fmt.Sprintf("%s=%d", procType, e.Counts[procType])
+	}
+	return fmt.Sprintf("[Counts: '%s', Version: '%d']", strings.Join(counts, " "), e.Version)
+}
+
+// ExpectEventually polls `deis ps:list` until the specified app's processes are as expected,
+// failing the current spec with a description of every attempt if they are not within
+// settings.MaxEventuallyTimeout.
+func ExpectEventually(user model.User, app model.App, expected Expected) {
+	psList := cmd.Command(&user, "deis", "ps:list", "--app="+app.Name)
+	_, err := cmd.PollCmd(psList, poll.New(settings.MaxEventuallyTimeout), poll.ExitCode(0), listing(app, expected))
+	ExpectWithOffset(1, err).NotTo(HaveOccurred(), "%s never had processes %s", app.Name, expected)
+}
+
+// listing holds for the output of `deis ps:list` when the app's processes are as expected.
+func listing(app model.App, expected Expected) poll.Predicate {
+	return func(r poll.Result) (bool, string) {
+		procs, err := parse.PsList(r.Out)
+		if err != nil {
+			return false, err.Error()
+		}
+		counts := map[string]int{}
+		var pending []string
+		for _, proc := range procs {
+			if !strings.HasPrefix(proc.Name, app.Name+"-") {
+				continue
+			}
+			counts[proc.Type]++
+			_, checked := expected.Counts[proc.Type]
+			if checked && proc.State != "up" {
+				pending = append(pending, fmt.Sprintf("%s is %s", proc.Name, proc.State))
+			}
+			if expected.Version != 0 && proc.Release != expected.Version {
+				pending = append(pending, fmt.Sprintf("%s is at v%d", proc.Name, proc.Release))
+			}
+		}
+		for procType, count := range expected.Counts {
+			if counts[procType] != count {
+				pending = append(pending, fmt.Sprintf("%d %s processes are listed, not %d", counts[procType], procType, count))
+			}
+		}
+		if len(pending) > 0 {
+			sort.Strings(pending)
+			return false, strings.Join(pending, ", ")
+		}
+		return true, ""
+	}
+}
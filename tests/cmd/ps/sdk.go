@@ -0,0 +1,44 @@
+package ps
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/deis/controller-sdk-go/api"
+	sdkps "github.com/deis/controller-sdk-go/ps"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+
+	. "github.com/onsi/gomega"
+)
+
+// List lists the specified app's processes as the specified user.
+func (SDK) List(user model.User, app model.App) []parse.Process {
+	pods, _, err := sdkps.List(cmd.Client(user), app.Name, 0)
+	Expect(err).NotTo(HaveOccurred())
+	return processes(pods)
+}
+
+// Scale scales the specified app's processes of the specified type as the specified user.
+func (SDK) Scale(user model.User, app model.App, procType string, count int) {
+	err := sdkps.Scale(cmd.Client(user), app.Name, map[string]int{procType: count})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Restart restarts the specified app's processes as the specified user.
+func (SDK) Restart(user model.User, app model.App, procType, name string) []parse.Process {
+	pods, err := sdkps.Restart(cmd.Client(user), app.Name, procType, name)
+	Expect(err).NotTo(HaveOccurred())
+	return processes(pods)
+}
+
+// processes returns the specified pods as they would be listed by `deis ps:list`.
+func processes(pods api.PodsList) []parse.Process {
+	var procs []parse.Process
+	for _, pod := range pods {
+		release, _ := strconv.Atoi(strings.TrimPrefix(pod.Release, "v"))
+		procs = append(procs, parse.Process{Name: pod.Name, Type: pod.Type, State: pod.State, Release: release})
+	}
+	return procs
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/parse"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega/gexec"
+)
+
+// The functions in this file record every command executed by way of this package to a report,
+// so that what a single Ginkgo node did can be reconstructed after the fact.
+
+// Record describes a single command executed by way of this package.
+type Record struct {
+	Spec    string `json:"spec"`
+	Command string `json:"command"`
+	User    string `json:"user,omitempty"`
+	// EnvDiff is the environment that the command was given beyond that of this process. Only the
+	// names of variables are recorded, except for those in recordedValues, which hold no secrets.
+	EnvDiff []string  `json:"envDiff,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// ExitCode is -1 if the command could not be started or had not exited when the report was
+	// closed.
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	// Stderr is always empty for commands run by Execute, since their output is combined.
+	Stderr string `json:"stderr"`
+	Error  string `json:"error,omitempty"`
+	// Build is the report of a build, for records made by RecordBuild rather than for commands.
+	Build *parse.BuildReport `json:"build,omitempty"`
+}
+
+var report = struct {
+	sync.Mutex
+	file    *os.File
+	enc     *json.Encoder
+	running map[*gexec.Session]*Record
+}{}
+
+// OpenReport starts recording every command executed by way of this package to the file at the
+// specified path, as one JSON encoded Record per line.
+func OpenReport(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	report.Lock()
+	defer report.Unlock()
+	report.file = file
+	report.enc = json.NewEncoder(file)
+	report.running = map[*gexec.Session]*Record{}
+	return nil
+}
+
+// CloseReport records any commands that are still running and closes the report.
+func CloseReport() error {
+	report.Lock()
+	defer report.Unlock()
+	if report.file == nil {
+		return nil
+	}
+	for sess, record := range report.running {
+		finishRecord(record, sess)
+		writeRecord(record)
+	}
+	err := report.file.Close()
+	report.file, report.enc, report.running = nil, nil, nil
+	return err
+}
+
+func newRecord(commandLine string, env []string) *Record {
+	record := &Record{
+		Spec:     ginkgo.CurrentGinkgoTestDescription().FullTestText,
+		Command:  commandLine,
+		EnvDiff:  envDiff(env),
+		Start:    time.Now(),
+		ExitCode: -1,
+	}
+	for _, v := range env {
+		if strings.HasPrefix(v, "DEIS_PROFILE=") {
+			record.User = strings.TrimPrefix(v, "DEIS_PROFILE=")
+		}
+	}
+	return record
+}
+
+// recordExecution records a command run to completion by Execute.
+func recordExecution(command string, execCmd *exec.Cmd, start time.Time, output string, err error) {
+	report.Lock()
+	defer report.Unlock()
+	if report.file == nil {
+		return
+	}
+	record := newRecord(command, execCmd.Env)
+	record.Start = start
+	record.End = time.Now()
+	record.Stdout = output
+	if execCmd.ProcessState != nil {
+		record.ExitCode = execCmd.ProcessState.Sys().(syscall.WaitStatus).ExitStatus()
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	writeRecord(record)
+}
+
+// recordSession records a command started by StartCmd once it has exited.
+func recordSession(command string, env []string, sess *gexec.Session, err error) {
+	report.Lock()
+	defer report.Unlock()
+	if report.file == nil {
+		return
+	}
+	record := newRecord(command, env)
+	if err != nil {
+		record.End = record.Start
+		record.Error = err.Error()
+		writeRecord(record)
+		return
+	}
+	report.running[sess] = record
+	go func() {
+		<-sess.Exited
+		report.Lock()
+		defer report.Unlock()
+		// The report may have been closed, and the record written, while the command was running.
+		if _, ok := report.running[sess]; !ok {
+			return
+		}
+		finishRecord(record, sess)
+		writeRecord(record)
+	}()
+}
+
+// RecordBuild records the report of a build alongside the commands, so that the time each phase
+// of a build takes can be compared across runs. The record's Command describes the build.
+func RecordBuild(build parse.BuildReport) {
+	report.Lock()
+	defer report.Unlock()
+	if report.file == nil || len(build.Phases) == 0 {
+		return
+	}
+	description := "build"
+	if build.Kind != "" {
+		description = fmt.Sprintf("%s build", build.Kind)
+	}
+	if build.Buildpack != "" {
+		description += fmt.Sprintf(" (%s)", build.Buildpack)
+	}
+	if build.App != "" {
+		description += fmt.Sprintf(" of %s:v%d", build.App, build.Version)
+	}
+	record := newRecord(description, nil)
+	record.Start = build.Phases[0].Start
+	record.End = record.Start.Add(build.Total())
+	record.ExitCode = 0
+	if !build.Succeeded {
+		record.ExitCode = 1
+		record.Error = build.Failure
+	}
+	record.Build = &build
+	writeRecord(record)
+}
+
+// recordTimeout notes on the record of a session started by StartCmdContext that it was killed
+// because its context expired.
+func recordTimeout(sess *gexec.Session, err error) {
+	report.Lock()
+	defer report.Unlock()
+	if record, ok := report.running[sess]; ok {
+		record.Error = err.Error()
+	}
+}
+
+func finishRecord(record *Record, sess *gexec.Session) {
+	record.End = time.Now()
+	record.ExitCode = sess.ExitCode()
+	record.Stdout = string(sess.Out.Contents())
+	record.Stderr = string(sess.Err.Contents())
+	delete(report.running, sess)
+}
+
+func writeRecord(record *Record) {
+	// The report is a debugging aid, so failing to write to it should not fail the spec.
+	report.enc.Encode(record)
+}
+
+// recordedValues are the environment variables whose values envDiff records.
+var recordedValues = map[string]bool{"DEIS_PROFILE": true, "GIT_KEY": true, "GIT_SSH": true, "HOME": true}
+
+// envDiff returns the entries of env that are not also present in the environment of this
+// process, with the values of all but recordedValues removed. A nil env means that of this
+// process is inherited, so there is no difference.
+func envDiff(env []string) []string {
+	current := map[string]bool{}
+	for _, v := range os.Environ() {
+		current[v] = true
+	}
+	var diff []string
+	for _, v := range env {
+		if current[v] {
+			continue
+		}
+		if name := strings.SplitN(v, "=", 2)[0]; !recordedValues[name] {
+			v = name
+		}
+		diff = append(diff, v)
+	}
+	return diff
+}
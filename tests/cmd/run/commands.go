@@ -0,0 +1,134 @@
+package run
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+// The functions in this file implement SUCCESS CASES for `deis apps:run`, which runs a one-off
+// command in an app's environment. A command that exits non-zero is not a failure of `deis
+// apps:run`, so these functions return its exit code rather than failing the spec.
+
+// Result is the outcome of a one-off command.
+type Result struct {
+	// Output is everything the command wrote. The controller does not keep stdout and stderr apart.
+	Output []byte
+	// ExitCode is the command's exit code, which is 128 plus the number of the signal that killed
+	// it, if one did.
+	ExitCode int
+}
+
+// Driver implements the `deis apps:run` SUCCESS CASES either by executing the deis CLI or by
+// calling the controller directly.
+type Driver interface {
+	RunWithin(user model.User, app model.App, command string, deadline time.Duration) (Result, error)
+}
+
+// CLI is the Driver that executes the deis CLI.
+type CLI struct{}
+
+// SDK is the Driver that calls the controller using controller-sdk-go.
+type SDK struct{}
+
+// Current returns the Driver selected by $E2E_DRIVER.
+func Current() Driver {
+	if cmd.UseSDK() {
+		return SDK{}
+	}
+	return CLI{}
+}
+
+// Run runs the specified command in the specified app's environment as the specified user, and
+// returns its outcome once it exits. It fails the current spec if the command does not exit within
+// settings.MaxEventuallyTimeout.
+func Run(user model.User, app model.App, command string) Result {
+	result, err := Current().RunWithin(user, app, command, settings.MaxEventuallyTimeout)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return result
+}
+
+// RunWithin is like Run, but if the command has not exited once the specified deadline has
+// passed, it gives up on the command and returns a *cmd.TimeoutError.
+func RunWithin(user model.User, app model.App, command string, deadline time.Duration) (Result, error) {
+	return Current().RunWithin(user, app, command, deadline)
+}
+
+// RunConcurrently runs each of the specified commands in the specified app's environment as the
+// specified user, all at once, and returns their outcomes in the same order. Like Run, it fails the
+// current spec if any of them does not exit within settings.MaxEventuallyTimeout.
+func RunConcurrently(user model.User, app model.App, commands ...string) []Result {
+	results := make([]Result, len(commands))
+	errs := make([]error, len(commands))
+	var wg sync.WaitGroup
+	for i, command := range commands {
+		wg.Add(1)
+		go func(i int, command string) {
+			defer wg.Done()
+			results[i], errs[i] = Current().RunWithin(user, app, command, settings.MaxEventuallyTimeout)
+		}(i, command)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		ExpectWithOffset(1, err).NotTo(HaveOccurred(), commands[i])
+	}
+	return results
+}
+
+// RunWithStdin is like Run, but writes the specified input to the standard input of `deis
+// apps:run`. It always executes the deis CLI, since only the CLI has a standard input to write to.
+func RunWithStdin(user model.User, app model.App, command string, stdin []byte) Result {
+	run := cmd.Command(&user, "deis", "apps:run", "--app="+app.Name, "--", command)
+	run.Stdin = stdin
+	sess, err := cmd.StartCmd(run)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(sess, settings.MaxEventuallyTimeout).Should(gexec.Exit())
+	return cliResult(sess)
+}
+
+// RunWithin executes `deis apps:run` as the specified user, killing it if the deadline passes.
+func (CLI) RunWithin(user model.User, app model.App, command string, deadline time.Duration) (Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	start := time.Now()
+	run := cmd.Command(&user, "deis", "apps:run", "--app="+app.Name, "--", command)
+	sess, err := cmd.StartCmdContext(ctx, run)
+	if err != nil {
+		return Result{}, err
+	}
+	<-sess.Exited
+	// The CLI is killed, and so has no exit code, if the context expires while it is running.
+	if ctx.Err() != nil && sess.ExitCode() == -1 {
+		return Result{}, &cmd.TimeoutError{Command: run.String(), Elapsed: time.Since(start), Err: ctx.Err()}
+	}
+	return cliResult(sess), nil
+}
+
+// cliResult returns the outcome of the command run by the exited `deis apps:run` session. The CLI
+// writes the command's output to stdout if it exited zero, and to stderr otherwise, and exits
+// with the command's exit code.
+func cliResult(sess *gexec.Session) Result {
+	output := append(sess.Out.Contents(), sess.Err.Contents()...)
+	return Result{Output: output, ExitCode: sess.ExitCode()}
+}
+
+// Env parses the output of `env`, as run by Run, into a map of variable names to values. Lines
+// that are not assignments, such as the continuations of multi-line values, are skipped.
+func Env(result Result) map[string]string {
+	env := map[string]string{}
+	for _, line := range strings.Split(string(result.Output), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && kv[0] != "" && !strings.ContainsAny(kv[0], " \t") {
+			env[kv[0]] = kv[1]
+		}
+	}
+	return env
+}
@@ -0,0 +1,39 @@
+package run
+
+import (
+	"context"
+	"time"
+
+	"github.com/deis/controller-sdk-go/api"
+	sdkapps "github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/model"
+)
+
+// RunWithin runs the specified command in the specified app's environment as the specified user.
+// If the deadline passes first, the request is abandoned, though the command runs on.
+func (SDK) RunWithin(user model.User, app model.App, command string, deadline time.Duration) (Result, error) {
+	type response struct {
+		out api.AppRunResponse
+		err error
+	}
+	client, err := cmd.ProfileClient(user)
+	if err != nil {
+		return Result{}, err
+	}
+	start := time.Now()
+	done := make(chan response, 1)
+	go func() {
+		out, err := sdkapps.Run(client, app.Name, command)
+		done <- response{out, err}
+	}()
+	select {
+	case resp := <-done:
+		if resp.err != nil {
+			return Result{}, resp.err
+		}
+		return Result{Output: []byte(resp.out.Output), ExitCode: resp.out.ReturnCode}, nil
+	case <-time.After(deadline):
+		return Result{}, &cmd.TimeoutError{Command: "apps:run " + command, Elapsed: time.Since(start), Err: context.DeadlineExceeded}
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/settings"
+)
+
+// The functions in this file bound how long any command executed by way of this package may run,
+// so that a hung command fails the spec that ran it instead of blocking its Ginkgo node forever.
+// Every command runs in a process group of its own, so that when its context expires, the child
+// processes it spawned, such as the ssh started by git by way of settings.GitSSH, are killed
+// along with it.
+
+// TimeoutError is the error returned when a command is killed because its context expired.
+type TimeoutError struct {
+	// Command is the command that was killed, as it would be typed into a shell.
+	Command string
+	// Elapsed is how long the command had been running when it was killed.
+	Elapsed time.Duration
+	// Err is the reason the context expired: context.DeadlineExceeded or context.Canceled.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err == context.Canceled {
+		return fmt.Sprintf("`%s` was cancelled and killed after %s", e.Command, e.Elapsed)
+	}
+	return fmt.Sprintf("`%s` timed out and was killed after %s", e.Command, e.Elapsed)
+}
+
+// IsTimeout reports whether err is a *TimeoutError.
+func IsTimeout(err error) bool {
+	_, ok := err.(*TimeoutError)
+	return ok
+}
+
+// DefaultContext returns a context whose deadline is settings.DefaultEventuallyTimeout from now.
+// Execute and ExecuteCmd run commands with such a context.
+func DefaultContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), settings.DefaultEventuallyTimeout)
+}
+
+// MaxContext returns a context whose deadline is settings.MaxEventuallyTimeout from now. Start,
+// StartCmd and Deis start commands with such a context.
+func MaxContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), settings.MaxEventuallyTimeout)
+}
+
+// setProcessGroup arranges for execCmd to be started in a new process group, of which it will be
+// the leader.
+func setProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.SysProcAttr == nil {
+		execCmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	execCmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills every process in the process group led by the started execCmd.
+func killProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.Process == nil {
+		return
+	}
+	// A negative pid signals the whole process group.
+	syscall.Kill(-execCmd.Process.Pid, syscall.SIGKILL)
+}
+
+// watch kills execCmd's process group if ctx expires before done is closed, first passing the
+// resulting *TimeoutError to onTimeout.
+func watch(ctx context.Context, execCmd *exec.Cmd, command string, start time.Time, done <-chan struct{}, onTimeout func(*TimeoutError)) {
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			onTimeout(&TimeoutError{Command: command, Elapsed: time.Since(start), Err: ctx.Err()})
+			killProcessGroup(execCmd)
+		}
+	}()
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/kube"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/ginkgo"
@@ -70,6 +72,12 @@ var _ = Describe("deis config", func() {
 				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("POWERED_BY=midi-chlorians"))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the app's pods were given the environment variable
+				Eventually(func() (map[string]string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.Env(spec), err
+				}, settings.MaxEventuallyTimeout).Should(HaveKeyWithValue("POWERED_BY", "midi-chlorians"))
 			})
 
 			Specify("that user can set multiple environment variables at once on that app", func() {
@@ -141,6 +149,20 @@ multiline string.`
 				Eventually(sess).Should(Exit(0))
 			})
 
+			Specify("that user can set an environment variable containing shell metacharacters on that app", func() {
+				value := `it's "$HOME"; echo *`
+				sess, err := cmd.Deis(&user, "config:set", "-a", app.Name, "QUOTED="+value)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+
+				sess, err = cmd.Deis(&user, "config:list", "-a", app.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess).Should(Exit(0))
+				config, err := parse.ConfigList(sess.Out.Contents())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config).To(HaveKeyWithValue("QUOTED", value))
+			})
+
 			Specify("that user can set an environment variable with non-ASCII and multibyte chars on that app", func() {
 				sess, err := cmd.Start("deis config:set FOO=讲台 BAR=Þorbjörnsson BAZ=ноль -a %s", &user, app.Name)
 				Eventually(sess).Should(Say("Creating config"))
@@ -234,7 +256,7 @@ FOO=bar`)
 				Eventually(sess).Should(Say(`FOO\s+bar`))
 
 				// Config should be found within the app env vars (without any line endings).
-				sess, err = cmd.Start("deis run -a %s 'printf %%q $BIP'", &user, app.Name)
+				sess, err = cmd.Deis(&user, "run", "-a", app.Name, "printf %q $BIP")
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 				Eventually(sess).Should(Say("baz"))
@@ -258,7 +280,7 @@ FOO=bar`)
 				Eventually(sess).Should(Exit(0))
 
 				// Config should be found within the app env vars (without any line endings).
-				sess, err = cmd.Start("deis run -a %s 'printf %%q $WOO'", &user, app.Name)
+				sess, err = cmd.Deis(&user, "run", "-a", app.Name, "printf %q $WOO")
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 				Eventually(sess).Should(Say("goo"))
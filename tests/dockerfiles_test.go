@@ -3,14 +3,15 @@ package tests
 import (
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/git"
 	"github.com/deis/workflow-e2e/tests/cmd/keys"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	"github.com/deis/workflow-e2e/tests/fixtures"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -39,36 +40,41 @@ var _ = Describe("all dockerfile apps", func() {
 			})
 
 			DescribeTable("can deploy an example dockerfile app",
-				func(url, buildpack, banner, proctype string) {
+				func(f fixtures.Fixture) {
 
 					var app model.App
 
-					output, err := cmd.Execute(`git clone %s`, url)
-					Expect(err).NotTo(HaveOccurred(), output)
-					// infer app directory from URL
-					splits := strings.Split(url, "/")
-					dir := strings.TrimSuffix(splits[len(splits)-1], ".git")
-					os.Chdir(dir)
-					// creeate with custom buildpack if needed
+					os.Chdir(writeFixture(f))
+					// create with custom buildpack if needed
 					var args []string
-					if buildpack != "" {
-						args = append(args, fmt.Sprintf("--buildpack %s", buildpack))
+					if f.Buildpack != "" {
+						args = append(args, fmt.Sprintf("--buildpack %s", f.Buildpack))
 					}
 					app = apps.Create(user, args...)
 					defer apps.Destroy(user, app)
-					git.Push(user, keyPath, app, banner)
-					_ = listProcs(user, app, proctype)
+					report := git.Push(user, keyPath, app, f.Manifest.Response)
+					Expect(report.Kind).To(Equal(parse.DockerfileBuild))
+					Expect(report.Version).To(Equal(2))
+					Expect(ps.Types(user, app)).To(ConsistOf(f.Manifest.ProcessTypes))
+					Expect(ps.List(user, app, f.Manifest.ProcessTypes[0])).NotTo(BeEmpty())
 
 				},
 
-				Entry("HTTP", "https://github.com/deis/example-dockerfile-http.git", "",
-					"Powered by Deis", ""),
-				Entry("Python", "https://github.com/deis/example-dockerfile-python.git", "",
-					"Powered by Deis", ""),
-				Entry("HTTP-Web", "https://github.com/deis/example-dockerfile-procfile-http.git", "",
-					"Powered by Deis", "web"),
+				Entry("HTTP", fixtures.DockerfileHTTP),
+				Entry("Python", fixtures.DockerfilePython),
+				Entry("HTTP-Web", fixtures.DockerfileProcfile),
+				Entry("Multi", fixtures.ProcfileMulti),
 			)
 
+			Specify("that user cannot deploy an app with a bad Dockerfile", func() {
+				f := fixtures.BrokenDockerfile
+				os.Chdir(writeFixture(f))
+				app := apps.Create(user)
+				defer apps.Destroy(user, app)
+				report := git.PushExpectingFailure(user, keyPath)
+				Expect(report.Failure).To(ContainSubstring(f.Manifest.FailsWith))
+			})
+
 		})
 
 	})
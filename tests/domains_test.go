@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
-	"strconv"
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/workflow-e2e/tests/cmd"
@@ -12,9 +11,10 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
 	"github.com/deis/workflow-e2e/tests/cmd/domains"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/resolver"
 	"github.com/deis/workflow-e2e/tests/settings"
-	"github.com/deis/workflow-e2e/tests/util"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -66,10 +66,7 @@ var _ = Describe("deis domains", func() {
 			})
 
 			Specify("that user cannot remove a non-existent domain from that app", func() {
-				sess, err := cmd.Start("deis domains:remove --app=%s %s", &user, app.Name, "non.existent.domain")
-				Eventually(sess.Err, settings.MaxEventuallyTimeout).Should(Say(util.PrependError(deis.ErrNotFound)))
-				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(1))
+				domains.RemoveExpectingError(user, app, "non.existent.domain", deis.ErrNotFound)
 			})
 
 			Context("with a domain added to it", func() {
@@ -108,30 +105,26 @@ var _ = Describe("deis domains", func() {
 
 			Context("with a domain added to it", func() {
 
-				cmdRetryTimeout := 60
-
-				var domain string
+				var domain, domainURL string
 
 				BeforeEach(func() {
 					domain = getRandDomain()
+					domainURL = resolver.URL("http", domain)
 					domains.Add(user, app, domain)
 				})
 
 				AfterEach(func() {
 					domains.Remove(user, app, domain)
 					// App can no longer be accessed at the previously associated domain
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -H "Host: %s" -w "%%{http_code}\\n" "%s" -o /dev/null`, domain, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(http.StatusNotFound), cmdRetryTimeout)).Should(BeTrue())
+					probe.ExpectEventually(probe.Get(domainURL), probe.Expected{StatusCode: http.StatusNotFound})
 				})
 
 				Specify("that app can be accessed at its usual address", func() {
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(http.StatusOK), cmdRetryTimeout)).Should(BeTrue())
+					probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: http.StatusOK})
 				})
 
 				Specify("that app can be accessed at the associated domain", func() {
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -H "Host: %s" -w "%%{http_code}\\n" "%s" -o /dev/null`, domain, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(http.StatusOK), cmdRetryTimeout)).Should(BeTrue())
+					probe.ExpectEventually(probe.Get(domainURL), probe.Expected{StatusCode: http.StatusOK})
 				})
 
 			})
@@ -0,0 +1,976 @@
+package fake
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var appIDRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type app struct {
+	UUID      string         `json:"uuid"`
+	ID        string         `json:"id"`
+	Owner     string         `json:"owner"`
+	URL       string         `json:"url"`
+	Structure map[string]int `json:"structure"`
+	Created   string         `json:"created"`
+	Updated   string         `json:"updated"`
+
+	users    []string
+	config   *config
+	builds   []*build
+	releases []*release
+	domains  []*domain
+	settings *appSettings
+	tls      *tlsSettings
+	pods     []*pod
+	logs     []string
+}
+
+type config struct {
+	UUID        string                 `json:"uuid"`
+	Owner       string                 `json:"owner"`
+	App         string                 `json:"app"`
+	Values      map[string]interface{} `json:"values"`
+	Memory      map[string]interface{} `json:"memory"`
+	CPU         map[string]interface{} `json:"cpu"`
+	Tags        map[string]interface{} `json:"tags"`
+	Registry    map[string]interface{} `json:"registry"`
+	Healthcheck map[string]interface{} `json:"healthcheck"`
+	Created     string                 `json:"created"`
+	Updated     string                 `json:"updated"`
+}
+
+type build struct {
+	UUID       string            `json:"uuid"`
+	App        string            `json:"app"`
+	Owner      string            `json:"owner"`
+	Image      string            `json:"image"`
+	Sha        string            `json:"sha"`
+	Procfile   map[string]string `json:"procfile"`
+	Dockerfile string            `json:"dockerfile"`
+	Created    string            `json:"created"`
+	Updated    string            `json:"updated"`
+}
+
+type release struct {
+	UUID    string `json:"uuid"`
+	App     string `json:"app"`
+	Build   string `json:"build,omitempty"`
+	Config  string `json:"config"`
+	Owner   string `json:"owner"`
+	Summary string `json:"summary"`
+	Version int    `json:"version"`
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+type domain struct {
+	App     string `json:"app"`
+	Owner   string `json:"owner"`
+	Domain  string `json:"domain"`
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+type appSettings struct {
+	UUID        string                 `json:"uuid"`
+	Owner       string                 `json:"owner"`
+	App         string                 `json:"app"`
+	Maintenance bool                   `json:"maintenance"`
+	Routable    bool                   `json:"routable"`
+	Whitelist   []string               `json:"whitelist"`
+	Label       map[string]interface{} `json:"label"`
+	Autoscale   map[string]interface{} `json:"autoscale"`
+	Created     string                 `json:"created"`
+	Updated     string                 `json:"updated"`
+}
+
+type tlsSettings struct {
+	UUID          string `json:"uuid"`
+	Owner         string `json:"owner"`
+	App           string `json:"app"`
+	HTTPSEnforced bool   `json:"https_enforced"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+type pod struct {
+	Release string `json:"release"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Started string `json:"started"`
+}
+
+// canAccess returns whether the user may read and modify the app.
+func (a *app) canAccess(u *user) bool {
+	return u.IsSuperuser || a.Owner == u.Username || a.isCollaborator(u.Username)
+}
+
+// canAdminister returns whether the user may destroy, transfer and share the app.
+func (a *app) canAdminister(u *user) bool {
+	return u.IsSuperuser || a.Owner == u.Username
+}
+
+func (a *app) isCollaborator(username string) bool {
+	for _, name := range a.users {
+		if name == username {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *app) removeCollaborator(username string) {
+	for i, name := range a.users {
+		if name == username {
+			a.users = append(a.users[:i], a.users[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *app) log(format string, args ...interface{}) {
+	a.logs = append(a.logs, fmt.Sprintf("%s deis[controller]: INFO %s", now(), fmt.Sprintf(format, args...)))
+}
+
+// logOutput records the output of the specified process of the app, line by line, the way the
+// logger does.
+func (a *app) logOutput(process, output string) {
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line != "" {
+			a.logs = append(a.logs, fmt.Sprintf("%s %s[%s]: %s", now(), a.ID, process, line))
+		}
+	}
+}
+
+func (a *app) latestRelease() *release {
+	return a.releases[len(a.releases)-1]
+}
+
+// currentBuild returns the build of the app's latest release, or nil if it has not been deployed.
+func (a *app) currentBuild() *build {
+	if len(a.releases) == 0 {
+		return nil
+	}
+	return a.buildOf(a.latestRelease())
+}
+
+// buildOf returns the build of the specified release, or nil if it has none.
+func (a *app) buildOf(rel *release) *build {
+	for _, b := range a.builds {
+		if b.UUID == rel.Build {
+			return b
+		}
+	}
+	return nil
+}
+
+// release records a new release of the specified build of the app, if any, and rolls its pods.
+func (a *app) release(owner, summary string, b *build) *release {
+	rel := &release{
+		UUID:    newUUID(),
+		App:     a.ID,
+		Config:  a.config.UUID,
+		Owner:   owner,
+		Summary: summary,
+		Version: len(a.releases) + 1,
+		Created: now(),
+		Updated: now(),
+	}
+	if b != nil {
+		rel.Build = b.UUID
+	}
+	a.releases = append(a.releases, rel)
+	a.Updated = now()
+	a.rollPods()
+	return rel
+}
+
+// rollPods replaces all pods with new ones matching the app's structure at the latest release.
+func (a *app) rollPods() {
+	a.pods = nil
+	if a.currentBuild() == nil {
+		return
+	}
+	var types []string
+	for t := range a.Structure {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		for i := 0; i < a.Structure[t]; i++ {
+			a.pods = append(a.pods, a.newPod(t))
+		}
+	}
+}
+
+func (a *app) newPod(procType string) *pod {
+	return &pod{
+		Release: fmt.Sprintf("v%d", a.latestRelease().Version),
+		Type:    procType,
+		Name:    fmt.Sprintf("%s-%s-%d-%s", a.ID, procType, 1000000000+rand.Intn(999999999), randSuffix(5)),
+		State:   "up",
+		Started: now(),
+	}
+}
+
+func randSuffix(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// serveApps handles /v2/apps/ and everything beneath it.
+func (c *Controller) serveApps(w http.ResponseWriter, r *request) {
+	if r.segment(2) == "" {
+		switch r.Method {
+		case "GET":
+			c.listApps(w, r)
+		case "POST":
+			c.createApp(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	a, ok := c.apps[r.segment(2)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if !a.canAccess(r.user) {
+		writeError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	switch r.segment(3) {
+	case "":
+		c.serveApp(w, r, a)
+	case "logs":
+		serveLogs(w, r, a)
+	case "run":
+		serveRun(w, r, a)
+	case "config":
+		c.serveConfig(w, r, a)
+	case "builds":
+		serveBuilds(w, r, a)
+	case "releases":
+		serveReleases(w, r, a)
+	case "pods":
+		servePods(w, r, a)
+	case "scale":
+		serveScale(w, r, a)
+	case "domains":
+		c.serveDomains(w, r, a)
+	case "perms":
+		c.servePerms(w, r, a)
+	case "settings":
+		serveSettings(w, r, a)
+	case "whitelist":
+		serveWhitelist(w, r, a)
+	case "tls":
+		serveTLS(w, r, a)
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (c *Controller) listApps(w http.ResponseWriter, r *request) {
+	var ids []string
+	for id, a := range c.apps {
+		if a.canAccess(r.user) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var results []interface{}
+	for _, id := range ids {
+		results = append(results, c.apps[id])
+	}
+	writeList(w, r, results)
+}
+
+func (c *Controller) createApp(w http.ResponseWriter, r *request) {
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.ID == "" {
+		body.ID = fmt.Sprintf("%s-%s", randSuffix(6), randSuffix(6))
+	}
+	if !appIDRegexp.MatchString(body.ID) {
+		writeFieldError(w, "id", "App name can only contain a-z (lowercase), 0-9 and hyphens")
+		return
+	}
+	if _, ok := c.apps[body.ID]; ok {
+		writeFieldError(w, "id", "Application with this id already exists.")
+		return
+	}
+
+	owner := r.user.Username
+	a := &app{
+		UUID:      newUUID(),
+		ID:        body.ID,
+		Owner:     owner,
+		URL:       fmt.Sprintf("%s.%s", body.ID, c.Domain),
+		Structure: map[string]int{},
+		Created:   now(),
+		Updated:   now(),
+	}
+	a.config = &config{
+		UUID:        newUUID(),
+		Owner:       owner,
+		App:         a.ID,
+		Values:      map[string]interface{}{},
+		Memory:      map[string]interface{}{},
+		CPU:         map[string]interface{}{},
+		Tags:        map[string]interface{}{},
+		Registry:    map[string]interface{}{},
+		Healthcheck: map[string]interface{}{},
+		Created:     now(),
+		Updated:     now(),
+	}
+	a.log("config %s updated", a.ID)
+	a.release(owner, fmt.Sprintf("%s created initial release", owner), nil)
+	a.log("%s created initial release", owner)
+	a.settings = &appSettings{
+		UUID:      newUUID(),
+		Owner:     owner,
+		App:       a.ID,
+		Routable:  true,
+		Whitelist: []string{},
+		Label:     map[string]interface{}{},
+		Autoscale: map[string]interface{}{},
+		Created:   now(),
+		Updated:   now(),
+	}
+	a.log("appsettings %s updated", a.ID)
+	a.tls = &tlsSettings{UUID: newUUID(), Owner: owner, App: a.ID, Created: now(), Updated: now()}
+	a.domains = []*domain{{App: a.ID, Owner: owner, Domain: a.ID, Created: now(), Updated: now()}}
+	a.log("domain %s added", a.ID)
+	c.apps[a.ID] = a
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// serveApp handles /v2/apps/<id>/.
+func (c *Controller) serveApp(w http.ResponseWriter, r *request, a *app) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a)
+	case "DELETE":
+		if !a.canAdminister(r.user) {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+		for _, crt := range c.certs {
+			for _, d := range a.domains {
+				crt.detach(d.Domain)
+			}
+		}
+		delete(c.apps, a.ID)
+		w.WriteHeader(http.StatusNoContent)
+	case "POST":
+		// The only supported update is a transfer of ownership.
+		if !a.canAdminister(r.user) {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+		var body struct {
+			Owner string `json:"owner"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := c.users[body.Owner]; !ok {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		a.Owner = body.Owner
+		a.Updated = now()
+		writeJSON(w, http.StatusOK, a)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveLogs handles /v2/apps/<id>/logs, honoring the "log_lines" query parameter.
+func serveLogs(w http.ResponseWriter, r *request, a *app) {
+	logs := a.logs
+	if n, err := strconv.Atoi(r.URL.Query().Get("log_lines")); err == nil && n >= 0 && n < len(logs) {
+		logs = logs[len(logs)-n:]
+	}
+	if len(logs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, strings.Join(logs, "\n"))
+}
+
+// serveRun handles /v2/apps/<id>/run. Nothing is actually executed; a handful of commands are
+// emulated well enough for specs that inspect their output.
+func serveRun(w http.ResponseWriter, r *request, a *app) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Command string `json:"command"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if a.currentBuild() == nil {
+		writeError(w, http.StatusBadRequest, "No build associated with this release to run this command")
+		return
+	}
+
+	var output string
+	exitCode := 0
+	args := strings.Fields(body.Command)
+	switch {
+	case len(args) == 0:
+	case args[0] == "echo":
+		output = strings.Join(args[1:], " ") + "\n"
+	case args[0] == "env":
+		var keys []string
+		for k := range a.config.Values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			output += fmt.Sprintf("%s=%v\n", k, a.config.Values[k])
+		}
+	case args[0] == "true":
+	case args[0] == "false":
+		exitCode = 1
+	default:
+		output = fmt.Sprintf("/bin/sh: 1: %s: No such file or directory\n", args[0])
+		exitCode = 127
+	}
+	a.log("%s runs '%s'", r.user.Username, body.Command)
+	a.logOutput(fmt.Sprintf("%s-run-%s", a.ID, randSuffix(5)), output)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"exit_code": exitCode, "output": output})
+}
+
+// serveConfig handles /v2/apps/<id>/config/, which also carries limits, tags, registry
+// credentials and healthchecks.
+func (c *Controller) serveConfig(w http.ResponseWriter, r *request, a *app) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a.config)
+	case "POST":
+		var body struct {
+			Values      map[string]interface{} `json:"values"`
+			Memory      map[string]interface{} `json:"memory"`
+			CPU         map[string]interface{} `json:"cpu"`
+			Tags        map[string]interface{} `json:"tags"`
+			Registry    map[string]interface{} `json:"registry"`
+			Healthcheck map[string]interface{} `json:"healthcheck"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for k, v := range body.Tags {
+			if v == nil {
+				continue
+			}
+			if c.NodeLabels[k] != fmt.Sprint(v) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("No nodes matched the provided labels: %s=%v", k, v))
+				return
+			}
+		}
+		if len(body.Registry) > 0 {
+			if _, ok := a.config.Values["PORT"]; !ok {
+				if _, ok := body.Values["PORT"]; !ok {
+					writeFieldError(w, "registry",
+						"PORT needs to be set in the config when using a private registry")
+					return
+				}
+			}
+		}
+		sections := []struct {
+			field    string
+			dst, src map[string]interface{}
+		}{
+			{"values", a.config.Values, body.Values},
+			{"memory", a.config.Memory, body.Memory},
+			{"cpu", a.config.CPU, body.CPU},
+			{"tags", a.config.Tags, body.Tags},
+			{"registry", a.config.Registry, body.Registry},
+			{"healthcheck", a.config.Healthcheck, body.Healthcheck},
+		}
+		for _, s := range sections {
+			if err := validateMerge(s.field, s.dst, s.src); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+		}
+		for _, s := range sections {
+			merge(s.dst, s.src)
+		}
+		a.config.UUID = newUUID()
+		a.config.Updated = now()
+		a.log("config %s updated", a.ID)
+		a.release(r.user.Username, fmt.Sprintf("%s changed the config", r.user.Username), a.currentBuild())
+		writeJSON(w, http.StatusCreated, a.config)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// validateMerge returns an error if src unsets a key that dst does not contain.
+func validateMerge(field string, dst, src map[string]interface{}) error {
+	for k, v := range src {
+		if _, ok := dst[k]; v == nil && !ok {
+			return fmt.Errorf("%s does not exist under %s", k, field)
+		}
+	}
+	return nil
+}
+
+// merge applies src to dst, deleting keys whose value in src is null.
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// serveBuilds handles /v2/apps/<id>/builds/.
+func serveBuilds(w http.ResponseWriter, r *request, a *app) {
+	switch r.Method {
+	case "GET":
+		var results []interface{}
+		for i := len(a.builds) - 1; i >= 0; i-- {
+			results = append(results, a.builds[i])
+		}
+		writeList(w, r, results)
+	case "POST":
+		var body struct {
+			Image      string            `json:"image"`
+			Sha        string            `json:"sha"`
+			Procfile   map[string]string `json:"procfile"`
+			Dockerfile string            `json:"dockerfile"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body.Image == "" {
+			writeFieldError(w, "image", "This field may not be blank.")
+			return
+		}
+		b := &build{
+			UUID:       newUUID(),
+			App:        a.ID,
+			Owner:      r.user.Username,
+			Image:      body.Image,
+			Sha:        body.Sha,
+			Procfile:   body.Procfile,
+			Dockerfile: body.Dockerfile,
+			Created:    now(),
+			Updated:    now(),
+		}
+		a.deploy(b, fmt.Sprintf("%s deployed %s", r.user.Username, b.Image))
+		writeJSON(w, http.StatusCreated, b)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// deploy records a new build of the app and releases it with the specified summary.
+func (a *app) deploy(b *build, summary string) *release {
+	if b.Procfile == nil {
+		b.Procfile = map[string]string{}
+	}
+	a.builds = append(a.builds, b)
+	a.applyStructure(b)
+	a.log("build %s created", a.ID)
+	return a.release(b.Owner, summary, b)
+}
+
+// applyStructure adjusts the app's process types to those of a new build the way the controller
+// does: a build without a Procfile runs a single "cmd" process, and a Procfile's "web" type is
+// scaled to one the first time it appears.
+func (a *app) applyStructure(b *build) {
+	if len(b.Procfile) == 0 {
+		structure := map[string]int{"cmd": 1}
+		if n, ok := a.Structure["cmd"]; ok {
+			structure["cmd"] = n
+		}
+		a.Structure = structure
+		return
+	}
+	structure := map[string]int{}
+	for t := range b.Procfile {
+		structure[t] = a.Structure[t]
+	}
+	if _, ok := a.Structure["web"]; !ok {
+		if _, ok := b.Procfile["web"]; ok {
+			structure["web"] = 1
+		}
+	}
+	a.Structure = structure
+}
+
+// serveReleases handles /v2/apps/<id>/releases/, /v2/apps/<id>/releases/v<N>/ and
+// /v2/apps/<id>/releases/rollback/.
+func serveReleases(w http.ResponseWriter, r *request, a *app) {
+	switch {
+	case r.Method == "GET" && r.segment(4) == "":
+		var results []interface{}
+		for i := len(a.releases) - 1; i >= 0; i-- {
+			results = append(results, a.releases[i])
+		}
+		writeList(w, r, results)
+	case r.Method == "GET":
+		v, err := strconv.Atoi(strings.TrimPrefix(r.segment(4), "v"))
+		if err != nil || v < 1 || v > len(a.releases) {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, a.releases[v-1])
+	case r.Method == "POST" && r.segment(4) == "rollback":
+		var body struct {
+			Version int `json:"version"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		current := a.latestRelease().Version
+		if body.Version == 0 {
+			body.Version = current - 1
+		}
+		if body.Version < 1 || body.Version >= current {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("version cannot be below 0 or above %d", current-1))
+			return
+		}
+		// Like the controller, roll back to the build of that release, and to its process types.
+		b := a.buildOf(a.releases[body.Version-1])
+		if b != nil {
+			a.applyStructure(b)
+		}
+		rel := a.release(r.user.Username, fmt.Sprintf("%s rolled back to v%d", r.user.Username, body.Version), b)
+		writeJSON(w, http.StatusCreated, map[string]int{"version": rel.Version})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// servePods handles /v2/apps/<id>/pods/[<type>/[<name>/]] and the corresponding restart actions.
+func servePods(w http.ResponseWriter, r *request, a *app) {
+	args := r.segments[4:]
+	restart := len(args) > 0 && args[len(args)-1] == "restart"
+	if restart {
+		args = args[:len(args)-1]
+	}
+	if (restart && r.Method != "POST") || (!restart && r.Method != "GET") {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var procType, name string
+	if len(args) > 0 {
+		procType = args[0]
+	}
+	if len(args) > 1 {
+		name = args[1]
+	}
+
+	var results []interface{}
+	for i, p := range a.pods {
+		if (procType != "" && p.Type != procType) || (name != "" && p.Name != name) {
+			continue
+		}
+		if restart {
+			a.pods[i] = a.newPod(p.Type)
+			p = a.pods[i]
+		}
+		results = append(results, p)
+	}
+	if restart {
+		if results == nil {
+			results = []interface{}{}
+		}
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+	writeList(w, r, results)
+}
+
+// serveScale handles /v2/apps/<id>/scale/.
+func serveScale(w http.ResponseWriter, r *request, a *app) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body map[string]int
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if a.currentBuild() == nil {
+		writeError(w, http.StatusBadRequest, "No build associated with this release")
+		return
+	}
+	for t, n := range body {
+		if _, ok := a.Structure[t]; !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Container type %s does not exist in application", t))
+			return
+		}
+		if n < 0 {
+			writeError(w, http.StatusBadRequest, "Must scale to a positive number")
+			return
+		}
+	}
+	for t, n := range body {
+		var kept []*pod
+		count := 0
+		for _, p := range a.pods {
+			if p.Type != t {
+				kept = append(kept, p)
+			} else if count < n {
+				kept = append(kept, p)
+				count++
+			}
+		}
+		for ; count < n; count++ {
+			kept = append(kept, a.newPod(t))
+		}
+		a.pods = kept
+		a.Structure[t] = n
+	}
+	a.log("%s scaled pods %v", r.user.Username, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveDomains handles /v2/apps/<id>/domains/ and /v2/apps/<id>/domains/<domain>.
+func (c *Controller) serveDomains(w http.ResponseWriter, r *request, a *app) {
+	switch {
+	case r.Method == "GET":
+		var results []interface{}
+		for _, d := range a.domains {
+			results = append(results, d)
+		}
+		writeList(w, r, results)
+	case r.Method == "POST":
+		var body struct {
+			Domain string `json:"domain"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if c.findDomain(body.Domain) != nil {
+			writeFieldError(w, "domain", "Domain is already in use by another application")
+			return
+		}
+		d := &domain{App: a.ID, Owner: r.user.Username, Domain: body.Domain, Created: now(), Updated: now()}
+		a.domains = append(a.domains, d)
+		a.log("domain %s added", body.Domain)
+		writeJSON(w, http.StatusCreated, d)
+	case r.Method == "DELETE":
+		name := strings.Join(r.segments[4:], "/")
+		for i, d := range a.domains {
+			if d.Domain == name {
+				a.domains = append(a.domains[:i], a.domains[i+1:]...)
+				for _, crt := range c.certs {
+					crt.detach(name)
+				}
+				a.log("domain %s removed", name)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not found.")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// findDomain returns the app domain record for the given domain name, if any app has it.
+func (c *Controller) findDomain(name string) *domain {
+	for _, a := range c.apps {
+		for _, d := range a.domains {
+			if d.Domain == name {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// servePerms handles /v2/apps/<id>/perms/ and /v2/apps/<id>/perms/<username>/.
+func (c *Controller) servePerms(w http.ResponseWriter, r *request, a *app) {
+	if r.Method != "GET" && !a.canAdminister(r.user) {
+		writeError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+	switch {
+	case r.Method == "GET":
+		users := append([]string{}, a.users...)
+		writeJSON(w, http.StatusOK, map[string][]string{"users": users})
+	case r.Method == "POST":
+		var body struct {
+			Username string `json:"username"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := c.users[body.Username]; !ok {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		if !a.isCollaborator(body.Username) {
+			a.users = append(a.users, body.Username)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "DELETE":
+		if !a.isCollaborator(r.segment(4)) {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		a.removeCollaborator(r.segment(4))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveSettings handles /v2/apps/<id>/settings/, which carries maintenance mode, routability and
+// labels.
+func serveSettings(w http.ResponseWriter, r *request, a *app) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a.settings)
+	case "POST":
+		var body struct {
+			Maintenance *bool                  `json:"maintenance"`
+			Routable    *bool                  `json:"routable"`
+			Label       map[string]interface{} `json:"label"`
+			Autoscale   map[string]interface{} `json:"autoscale"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := validateMerge("label", a.settings.Label, body.Label); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if err := validateMerge("autoscale", a.settings.Autoscale, body.Autoscale); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if body.Maintenance != nil {
+			a.settings.Maintenance = *body.Maintenance
+		}
+		if body.Routable != nil {
+			a.settings.Routable = *body.Routable
+		}
+		merge(a.settings.Label, body.Label)
+		merge(a.settings.Autoscale, body.Autoscale)
+		a.settings.UUID = newUUID()
+		a.settings.Updated = now()
+		a.log("appsettings %s updated", a.ID)
+		writeJSON(w, http.StatusCreated, a.settings)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveWhitelist handles /v2/apps/<id>/whitelist/.
+func serveWhitelist(w http.ResponseWriter, r *request, a *app) {
+	var body struct {
+		Addresses []string `json:"addresses"`
+	}
+	if r.Method != "GET" {
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, map[string][]string{"addresses": a.settings.Whitelist})
+	case "POST":
+		for _, addr := range body.Addresses {
+			if indexOf(a.settings.Whitelist, addr) < 0 {
+				a.settings.Whitelist = append(a.settings.Whitelist, addr)
+			}
+		}
+		a.log("appsettings %s updated", a.ID)
+		writeJSON(w, http.StatusCreated, map[string][]string{"addresses": a.settings.Whitelist})
+	case "DELETE":
+		for _, addr := range body.Addresses {
+			if indexOf(a.settings.Whitelist, addr) < 0 {
+				writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s does not exist in whitelist", addr))
+				return
+			}
+		}
+		for _, addr := range body.Addresses {
+			i := indexOf(a.settings.Whitelist, addr)
+			a.settings.Whitelist = append(a.settings.Whitelist[:i], a.settings.Whitelist[i+1:]...)
+		}
+		a.log("appsettings %s updated", a.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// serveTLS handles /v2/apps/<id>/tls/.
+func serveTLS(w http.ResponseWriter, r *request, a *app) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a.tls)
+	case "POST":
+		var body struct {
+			HTTPSEnforced *bool `json:"https_enforced"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body.HTTPSEnforced != nil {
+			a.tls.HTTPSEnforced = *body.HTTPSEnforced
+		}
+		a.tls.UUID = newUUID()
+		a.tls.Updated = now()
+		writeJSON(w, http.StatusCreated, a.tls)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
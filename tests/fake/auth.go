@@ -0,0 +1,356 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+)
+
+type user struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	IsSuperuser bool   `json:"is_superuser"`
+	IsStaff     bool   `json:"is_staff"`
+	IsActive    bool   `json:"is_active"`
+	LastLogin   string `json:"last_login"`
+	DateJoined  string `json:"date_joined"`
+	password    string
+}
+
+type key struct {
+	UUID    string `json:"uuid"`
+	ID      string `json:"id"`
+	Owner   string `json:"owner"`
+	Public  string `json:"public"`
+	Created string `json:"created"`
+	Updated string `json:"updated"`
+}
+
+// serveAuth handles /v2/auth/<action>/.
+func (c *Controller) serveAuth(w http.ResponseWriter, r *request) {
+	switch r.segment(2) {
+	case "register":
+		c.register(w, r)
+	case "login":
+		c.login(w, r)
+	case "logout":
+		w.WriteHeader(http.StatusNoContent)
+	case "whoami":
+		writeJSON(w, http.StatusOK, r.user)
+	case "cancel":
+		c.cancel(w, r)
+	case "tokens":
+		c.regenerate(w, r)
+	case "passwd":
+		c.passwd(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (c *Controller) register(w http.ResponseWriter, r *request) {
+	var body struct {
+		Username  string `json:"username"`
+		Password  string `json:"password"`
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Username == "" {
+		writeFieldError(w, "username", "This field may not be blank.")
+		return
+	}
+	if _, ok := c.users[body.Username]; ok {
+		writeFieldError(w, "username", "A user with that username already exists.")
+		return
+	}
+	// Like the real controller, the first user to register becomes an administrator.
+	u := &user{
+		Username:    body.Username,
+		Email:       body.Email,
+		FirstName:   body.FirstName,
+		LastName:    body.LastName,
+		IsSuperuser: len(c.users) == 0,
+		IsActive:    true,
+		DateJoined:  now(),
+		password:    body.Password,
+	}
+	u.IsStaff = u.IsSuperuser
+	c.users[u.Username] = u
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func (c *Controller) login(w http.ResponseWriter, r *request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, ok := c.users[body.Username]
+	if !ok || u.password != body.Password {
+		writeFieldError(w, "non_field_errors", "Unable to log in with provided credentials.")
+		return
+	}
+	u.LastLogin = now()
+	writeJSON(w, http.StatusOK, map[string]string{"token": c.issueToken(u.Username)})
+}
+
+// issueToken returns the user's current token, creating one if the user has none.
+func (c *Controller) issueToken(username string) string {
+	for token, owner := range c.tokens {
+		if owner == username {
+			return token
+		}
+	}
+	token := newToken()
+	c.tokens[token] = username
+	return token
+}
+
+func (c *Controller) revokeTokens(username string) {
+	for token, owner := range c.tokens {
+		if owner == username {
+			delete(c.tokens, token)
+		}
+	}
+}
+
+func (c *Controller) cancel(w http.ResponseWriter, r *request) {
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	target := r.user
+	if body.Username != "" && body.Username != r.user.Username {
+		if !r.user.IsSuperuser {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+		if target = c.users[body.Username]; target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+	}
+	for _, a := range c.apps {
+		if a.Owner == target.Username {
+			writeError(w, http.StatusConflict,
+				"This user cannot be deleted as they still own applications. Please transfer or delete them first.")
+			return
+		}
+	}
+	c.revokeTokens(target.Username)
+	for id, k := range c.keys {
+		if k.Owner == target.Username {
+			delete(c.keys, id)
+		}
+	}
+	for name, crt := range c.certs {
+		if crt.Owner == target.Username {
+			delete(c.certs, name)
+		}
+	}
+	for _, a := range c.apps {
+		a.removeCollaborator(target.Username)
+	}
+	delete(c.users, target.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Controller) regenerate(w http.ResponseWriter, r *request) {
+	var body struct {
+		Username string `json:"username"`
+		All      bool   `json:"all"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.All || (body.Username != "" && body.Username != r.user.Username) {
+		if !r.user.IsSuperuser {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+		if body.All {
+			c.tokens = map[string]string{}
+			writeJSON(w, http.StatusOK, map[string]string{})
+			return
+		}
+		if c.users[body.Username] == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		c.revokeTokens(body.Username)
+		writeJSON(w, http.StatusOK, map[string]string{})
+		return
+	}
+	c.revokeTokens(r.user.Username)
+	writeJSON(w, http.StatusOK, map[string]string{"token": c.issueToken(r.user.Username)})
+}
+
+func (c *Controller) passwd(w http.ResponseWriter, r *request) {
+	var body struct {
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	target := r.user
+	if body.Username != "" && body.Username != r.user.Username {
+		if !r.user.IsSuperuser {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+		if target = c.users[body.Username]; target == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+	} else if target.password != body.Password {
+		writeFieldError(w, "detail", "Current password does not match")
+		return
+	}
+	target.password = body.NewPassword
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveUsers handles /v2/users/, which only administrators may list.
+func (c *Controller) serveUsers(w http.ResponseWriter, r *request) {
+	if !r.user.IsSuperuser {
+		writeError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+	var results []interface{}
+	for _, name := range c.sortedUsernames() {
+		results = append(results, c.users[name])
+	}
+	writeList(w, r, results)
+}
+
+// serveAdminPerms handles /v2/admin/perms/ and /v2/admin/perms/<username>/.
+func (c *Controller) serveAdminPerms(w http.ResponseWriter, r *request) {
+	if r.segment(2) != "perms" {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if !r.user.IsSuperuser {
+		writeError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.segment(3) == "":
+		var results []interface{}
+		for _, name := range c.sortedUsernames() {
+			if u := c.users[name]; u.IsSuperuser {
+				results = append(results, map[string]interface{}{"username": u.Username, "is_superuser": true})
+			}
+		}
+		writeList(w, r, results)
+	case r.Method == "POST" && r.segment(3) == "":
+		var body struct {
+			Username string `json:"username"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		u, ok := c.users[body.Username]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		u.IsSuperuser, u.IsStaff = true, true
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "DELETE":
+		u, ok := c.users[r.segment(3)]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		u.IsSuperuser, u.IsStaff = false, false
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveKeys handles /v2/keys/ and /v2/keys/<id>/.
+func (c *Controller) serveKeys(w http.ResponseWriter, r *request) {
+	switch {
+	case r.Method == "GET" && r.segment(2) == "":
+		var ids []string
+		for id, k := range c.keys {
+			if k.Owner == r.user.Username {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		var results []interface{}
+		for _, id := range ids {
+			results = append(results, c.keys[id])
+		}
+		writeList(w, r, results)
+	case r.Method == "POST" && r.segment(2) == "":
+		var body struct {
+			ID     string `json:"id"`
+			Public string `json:"public"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := c.keys[body.ID]; ok {
+			writeFieldError(w, "id", "Key with this id already exists.")
+			return
+		}
+		for _, k := range c.keys {
+			if k.Public == body.Public {
+				writeFieldError(w, "public", "Key with this public already exists.")
+				return
+			}
+		}
+		k := &key{
+			UUID:    newUUID(),
+			ID:      body.ID,
+			Owner:   r.user.Username,
+			Public:  body.Public,
+			Created: now(),
+			Updated: now(),
+		}
+		c.keys[k.ID] = k
+		writeJSON(w, http.StatusCreated, k)
+	case r.Method == "DELETE":
+		// Like the real controller, only a key's owner can see it, so not even an admin can delete
+		// the keys of other users.
+		k, ok := c.keys[r.segment(2)]
+		if !ok || k.Owner != r.user.Username {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		delete(c.keys, k.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *Controller) sortedUsernames() []string {
+	var names []string
+	for name := range c.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
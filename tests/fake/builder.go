@@ -0,0 +1,496 @@
+package fake
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The types and functions in this file implement a stand-in for deis-builder's SSH server. It
+// accepts `git push`es from the holders of keys registered with a controller, stores them in bare
+// repositories on disk and then plays out a scripted build, so that the git helpers, and the specs
+// that push apps, can be exercised without a Workflow cluster.
+
+const (
+	// BuildStartMessage is the first line of every build's output, as deis-builder writes it.
+	BuildStartMessage = "Starting build... but first, coffee!"
+	// PushOngoingMessage is the error with which a push is refused while a build of an earlier
+	// push to the same app is still running.
+	PushOngoingMessage = "Another git push is ongoing"
+	// BadBuildpackMessage is the last line of the output of a build that fails with BadBuildpack.
+	BadBuildpackMessage = "-----> Unable to select a buildpack"
+	// DefaultBuildTimeout is how long a build that fails with Timeout runs, unless the Builder's
+	// BuildTimeout says otherwise.
+	DefaultBuildTimeout = 10 * time.Second
+)
+
+// Failure is the way in which a scripted build fails.
+type Failure int
+
+const (
+	// NoFailure lets the build succeed, deploying the push as a new release of the app.
+	NoFailure Failure = iota
+	// BadBuildpack fails the build as when no buildpack recognizes the app.
+	BadBuildpack
+	// Timeout runs the build until the Builder's BuildTimeout has elapsed, then fails it.
+	Timeout
+	// Disconnect drops the connection once the build's output has been written, so that the
+	// client never learns how the build ended.
+	Disconnect
+)
+
+// Build scripts what the builder does once it has received a push.
+type Build struct {
+	// Output are the lines written to the client after BuildStartMessage, Interval apart.
+	Output   []string
+	Interval time.Duration
+	Failure  Failure
+}
+
+// DefaultBuild is the build of any app for which no other has been scripted.
+var DefaultBuild = Build{Output: []string{
+	"-----> Restoring cache...",
+	"-----> Go app detected",
+	"-----> Discovering process types",
+	"-----> Compiled slug size is 1.9M",
+	"Build complete.",
+	"Launching App...",
+}}
+
+// Hooks are the calls that the builder makes of a controller, as deis-builder makes of Workflow's.
+type Hooks interface {
+	// UserForKey returns the name of the user who registered the specified key, if any did.
+	UserForKey(key ssh.PublicKey) (string, bool)
+	// CanPush returns whether the specified user may push to the specified app.
+	CanPush(username, app string) bool
+	// Deploy records a build of the specified commit, whose Procfile (if it has one) declares the
+	// specified process types, and returns the version of the release that deploys it.
+	Deploy(username, app, sha string, procfile map[string]string) (int, error)
+}
+
+// Builder is an in-memory stand-in for deis-builder.
+type Builder struct {
+	// Addr is the host:port at which the builder accepts SSH connections.
+	Addr string
+	// BuildTimeout is how long a build that fails with Timeout runs. If it is zero,
+	// DefaultBuildTimeout is used.
+	BuildTimeout time.Duration
+
+	hooks    Hooks
+	config   *ssh.ServerConfig
+	listener net.Listener
+	dir      string
+	closed   chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	builds   map[string]Build
+	pushing  map[string]bool
+	conns    map[*ssh.ServerConn]bool
+}
+
+// NewBuilder starts a new builder listening at the specified address, such as "127.0.0.1:0",
+// which authenticates users and deploys their pushes by way of the specified hooks.
+func NewBuilder(addr string, hooks Hooks) (*Builder, error) {
+	hostKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "fake-builder")
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	b := &Builder{
+		Addr:     listener.Addr().String(),
+		hooks:    hooks,
+		listener: listener,
+		dir:      dir,
+		closed:   make(chan struct{}),
+		builds:   map[string]Build{},
+		pushing:  map[string]bool{},
+		conns:    map[*ssh.ServerConn]bool{},
+	}
+	b.config = &ssh.ServerConfig{PublicKeyCallback: b.authenticate}
+	b.config.AddHostKey(signer)
+	b.wg.Add(1)
+	go b.accept()
+	return b, nil
+}
+
+// Close shuts the builder down, ending any builds still running, and deletes every repository
+// pushed to it.
+func (b *Builder) Close() {
+	close(b.closed)
+	b.listener.Close()
+	b.mu.Lock()
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+	os.RemoveAll(b.dir)
+}
+
+// Script arranges for every later push to the specified app to be built as described.
+func (b *Builder) Script(app string, build Build) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.builds[app] = build
+}
+
+// Pushing returns whether a push to the specified app is being received or built.
+func (b *Builder) Pushing(app string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pushing[app]
+}
+
+func (b *Builder) authenticate(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	username, ok := b.hooks.UserForKey(key)
+	if !ok {
+		sum := sha256.Sum256(key.Marshal())
+		return nil, fmt.Errorf("no user has registered the key SHA256:%s", base64.RawStdEncoding.EncodeToString(sum[:]))
+	}
+	return &ssh.Permissions{Extensions: map[string]string{"user": username}}, nil
+}
+
+func (b *Builder) accept() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.wg.Add(1)
+		go b.serveConn(conn)
+	}
+}
+
+func (b *Builder) serveConn(conn net.Conn) {
+	defer b.wg.Done()
+	sconn, channels, requests, err := ssh.NewServerConn(conn, b.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	b.mu.Lock()
+	b.conns[sconn] = true
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, sconn)
+		b.mu.Unlock()
+		sconn.Close()
+	}()
+
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		b.wg.Add(1)
+		go b.serveSession(sconn, channel, requests)
+	}
+}
+
+// serveSession handles the requests made of a session, of which only an exec of git-receive-pack
+// is honored. Like the builder, it refuses any other request, such as for a shell, but leaves the
+// session open.
+func (b *Builder) serveSession(sconn *ssh.ServerConn, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer b.wg.Done()
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			// GIT_PROTOCOL is ignored, so every client falls back to version 0 of the protocol.
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				exit(channel, b.exec(sconn, channel, payload.Command))
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// exec executes the specified command as the authenticated user and returns its exit status, or
+// -1 if the connection was dropped instead.
+func (b *Builder) exec(sconn *ssh.ServerConn, channel ssh.Channel, command string) int {
+	username := sconn.Permissions.Extensions["user"]
+	app, ok := receivePackApp(command)
+	if !ok {
+		fmt.Fprintf(channel.Stderr(), "Unsupported command: %s\n", command)
+		return 1
+	}
+	if !b.hooks.CanPush(username, app) {
+		remoteError(channel, fmt.Sprintf("User %s does not have permission to push to %s", username, app))
+		return 1
+	}
+	if !b.lock(app) {
+		remoteError(channel, PushOngoingMessage)
+		return 1
+	}
+	// The build carries on even if the client goes away, and holds the lock until it is done, as
+	// deis-builder's does.
+	defer b.unlock(app)
+
+	repo, err := b.repo(app)
+	if err != nil {
+		remoteError(channel, err.Error())
+		return 1
+	}
+	before := revParse(repo)
+	receive := exec.Command("git", "receive-pack", repo)
+	stdin, err := receive.StdinPipe()
+	if err != nil {
+		remoteError(channel, err.Error())
+		return 1
+	}
+	receive.Stdout = lenient{w: channel}
+	receive.Stderr = lenient{w: channel.Stderr()}
+	if err := receive.Start(); err != nil {
+		remoteError(channel, err.Error())
+		return 1
+	}
+	go func() {
+		io.Copy(stdin, channel)
+		stdin.Close()
+	}()
+	if err := receive.Wait(); err != nil {
+		return 1
+	}
+	sha := revParse(repo)
+	if sha == "" || sha == before {
+		return 0
+	}
+
+	out := lenient{w: channel.Stderr()}
+	build := b.script(app)
+	fmt.Fprintln(out, BuildStartMessage)
+	for i, line := range build.Output {
+		if i > 0 && !b.sleep(build.Interval) {
+			return 1
+		}
+		fmt.Fprintln(out, line)
+	}
+	switch build.Failure {
+	case BadBuildpack:
+		fmt.Fprintln(out, BadBuildpackMessage)
+		return 1
+	case Timeout:
+		timeout := b.BuildTimeout
+		if timeout == 0 {
+			timeout = DefaultBuildTimeout
+		}
+		b.sleep(timeout)
+		fmt.Fprintf(out, "Timed out after %s waiting for the build of %s to finish\n", timeout, app)
+		return 1
+	case Disconnect:
+		sconn.Close()
+		return -1
+	}
+	version, err := b.hooks.Deploy(username, app, sha, procfile(repo, sha))
+	if err != nil {
+		fmt.Fprintf(out, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Done, %s:v%d deployed to Workflow\n\n", app, version)
+	fmt.Fprintln(out, "Use 'deis open' to view this application in your browser")
+	return 0
+}
+
+func (b *Builder) script(app string) Build {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if build, ok := b.builds[app]; ok {
+		return build
+	}
+	return DefaultBuild
+}
+
+// lock claims the right to push to the specified app, returning false if another push holds it.
+func (b *Builder) lock(app string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pushing[app] {
+		return false
+	}
+	b.pushing[app] = true
+	return true
+}
+
+func (b *Builder) unlock(app string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pushing, app)
+}
+
+// sleep waits for the specified duration, returning false if the builder is closed first.
+func (b *Builder) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-b.closed:
+		return false
+	}
+}
+
+// repo returns the path to the bare repository for the specified app, creating it if necessary.
+func (b *Builder) repo(app string) (string, error) {
+	repo := filepath.Join(b.dir, app+".git")
+	if _, err := os.Stat(repo); err == nil {
+		return repo, nil
+	}
+	if out, err := exec.Command("git", "init", "--bare", "--quiet", repo).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("could not create a repository for %s: %s", app, out)
+	}
+	return repo, nil
+}
+
+// receivePackApp returns the app named by a git-receive-pack command, such as
+// "git-receive-pack '/myapp.git'".
+func receivePackApp(command string) (string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) != 2 || fields[0] != "git-receive-pack" {
+		return "", false
+	}
+	app := strings.Trim(fields[1], `'"`)
+	app = strings.TrimSuffix(strings.TrimPrefix(app, "/"), ".git")
+	if app == "" || strings.ContainsAny(app, "/.") {
+		return "", false
+	}
+	return app, true
+}
+
+// revParse returns the commit that the master branch of the specified repository refers to, or ""
+// if it has none.
+func revParse(repo string) string {
+	out, err := exec.Command("git", "--git-dir", repo, "rev-parse", "--verify", "--quiet", "refs/heads/master").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// procfile returns the process types declared by the Procfile of the specified commit, or nil if
+// it has none.
+func procfile(repo, sha string) map[string]string {
+	out, err := exec.Command("git", "--git-dir", repo, "show", sha+":Procfile").Output()
+	if err != nil {
+		return nil
+	}
+	types := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
+			types[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return types
+}
+
+// remoteError sends the specified message as a pkt-line error, which git reports as
+// "fatal: remote error: <message>".
+func remoteError(w io.Writer, message string) {
+	line := "ERR " + message + "\n"
+	fmt.Fprintf(w, "%04x%s", len(line)+4, line)
+}
+
+// exit reports the specified exit status, if it is not -1, and closes the channel.
+func exit(channel ssh.Channel, status int) {
+	if status >= 0 {
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	}
+	channel.Close()
+}
+
+// lenient is a writer that carries on accepting writes after the writer it wraps has failed, so
+// that a process whose output it is does not block once the client has gone.
+type lenient struct {
+	w io.Writer
+}
+
+func (l lenient) Write(p []byte) (int, error) {
+	l.w.Write(p)
+	return len(p), nil
+}
+
+// UserForKey returns the user who registered the specified key with the fake controller.
+func (c *Controller) UserForKey(key ssh.PublicKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range c.keys {
+		registered, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.Public))
+		if err == nil && bytes.Equal(registered.Marshal(), key.Marshal()) {
+			return k.Owner, true
+		}
+	}
+	return "", false
+}
+
+// CanPush returns whether the specified user may deploy the specified app.
+func (c *Controller) CanPush(username, appID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, u := c.apps[appID], c.users[username]
+	return a != nil && u != nil && a.canAccess(u)
+}
+
+// Deploy records a build of the specified commit of the app, and releases it.
+func (c *Controller) Deploy(username, appID, sha string, procfile map[string]string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, ok := c.apps[appID]
+	if !ok {
+		return 0, fmt.Errorf("app %s does not exist", appID)
+	}
+	b := &build{
+		UUID:     newUUID(),
+		App:      a.ID,
+		Owner:    username,
+		Image:    fmt.Sprintf("%s:git-%s", a.ID, sha[:8]),
+		Sha:      sha,
+		Procfile: procfile,
+		Created:  now(),
+		Updated:  now(),
+	}
+	return a.deploy(b, fmt.Sprintf("%s deployed %s", username, sha[:7])).Version, nil
+}
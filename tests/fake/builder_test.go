@@ -0,0 +1,325 @@
+package fake
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+// keyring are the Hooks of a builder with no controller behind it. The holder of any key added to
+// it may push to any app, and each push is numbered as the next release of the app.
+type keyring struct {
+	mu       sync.Mutex
+	keys     map[string]string
+	versions map[string]int
+}
+
+func newKeyring() *keyring {
+	return &keyring{keys: map[string]string{}, versions: map[string]int{}}
+}
+
+// add registers the specified public key, in the authorized_keys format, as belonging to the
+// specified user.
+func (k *keyring) add(username, public string) error {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(public))
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[string(key.Marshal())] = username
+	return nil
+}
+
+func (k *keyring) UserForKey(key ssh.PublicKey) (string, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	username, ok := k.keys[string(key.Marshal())]
+	return username, ok
+}
+
+func (k *keyring) CanPush(username, app string) bool {
+	return true
+}
+
+func (k *keyring) Deploy(username, app, sha string, procfile map[string]string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.versions[app] == 0 {
+		k.versions[app] = 1
+	}
+	k.versions[app]++
+	return k.versions[app], nil
+}
+
+var _ = Describe("fake builder", func() {
+
+	var (
+		b       *Builder
+		dir     string
+		keyPath string
+		public  string
+	)
+
+	// git executes git in the app's working copy, failing the spec if it fails.
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=e2e", "-c", "user.email=e2e@deis.io"}, args...)...)
+		cmd.Dir = filepath.Join(dir, "app")
+		out, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+	}
+
+	// commit commits a file with the specified name and contents to the app's working copy.
+	commit := func(name, contents string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "app", name), []byte(contents), 0644)).To(Succeed())
+		git("add", name)
+		git("commit", "--quiet", "-m", "add "+name)
+	}
+
+	// push starts a `git push deis master` of the app's working copy using the key at keyPath.
+	push := func() *gexec.Session {
+		cmd := exec.Command("git", "push", "deis", "master")
+		cmd.Dir = filepath.Join(dir, "app")
+		cmd.Env = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes "+
+			"-o BatchMode=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null", keyPath))
+		sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		return sess
+	}
+
+	// start starts a builder with the specified hooks, and points the app's working copy at it.
+	start := func(hooks Hooks, app string) {
+		var err error
+		b, err = NewBuilder("127.0.0.1:0", hooks)
+		Expect(err).NotTo(HaveOccurred())
+		git("remote", "add", "deis", fmt.Sprintf("ssh://git@%s/%s.git", b.Addr, app))
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "fake-builder-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		keyPath = filepath.Join(dir, "id_ecdsa")
+		Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+		sshKey, err := ssh.NewPublicKey(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		public = string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(sshKey)))
+
+		out, err := exec.Command("git", "init", "--quiet", filepath.Join(dir, "app")).CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+		git("checkout", "--quiet", "-b", "master")
+		commit("index.html", "Powered by Deis")
+	})
+
+	AfterEach(func() {
+		if b != nil {
+			b.Close()
+			b = nil
+		}
+		os.RemoveAll(dir)
+	})
+
+	Context("standing in for the builder of the fake controller", func() {
+
+		var c *Controller
+
+		// call issues a request to the fake controller with the given token and decodes any JSON
+		// response into out.
+		call := func(method, path, token string, body, out interface{}) int {
+			var buf bytes.Buffer
+			Expect(json.NewEncoder(&buf).Encode(body)).To(Succeed())
+			req, err := http.NewRequest(method, c.URL+path, &buf)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Authorization", "token "+token)
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			if out != nil {
+				Expect(json.NewDecoder(resp.Body).Decode(out)).To(Succeed())
+			}
+			return resp.StatusCode
+		}
+
+		register := func(username string) string {
+			creds := map[string]string{"username": username, "password": "asdf1234", "email": username + "@deis.io"}
+			Expect(call("POST", "/v2/auth/register/", "", creds, nil)).To(Equal(http.StatusCreated))
+			var login struct {
+				Token string `json:"token"`
+			}
+			Expect(call("POST", "/v2/auth/login/", "", creds, &login)).To(Equal(http.StatusOK))
+			return login.Token
+		}
+
+		var ownerToken string
+
+		BeforeEach(func() {
+			c = NewController("k8s.local")
+			ownerToken = register("owner")
+			Expect(call("POST", "/v2/apps/", ownerToken, map[string]string{"id": "myapp"}, nil)).To(Equal(http.StatusCreated))
+			start(c, "myapp")
+		})
+
+		AfterEach(func() {
+			c.Close()
+		})
+
+		Specify("a push by the owner of the app, with a registered key, is deployed", func() {
+			key := map[string]string{"id": "owner@e2e", "public": public}
+			Expect(call("POST", "/v2/keys/", ownerToken, key, nil)).To(Equal(http.StatusCreated))
+			commit("Procfile", "web: ./server\nworker: ./worker\n")
+
+			sess := push()
+			Eventually(sess.Err).Should(Say(BuildStartMessage))
+			Eventually(sess.Err).Should(Say(`Done, myapp:v2 deployed to Workflow`))
+			Eventually(sess).Should(gexec.Exit(0))
+
+			var builds struct {
+				Results []build `json:"results"`
+			}
+			Expect(call("GET", "/v2/apps/myapp/builds/", ownerToken, nil, &builds)).To(Equal(http.StatusOK))
+			Expect(builds.Results).To(HaveLen(1))
+			Expect(builds.Results[0].Owner).To(Equal("owner"))
+			Expect(builds.Results[0].Procfile).To(Equal(map[string]string{"web": "./server", "worker": "./worker"}))
+			var a app
+			Expect(call("GET", "/v2/apps/myapp/", ownerToken, nil, &a)).To(Equal(http.StatusOK))
+			Expect(a.Structure).To(Equal(map[string]int{"web": 1, "worker": 0}))
+		})
+
+		Specify("a push with a key that no one has registered is refused", func() {
+			sess := push()
+			Eventually(sess.Err).Should(Say("Permission denied"))
+			Eventually(sess).Should(gexec.Exit(128))
+		})
+
+		Specify("a push by a user who cannot access the app is refused", func() {
+			token := register("other")
+			key := map[string]string{"id": "other@e2e", "public": public}
+			Expect(call("POST", "/v2/keys/", token, key, nil)).To(Equal(http.StatusCreated))
+
+			sess := push()
+			Eventually(sess.Err).Should(Say("fatal: remote error: User other does not have permission to push to myapp"))
+			Eventually(sess).Should(gexec.Exit(128))
+		})
+
+	})
+
+	Context("with no controller behind it", func() {
+
+		BeforeEach(func() {
+			keys := newKeyring()
+			Expect(keys.add("test-1", public)).To(Succeed())
+			start(keys, "myapp")
+		})
+
+		Specify("a request for a shell is refused once the key is accepted", func() {
+			host, port, err := net.SplitHostPort(b.Addr)
+			Expect(err).NotTo(HaveOccurred())
+			cmd := exec.Command("ssh", "-T", "-p", port, "-i", keyPath, "-o", "IdentitiesOnly=yes", "-o", "BatchMode=yes",
+				"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "git@"+host)
+			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(sess.Err).Should(Say("shell request failed on channel 0"))
+			Eventually(sess).Should(gexec.Exit())
+		})
+
+		Specify("build output is streamed to the client as it is written", func() {
+			b.Script("myapp", Build{Output: []string{"-----> first", "-----> second"}, Interval: time.Second})
+			sess := push()
+			Eventually(sess.Err).Should(Say(BuildStartMessage))
+			Eventually(sess.Err).Should(Say("-----> first"))
+			Consistently(sess.Err, 500*time.Millisecond).ShouldNot(Say("-----> second"))
+			Eventually(sess.Err, 2*time.Second).Should(Say("-----> second"))
+			Eventually(sess.Err).Should(Say(`Done, myapp:v2 deployed to Workflow`))
+			Eventually(sess).Should(gexec.Exit(0))
+
+			b.Script("myapp", DefaultBuild)
+			commit("Procfile", "web: ./server\n")
+			sess = push()
+			Eventually(sess.Err).Should(Say(`Done, myapp:v3 deployed to Workflow`))
+			Eventually(sess).Should(gexec.Exit(0))
+		})
+
+		Specify("a push that changes nothing is not built", func() {
+			Eventually(push()).Should(gexec.Exit(0))
+			sess := push()
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Err).To(Say("Everything up-to-date"))
+			Expect(sess.Err).NotTo(Say(BuildStartMessage))
+		})
+
+		Specify("a push is refused while the build of an interrupted push is still running", func() {
+			b.BuildTimeout = 2 * time.Second
+			b.Script("myapp", Build{Failure: Timeout})
+			sess := push()
+			Eventually(sess.Err).Should(Say(BuildStartMessage))
+			// The interrupted push's ssh lives on until the build ends, so don't wait for it to exit.
+			sess.Interrupt()
+
+			sess = push()
+			Eventually(sess.Err).ShouldNot(Say("exec request failed on channel 0"))
+			Eventually(sess.Err).Should(Say("fatal: remote error: " + PushOngoingMessage))
+			Eventually(sess).Should(gexec.Exit(128))
+
+			Eventually(func() bool { return b.Pushing("myapp") }, 5*time.Second).Should(BeFalse())
+			b.Script("myapp", DefaultBuild)
+			commit("Procfile", "web: ./server\n")
+			sess = push()
+			Eventually(sess.Err).Should(Say(`Done, myapp:v2 deployed to Workflow`))
+			Eventually(sess).Should(gexec.Exit(0))
+		})
+
+		Specify("a build can fail for want of a buildpack", func() {
+			b.Script("myapp", Build{Output: []string{"-----> Restoring cache..."}, Failure: BadBuildpack})
+			sess := push()
+			Eventually(sess.Err).Should(Say(BadBuildpackMessage))
+			Eventually(sess).Should(gexec.Exit())
+			Expect(sess.ExitCode()).NotTo(Equal(0))
+		})
+
+		Specify("a build can time out", func() {
+			b.BuildTimeout = 500 * time.Millisecond
+			b.Script("myapp", Build{Failure: Timeout})
+			sess := push()
+			Eventually(sess.Err).Should(Say("Timed out after 500ms waiting for the build of myapp to finish"))
+			Eventually(sess).Should(gexec.Exit())
+			Expect(sess.ExitCode()).NotTo(Equal(0))
+		})
+
+		Specify("a build can end with the connection dropped", func() {
+			b.Script("myapp", Build{Output: []string{"-----> Compiling app"}, Failure: Disconnect})
+			sess := push()
+			Eventually(sess.Err).Should(Say("-----> Compiling app"))
+			Eventually(sess).Should(gexec.Exit())
+			Expect(sess.ExitCode()).NotTo(Equal(0))
+			Expect(sess.Err).NotTo(Say("Done"))
+			Eventually(func() bool { return b.Pushing("myapp") }).Should(BeFalse())
+		})
+
+	})
+
+})
@@ -0,0 +1,184 @@
+package fake
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var certNameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type cert struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Owner       string   `json:"owner"`
+	CommonName  string   `json:"common_name"`
+	SubjectAlt  []string `json:"san"`
+	Domains     []string `json:"domains"`
+	Fingerprint string   `json:"fingerprint"`
+	Issuer      string   `json:"issuer"`
+	Subject     string   `json:"subject"`
+	Starts      string   `json:"starts"`
+	Expires     string   `json:"expires"`
+	Created     string   `json:"created"`
+	Updated     string   `json:"updated"`
+}
+
+func (crt *cert) detach(domain string) {
+	if i := indexOf(crt.Domains, domain); i >= 0 {
+		crt.Domains = append(crt.Domains[:i], crt.Domains[i+1:]...)
+	}
+}
+
+// serveCerts handles /v2/certs/, /v2/certs/<name>/ and /v2/certs/<name>/domain/[<domain>/].
+func (c *Controller) serveCerts(w http.ResponseWriter, r *request) {
+	if r.segment(2) == "" {
+		switch r.Method {
+		case "GET":
+			var names []string
+			for name := range c.certs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var results []interface{}
+			for _, name := range names {
+				results = append(results, c.certs[name])
+			}
+			writeList(w, r, results)
+		case "POST":
+			c.createCert(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	crt, ok := c.certs[r.segment(2)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if crt.Owner != r.user.Username && !r.user.IsSuperuser {
+		writeError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	switch {
+	case r.segment(3) == "" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, crt)
+	case r.segment(3) == "" && r.Method == "DELETE":
+		delete(c.certs, crt.Name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.segment(3) == "domain" && r.Method == "POST":
+		var body struct {
+			Domain string `json:"domain"`
+		}
+		if err := r.decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if c.findDomain(body.Domain) == nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		if indexOf(crt.Domains, body.Domain) < 0 {
+			crt.Domains = append(crt.Domains, body.Domain)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.segment(3) == "domain" && r.Method == "DELETE":
+		domain := strings.Join(r.segments[4:], "/")
+		if indexOf(crt.Domains, domain) < 0 {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		crt.detach(domain)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *Controller) createCert(w http.ResponseWriter, r *request) {
+	var body struct {
+		Name        string `json:"name"`
+		Certificate string `json:"certificate"`
+		Key         string `json:"key"`
+	}
+	if err := r.decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !certNameRegexp.MatchString(body.Name) {
+		writeFieldError(w, "name", "Can only contain a-z (lowercase), 0-9 and hyphens")
+		return
+	}
+	if _, ok := c.certs[body.Name]; ok {
+		writeFieldError(w, "name", "Certificate with this name already exists.")
+		return
+	}
+	pair, err := tls.X509KeyPair([]byte(body.Certificate), []byte(body.Key))
+	if err != nil {
+		writeFieldError(w, "certificate", fmt.Sprintf("Could not load certificate: %s", err))
+		return
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		writeFieldError(w, "certificate", fmt.Sprintf("Could not load certificate: %s", err))
+		return
+	}
+
+	// Refuse certificates that have already expired, but not those that are merely not yet valid,
+	// which may be uploaded ahead of time.
+	if time.Now().After(leaf.NotAfter) {
+		writeFieldError(w, "certificate", fmt.Sprintf("Could not load certificate: certificate expired at %s", leaf.NotAfter.UTC().Format(timeFormat)))
+		return
+	}
+
+	crt := &cert{
+		ID:          c.nextCertID(),
+		Name:        body.Name,
+		Owner:       r.user.Username,
+		CommonName:  leaf.Subject.CommonName,
+		SubjectAlt:  leaf.DNSNames,
+		Domains:     []string{},
+		Fingerprint: fingerprint(leaf.Raw),
+		Issuer:      fmt.Sprintf("/CN=%s", leaf.Issuer.CommonName),
+		Subject:     fmt.Sprintf("/CN=%s", leaf.Subject.CommonName),
+		Starts:      leaf.NotBefore.UTC().Format(timeFormat),
+		Expires:     leaf.NotAfter.UTC().Format(timeFormat),
+		Created:     now(),
+		Updated:     now(),
+	}
+	if crt.SubjectAlt == nil {
+		crt.SubjectAlt = []string{}
+	}
+	c.certs[crt.Name] = crt
+	writeJSON(w, http.StatusCreated, crt)
+}
+
+func (c *Controller) nextCertID() int {
+	id := 1
+	for _, crt := range c.certs {
+		if crt.ID >= id {
+			id = crt.ID + 1
+		}
+	}
+	return id
+}
+
+// fingerprint returns the colon separated SHA256 fingerprint of a DER encoded certificate, in the
+// same format the controller reports it.
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
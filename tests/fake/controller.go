@@ -0,0 +1,214 @@
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The types and functions in this file implement a fake Workflow controller. It speaks the subset
+// of the controller's REST API that the `deis` CLI uses, keeping all of its state in memory, so
+// that the test suite can be exercised without a Kubernetes cluster.
+
+const (
+	// APIVersion is the controller API version advertised by the fake controller.
+	APIVersion = "2.3"
+	// PlatformVersion is the Workflow version advertised by the fake controller.
+	PlatformVersion = "fake"
+	timeFormat      = "2006-01-02T15:04:05Z"
+	errForbidden    = "You do not have permission to perform this action."
+)
+
+// Controller is an in-memory stand-in for the Workflow controller.
+type Controller struct {
+	// URL is the base URL of the running fake controller, e.g. http://127.0.0.1:54321
+	URL string
+	// Domain is the platform domain under which apps are reported to be routable.
+	Domain string
+	// NodeLabels are the labels of the (imaginary) Kubernetes nodes that `deis tags:set` is
+	// validated against.
+	NodeLabels map[string]string
+
+	server *httptest.Server
+	mu     sync.Mutex
+	users  map[string]*user
+	tokens map[string]string
+	apps   map[string]*app
+	certs  map[string]*cert
+	keys   map[string]*key
+}
+
+// DefaultNodeLabels are the NodeLabels of every new Controller.
+var DefaultNodeLabels = map[string]string{"kubernetes.io/hostname": "fake-node"}
+
+// NewController starts a new fake controller listening on a random port on the loopback interface.
+// Apps created through it are reported as routable at <app>.<domain>.
+func NewController(domain string) *Controller {
+	c := &Controller{
+		Domain:     domain,
+		NodeLabels: map[string]string{},
+		users:      map[string]*user{},
+		tokens:     map[string]string{},
+		apps:       map[string]*app{},
+		certs:      map[string]*cert{},
+		keys:       map[string]*key{},
+	}
+	for k, v := range DefaultNodeLabels {
+		c.NodeLabels[k] = v
+	}
+	c.server = httptest.NewServer(c)
+	c.URL = c.server.URL
+	return c
+}
+
+// Close shuts the fake controller down.
+func (c *Controller) Close() {
+	c.server.Close()
+}
+
+// ServeHTTP dispatches a request to the handler for the requested resource.
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DEIS_API_VERSION", APIVersion)
+	w.Header().Set("DEIS_PLATFORM_VERSION", PlatformVersion)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := &request{Request: r, segments: splitPath(r.URL.Path)}
+	if len(req.segments) < 2 || req.segments[0] != "v2" {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	// Registration and login are the only endpoints that don't require a token.
+	if req.segments[1] == "auth" && len(req.segments) > 2 &&
+		(req.segments[2] == "register" || req.segments[2] == "login") {
+		c.serveAuth(w, req)
+		return
+	}
+	if req.user = c.authenticate(r); req.user == nil {
+		writeError(w, http.StatusUnauthorized, "Invalid token.")
+		return
+	}
+
+	switch req.segments[1] {
+	case "auth":
+		c.serveAuth(w, req)
+	case "users":
+		c.serveUsers(w, req)
+	case "admin":
+		c.serveAdminPerms(w, req)
+	case "apps":
+		c.serveApps(w, req)
+	case "certs":
+		c.serveCerts(w, req)
+	case "keys":
+		c.serveKeys(w, req)
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+// request wraps an *http.Request with the authenticated user and the split request path.
+type request struct {
+	*http.Request
+	user     *user
+	segments []string
+}
+
+// segment returns the i-th path segment, or "" if the path is shorter than that.
+func (r *request) segment(i int) string {
+	if i < len(r.segments) {
+		return r.segments[i]
+	}
+	return ""
+}
+
+// decode unmarshals the JSON request body into v.
+func (r *request) decode(v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (c *Controller) authenticate(r *http.Request) *user {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "token ")
+	if username, ok := c.tokens[token]; ok {
+		return c.users[username]
+	}
+	return nil
+}
+
+func splitPath(p string) []string {
+	var segments []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// writeJSON writes v to the response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a Django REST Framework style error response.
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
+
+// writeFieldError writes a Django REST Framework style validation error for a single field.
+func writeFieldError(w http.ResponseWriter, field, msg string) {
+	writeJSON(w, http.StatusBadRequest, map[string][]string{field: {msg}})
+}
+
+// writeList writes a paginated list response, honoring the "limit" query parameter.
+func writeList(w http.ResponseWriter, r *request, results []interface{}) {
+	count := len(results)
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < count {
+		results = results[:limit]
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    count,
+		"next":     nil,
+		"previous": nil,
+		"results":  results,
+	})
+}
+
+func now() string {
+	return time.Now().UTC().Format(timeFormat)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func newToken() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
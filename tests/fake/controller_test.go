@@ -0,0 +1,226 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/pki"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fake controller", func() {
+
+	var c *Controller
+
+	// call issues a request to the fake controller with the given token and decodes any JSON
+	// response into out.
+	call := func(method, path, token string, body, out interface{}) int {
+		var buf bytes.Buffer
+		if body != nil {
+			Expect(json.NewEncoder(&buf).Encode(body)).To(Succeed())
+		}
+		req, err := http.NewRequest(method, c.URL+path, &buf)
+		Expect(err).NotTo(HaveOccurred())
+		if token != "" {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("DEIS_API_VERSION")).To(Equal(APIVersion))
+		if out != nil {
+			Expect(json.NewDecoder(resp.Body).Decode(out)).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	register := func(username string) string {
+		creds := map[string]string{"username": username, "password": "asdf1234", "email": username + "@deis.io"}
+		Expect(call("POST", "/v2/auth/register/", "", creds, nil)).To(Equal(http.StatusCreated))
+		var login struct {
+			Token string `json:"token"`
+		}
+		Expect(call("POST", "/v2/auth/login/", "", creds, &login)).To(Equal(http.StatusOK))
+		Expect(login.Token).NotTo(BeEmpty())
+		return login.Token
+	}
+
+	BeforeEach(func() {
+		c = NewController("k8s.local")
+	})
+
+	AfterEach(func() {
+		c.Close()
+	})
+
+	Specify("requests without a valid token are rejected", func() {
+		Expect(call("GET", "/v2/apps/", "", nil, nil)).To(Equal(http.StatusUnauthorized))
+		Expect(call("GET", "/v2/apps/", "bogus", nil, nil)).To(Equal(http.StatusUnauthorized))
+	})
+
+	Specify("the first user to register is an admin", func() {
+		adminToken := register("admin")
+		userToken := register("test-1")
+		var me user
+		Expect(call("GET", "/v2/auth/whoami/", adminToken, nil, &me)).To(Equal(http.StatusOK))
+		Expect(me.IsSuperuser).To(BeTrue())
+		Expect(call("GET", "/v2/auth/whoami/", userToken, nil, &me)).To(Equal(http.StatusOK))
+		Expect(me.IsSuperuser).To(BeFalse())
+		Expect(call("GET", "/v2/users/", userToken, nil, nil)).To(Equal(http.StatusForbidden))
+	})
+
+	Specify("certificates that have expired are refused, but those not yet valid are not", func() {
+		token := register("test-1")
+		authority, err := pki.NewAuthority("Test Root")
+		Expect(err).NotTo(HaveOccurred())
+		upload := func(name string, notBefore, notAfter time.Time) int {
+			leaf, err := authority.Issue(pki.Request{CommonName: "www.foo.com", NotBefore: notBefore, NotAfter: notAfter})
+			Expect(err).NotTo(HaveOccurred())
+			key, err := leaf.KeyPEM()
+			Expect(err).NotTo(HaveOccurred())
+			body := map[string]string{"name": name, "certificate": string(leaf.CertPEM()), "key": string(key)}
+			return call("POST", "/v2/certs/", token, body, nil)
+		}
+		now := time.Now()
+		Expect(upload("expired", now.Add(-48*time.Hour), now.Add(-24*time.Hour))).To(Equal(http.StatusBadRequest))
+		Expect(upload("future", now.Add(24*time.Hour), now.Add(48*time.Hour))).To(Equal(http.StatusCreated))
+		Expect(upload("current", time.Time{}, time.Time{})).To(Equal(http.StatusCreated))
+	})
+
+	Context("with an app that has been deployed", func() {
+
+		var token string
+
+		BeforeEach(func() {
+			token = register("test-1")
+			var a app
+			Expect(call("POST", "/v2/apps/", token, map[string]string{"id": "test-app"}, &a)).To(Equal(http.StatusCreated))
+			Expect(a.URL).To(Equal("test-app.k8s.local"))
+			Expect(call("POST", "/v2/apps/test-app/builds/", token, map[string]string{"image": "deis/example-go"}, nil)).To(Equal(http.StatusCreated))
+		})
+
+		Specify("a duplicate app cannot be created", func() {
+			var errs map[string][]string
+			Expect(call("POST", "/v2/apps/", token, map[string]string{"id": "test-app"}, &errs)).To(Equal(http.StatusBadRequest))
+			Expect(errs["id"]).To(ConsistOf("Application with this id already exists."))
+		})
+
+		Specify("the app runs a single cmd process at the latest release", func() {
+			var pods struct {
+				Count   int    `json:"count"`
+				Results []*pod `json:"results"`
+			}
+			Expect(call("GET", "/v2/apps/test-app/pods/", token, nil, &pods)).To(Equal(http.StatusOK))
+			Expect(pods.Count).To(Equal(1))
+			Expect(pods.Results[0].Type).To(Equal("cmd"))
+			Expect(pods.Results[0].Release).To(Equal("v2"))
+		})
+
+		Specify("the app can be scaled", func() {
+			Expect(call("POST", "/v2/apps/test-app/scale/", token, map[string]int{"cmd": 3}, nil)).To(Equal(http.StatusNoContent))
+			var pods struct {
+				Count int `json:"count"`
+			}
+			Expect(call("GET", "/v2/apps/test-app/pods/", token, nil, &pods)).To(Equal(http.StatusOK))
+			Expect(pods.Count).To(Equal(3))
+			Expect(call("POST", "/v2/apps/test-app/scale/", token, map[string]int{"web": 1}, nil)).To(Equal(http.StatusBadRequest))
+		})
+
+		Specify("the app's processes are restarted only by a POST", func() {
+			var pods struct {
+				Results []*pod `json:"results"`
+			}
+			Expect(call("GET", "/v2/apps/test-app/pods/", token, nil, &pods)).To(Equal(http.StatusOK))
+			name := pods.Results[0].Name
+			Expect(call("GET", "/v2/apps/test-app/pods/restart/", token, nil, nil)).To(Equal(http.StatusMethodNotAllowed))
+			Expect(call("GET", "/v2/apps/test-app/pods/", token, nil, &pods)).To(Equal(http.StatusOK))
+			Expect(pods.Results[0].Name).To(Equal(name))
+
+			var restarted []*pod
+			Expect(call("POST", "/v2/apps/test-app/pods/restart/", token, nil, &restarted)).To(Equal(http.StatusOK))
+			Expect(restarted).To(HaveLen(1))
+			Expect(restarted[0].Name).NotTo(Equal(name))
+		})
+
+		Specify("config changes create releases and unsetting a missing key fails", func() {
+			values := map[string]interface{}{"values": map[string]interface{}{"FOO": "bar"}}
+			Expect(call("POST", "/v2/apps/test-app/config/", token, values, nil)).To(Equal(http.StatusCreated))
+			var rel release
+			Expect(call("GET", "/v2/apps/test-app/releases/v3/", token, nil, &rel)).To(Equal(http.StatusOK))
+			unset := map[string]interface{}{"values": map[string]interface{}{"BAZ": nil}}
+			Expect(call("POST", "/v2/apps/test-app/config/", token, unset, nil)).To(Equal(http.StatusUnprocessableEntity))
+		})
+
+		Specify("a rollback releases the build of the release it rolls back to, with its process types", func() {
+			build := map[string]interface{}{"image": "deis/example-go", "procfile": map[string]string{"web": "example-go"}}
+			Expect(call("POST", "/v2/apps/test-app/builds/", token, build, nil)).To(Equal(http.StatusCreated))
+			var v2, v3 release
+			Expect(call("GET", "/v2/apps/test-app/releases/v2/", token, nil, &v2)).To(Equal(http.StatusOK))
+			Expect(call("GET", "/v2/apps/test-app/releases/v3/", token, nil, &v3)).To(Equal(http.StatusOK))
+			Expect(v3.Build).NotTo(Equal(v2.Build))
+
+			Expect(call("POST", "/v2/apps/test-app/releases/rollback/", token, map[string]int{"version": 2}, nil)).To(Equal(http.StatusCreated))
+			var v4 release
+			Expect(call("GET", "/v2/apps/test-app/releases/v4/", token, nil, &v4)).To(Equal(http.StatusOK))
+			Expect(v4.Build).To(Equal(v2.Build))
+			var pods struct {
+				Results []*pod `json:"results"`
+			}
+			Expect(call("GET", "/v2/apps/test-app/pods/", token, nil, &pods)).To(Equal(http.StatusOK))
+			Expect(pods.Results).To(HaveLen(1))
+			Expect(pods.Results[0].Type).To(Equal("cmd"))
+
+			values := map[string]interface{}{"values": map[string]interface{}{"FOO": "bar"}}
+			Expect(call("POST", "/v2/apps/test-app/config/", token, values, nil)).To(Equal(http.StatusCreated))
+			var v5 release
+			Expect(call("GET", "/v2/apps/test-app/releases/v5/", token, nil, &v5)).To(Equal(http.StatusOK))
+			Expect(v5.Build).To(Equal(v2.Build))
+		})
+
+		Specify("other users cannot see the app until they are made collaborators", func() {
+			otherToken := register("test-2")
+			Expect(call("GET", "/v2/apps/test-app/", otherToken, nil, nil)).To(Equal(http.StatusForbidden))
+			Expect(call("POST", "/v2/apps/test-app/perms/", token, map[string]string{"username": "test-2"}, nil)).To(Equal(http.StatusCreated))
+			Expect(call("GET", "/v2/apps/test-app/", otherToken, nil, nil)).To(Equal(http.StatusOK))
+			Expect(call("DELETE", "/v2/apps/test-app/", otherToken, nil, nil)).To(Equal(http.StatusForbidden))
+		})
+
+		Specify("the output of commands run in the app is logged until the app is destroyed", func() {
+			logs := func() (int, string) {
+				req, err := http.NewRequest("GET", c.URL+"/v2/apps/test-app/logs/?log_lines=2", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Authorization", "token "+token)
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				return resp.StatusCode, string(body)
+			}
+
+			Expect(call("POST", "/v2/apps/test-app/run", token, map[string]string{"command": "echo e2e-marker"}, nil)).To(Equal(http.StatusOK))
+			code, body := logs()
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("deis[controller]: INFO test-1 runs 'echo e2e-marker'"))
+			Expect(body).To(MatchRegexp(`test-app\[test-app-run-\w+\]: e2e-marker\n$`))
+
+			Expect(call("DELETE", "/v2/apps/test-app/", token, nil, nil)).To(Equal(http.StatusNoContent))
+			code, body = logs()
+			Expect(code).To(Equal(http.StatusNotFound))
+			Expect(body).To(ContainSubstring("Not found."))
+		})
+
+		Specify("the owner cannot cancel their account while they own the app", func() {
+			Expect(call("DELETE", "/v2/auth/cancel/", token, nil, nil)).To(Equal(http.StatusConflict))
+			Expect(call("DELETE", "/v2/apps/test-app/", token, nil, nil)).To(Equal(http.StatusNoContent))
+			Expect(call("DELETE", "/v2/auth/cancel/", token, nil, nil)).To(Equal(http.StatusNoContent))
+		})
+
+	})
+
+})
@@ -0,0 +1,392 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The types and functions in this file implement a stand-in for a private Docker registry. It
+// speaks the read-only subset of the Docker Registry HTTP API V2 that `docker pull` uses, serving
+// images that the test suite seeds it with from memory, so that specs which deploy images need
+// neither internet access nor long-lived registry credentials.
+
+const (
+	manifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	configMediaType   = "application/vnd.docker.container.image.v1+json"
+	layerMediaType    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+)
+
+// Registry is an in-memory stand-in for a private Docker registry.
+type Registry struct {
+	// URL is the base URL of the running registry, e.g. http://127.0.0.1:54321
+	URL string
+	// Host is the host:port at which clients of the registry, such as the Docker daemons of the
+	// cluster under test, reach it. Images are named relative to it.
+	Host string
+	// Username and Password are the credentials required to pull private images. They are
+	// generated anew for each registry.
+	Username string
+	Password string
+	// TokenAuth makes the registry challenge clients to obtain a bearer token from its /token
+	// endpoint, as Docker Hub and quay.io do, instead of to present their credentials directly.
+	TokenAuth bool
+
+	server *httptest.Server
+	mu     sync.Mutex
+	repos  map[string]*repository
+	blobs  map[string][]byte
+	tokens map[string]bool
+}
+
+// repository is a named collection of tagged manifests.
+type repository struct {
+	public    bool
+	manifests map[string][]byte
+}
+
+// descriptor references a blob from a manifest.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Size      int    `json:"size"`
+	Digest    string `json:"digest"`
+}
+
+// manifest is an image manifest, version 2, schema 2.
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+}
+
+// NewRegistry starts a new registry listening on the specified address, such as ":5000". If addr
+// is empty, it listens on a random port on the loopback interface. host is the host:port at which
+// clients reach the registry; if it is empty, they are assumed to reach it at the address it
+// listens on.
+func NewRegistry(addr, host string) (*Registry, error) {
+	r := &Registry{
+		Host:     host,
+		Username: "e2e-" + newToken()[:8],
+		Password: newToken(),
+		repos:    map[string]*repository{},
+		blobs:    map[string][]byte{},
+		tokens:   map[string]bool{},
+	}
+	if addr == "" {
+		r.server = httptest.NewServer(r)
+	} else {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		r.server = httptest.NewUnstartedServer(r)
+		r.server.Listener.Close()
+		r.server.Listener = listener
+		r.server.Start()
+	}
+	r.URL = r.server.URL
+	if r.Host == "" {
+		r.Host = strings.TrimPrefix(r.URL, "http://")
+	}
+	return r, nil
+}
+
+// Close shuts the registry down.
+func (r *Registry) Close() {
+	r.server.Close()
+}
+
+// Image returns the name by which clients of the registry pull the specified repository.
+func (r *Registry) Image(repo string) string {
+	return r.Host + "/" + repo
+}
+
+// AddImage stores an image built from the specified config and uncompressed layer tarballs as
+// repo:tag. Anyone may pull the images in a public repository; the registry's credentials are
+// required to pull any other. It returns the digest of the image's manifest.
+func (r *Registry) AddImage(repo, tag string, public bool, config []byte, layers ...[]byte) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		Config:        r.putBlob(configMediaType, config),
+		Layers:        []descriptor{},
+	}
+	for _, layer := range layers {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(layer); err != nil {
+			return "", err
+		}
+		if err := gz.Close(); err != nil {
+			return "", err
+		}
+		m.Layers = append(m.Layers, r.putBlob(layerMediaType, buf.Bytes()))
+	}
+	body, err := json.MarshalIndent(m, "", "   ")
+	if err != nil {
+		return "", err
+	}
+
+	rep, ok := r.repos[repo]
+	if !ok {
+		rep = &repository{manifests: map[string][]byte{}}
+		r.repos[repo] = rep
+	}
+	rep.public = public
+	rep.manifests[tag] = body
+	return digest(body), nil
+}
+
+// Seed stores the image in a tarball written by `docker save` as repo:tag. If the tarball holds
+// more than one image, the first is stored.
+func (r *Registry) Seed(repo, tag string, public bool, tarball io.Reader) error {
+	files := map[string][]byte{}
+	tr := tar.NewReader(tarball)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if files[path.Clean(hdr.Name)], err = ioutil.ReadAll(tr); err != nil {
+			return err
+		}
+	}
+
+	var saved []struct {
+		Config string
+		Layers []string
+	}
+	if err := json.Unmarshal(files["manifest.json"], &saved); err != nil {
+		return fmt.Errorf("reading manifest.json: %s", err)
+	}
+	if len(saved) == 0 {
+		return fmt.Errorf("no images found in tarball")
+	}
+	config, ok := files[path.Clean(saved[0].Config)]
+	if !ok {
+		return fmt.Errorf("config %s not found in tarball", saved[0].Config)
+	}
+	var layers [][]byte
+	for _, name := range saved[0].Layers {
+		layer, ok := files[path.Clean(name)]
+		if !ok {
+			return fmt.Errorf("layer %s not found in tarball", name)
+		}
+		layers = append(layers, layer)
+	}
+	_, err := r.AddImage(repo, tag, public, config, layers...)
+	return err
+}
+
+// Copy stores every tag of the src repository in the dst repository as well, which is public or
+// private as specified.
+func (r *Registry) Copy(src, dst string, public bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep, ok := r.repos[src]
+	if !ok {
+		return fmt.Errorf("no such repository %s", src)
+	}
+	copied := &repository{public: public, manifests: map[string][]byte{}}
+	for tag, body := range rep.manifests {
+		copied.manifests[tag] = body
+	}
+	r.repos[dst] = copied
+	return nil
+}
+
+// putBlob stores the specified content, returning a descriptor of it. Callers must hold r.mu.
+func (r *Registry) putBlob(mediaType string, content []byte) descriptor {
+	d := digest(content)
+	r.blobs[d] = content
+	return descriptor{MediaType: mediaType, Size: len(content), Digest: d}
+}
+
+func digest(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// ServeHTTP serves the API version check, manifests, blobs and, when TokenAuth is set, tokens. The
+// registry is locked only while the request is resolved, never while the response is written, so
+// that a client that is slow to read a large blob does not hold up every other.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, "/v2/") {
+		registryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+		return
+	}
+	if req.Method != "GET" && req.Method != "HEAD" {
+		registryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "this registry is read-only")
+		return
+	}
+	valid, authorized := r.authorize(req)
+	if req.URL.Path == "/v2/" {
+		if !valid {
+			r.challenge(w, "")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// The repository name may itself contain slashes, so find the kind of resource from the end.
+	segments := strings.Split(strings.TrimPrefix(req.URL.Path, "/v2/"), "/")
+	if len(segments) < 3 {
+		registryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+		return
+	}
+	name := strings.Join(segments[:len(segments)-2], "/")
+	kind, ref := segments[len(segments)-2], segments[len(segments)-1]
+
+	exists, public, body, found := r.lookup(name, kind, ref)
+	if !authorized && (!exists || !public) {
+		// Don't reveal whether private repositories exist to anonymous clients.
+		r.challenge(w, name)
+		return
+	}
+	if !exists {
+		registryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+		return
+	}
+
+	switch kind {
+	case "manifests":
+		if !found {
+			registryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+			return
+		}
+		w.Header().Set("Content-Type", manifestMediaType)
+		w.Header().Set("Docker-Content-Digest", digest(body))
+		writeContent(w, req, body)
+	case "blobs":
+		if !found {
+			registryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to registry")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", ref)
+		writeContent(w, req, body)
+	default:
+		registryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+	}
+}
+
+// lookup reports whether the named repository exists and is public, and returns the manifest
+// (by tag or digest) or blob (by digest) that kind and ref identify, if it is found. Stored content
+// is never modified, so the body may be written after the registry is unlocked.
+func (r *Registry) lookup(name, kind, ref string) (exists, public bool, body []byte, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep, exists := r.repos[name]
+	if !exists {
+		return false, false, nil, false
+	}
+	switch kind {
+	case "manifests":
+		body, found = rep.manifests[ref]
+		if !found {
+			for _, m := range rep.manifests {
+				if digest(m) == ref {
+					body, found = m, true
+					break
+				}
+			}
+		}
+	case "blobs":
+		body, found = r.blobs[ref]
+	}
+	return true, rep.public, body, found
+}
+
+// authorize reports whether the request carries valid credentials or a token that the registry
+// issued, and whether those grant access to private repositories.
+func (r *Registry) authorize(req *http.Request) (valid, private bool) {
+	if r.TokenAuth {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return false, false
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		private, valid = r.tokens[strings.TrimPrefix(auth, "Bearer ")]
+		return valid, private
+	}
+	username, password, ok := req.BasicAuth()
+	valid = ok && username == r.Username && password == r.Password
+	return valid, valid
+}
+
+// challenge tells a client how to authenticate in order to access the named repository.
+func (r *Registry) challenge(w http.ResponseWriter, name string) {
+	if r.TokenAuth {
+		challenge := fmt.Sprintf(`Bearer realm="http://%s/token",service="%s"`, r.Host, r.Host)
+		if name != "" {
+			challenge += fmt.Sprintf(`,scope="repository:%s:pull"`, name)
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+	} else {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, r.Host))
+	}
+	registryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+}
+
+// serveToken issues a bearer token to clients that present the registry's credentials. Anonymous
+// clients are issued a token too, but it grants access to public repositories only.
+func (r *Registry) serveToken(w http.ResponseWriter, req *http.Request) {
+	if !r.TokenAuth {
+		registryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+		return
+	}
+	username, password, ok := req.BasicAuth()
+	if ok && (username != r.Username || password != r.Password) {
+		registryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid credentials")
+		return
+	}
+	token := newToken()
+	r.mu.Lock()
+	r.tokens[token] = ok
+	r.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "access_token": token})
+}
+
+func writeContent(w http.ResponseWriter, req *http.Request, body []byte) {
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if req.Method != "HEAD" {
+		w.Write(body)
+	}
+}
+
+func registryError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
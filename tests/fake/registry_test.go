@@ -0,0 +1,205 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fake registry", func() {
+
+	var r *Registry
+
+	// savedImage returns a tarball in the format written by `docker save`, holding an image with
+	// the specified config and a single layer.
+	savedImage := func(config, layer string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		files := []struct{ name, body string }{
+			{"abc123.json", config},
+			{"0123abcd/layer.tar", layer},
+			{"manifest.json", `[{"Config":"abc123.json","RepoTags":["test:latest"],"Layers":["0123abcd/layer.tar"]}]`},
+		}
+		for _, f := range files {
+			Expect(tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tw.Write([]byte(f.body))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tw.Close()).To(Succeed())
+		return buf.Bytes()
+	}
+
+	// get issues a GET request to the registry with the specified Authorization header, if any, and
+	// returns the response's status, headers and body.
+	get := func(path, auth string) (int, http.Header, []byte) {
+		req, err := http.NewRequest("GET", r.URL+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, resp.Header, body
+	}
+
+	basic := func(username, password string) string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization")
+	}
+
+	BeforeEach(func() {
+		var err error
+		r, err = NewRegistry("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Seed("deis/example", "latest", true, bytes.NewReader(savedImage(`{"architecture":"amd64"}`, "layer")))).To(Succeed())
+		Expect(r.Copy("deis/private", "deis/private", false)).NotTo(Succeed())
+		Expect(r.Copy("deis/example", "deis/private", false)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		r.Close()
+	})
+
+	Specify("each registry generates its own credentials", func() {
+		other, err := NewRegistry("", "")
+		Expect(err).NotTo(HaveOccurred())
+		defer other.Close()
+		Expect(r.Username).NotTo(BeEmpty())
+		Expect(r.Password).NotTo(BeEmpty())
+		Expect(other.Password).NotTo(Equal(r.Password))
+		Expect(r.Image("deis/example")).To(Equal(r.Host + "/deis/example"))
+	})
+
+	Specify("anyone can pull a public image", func() {
+		status, header, body := get("/v2/deis/example/manifests/latest", "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(header.Get("Content-Type")).To(Equal(manifestMediaType))
+		Expect(header.Get("Docker-Content-Digest")).To(Equal(digest(body)))
+
+		var m manifest
+		Expect(json.Unmarshal(body, &m)).To(Succeed())
+		Expect(m.SchemaVersion).To(Equal(2))
+		Expect(m.Layers).To(HaveLen(1))
+
+		status, _, config := get("/v2/deis/example/blobs/"+m.Config.Digest, "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(string(config)).To(Equal(`{"architecture":"amd64"}`))
+		status, _, layer := get("/v2/deis/example/blobs/"+m.Layers[0].Digest, "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(digest(layer)).To(Equal(m.Layers[0].Digest))
+
+		status, _, _ = get("/v2/deis/example/manifests/"+digest(body), "")
+		Expect(status).To(Equal(http.StatusOK))
+		status, _, _ = get("/v2/deis/example/manifests/v1", "")
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+
+	Specify("only clients with the registry's credentials can pull a private image", func() {
+		status, header, _ := get("/v2/deis/private/manifests/latest", "")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+		Expect(header.Get("WWW-Authenticate")).To(HavePrefix("Basic "))
+		status, _, _ = get("/v2/deis/private/manifests/latest", basic(r.Username, "wrong"))
+		Expect(status).To(Equal(http.StatusUnauthorized))
+		status, _, _ = get("/v2/deis/private/manifests/latest", basic(r.Username, r.Password))
+		Expect(status).To(Equal(http.StatusOK))
+
+		status, _, _ = get("/v2/", "")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+		status, _, _ = get("/v2/", basic(r.Username, r.Password))
+		Expect(status).To(Equal(http.StatusOK))
+	})
+
+	Specify("the registry is read-only", func() {
+		req, err := http.NewRequest("PUT", r.URL+"/v2/deis/example/manifests/latest", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.SetBasicAuth(r.Username, r.Password)
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	Specify("a client that is slow to read a blob does not hold up others", func() {
+		// Large enough, once gzipped, to fill the socket buffers between the registry and a client
+		// that reads nothing.
+		layer := make([]byte, 16<<20)
+		_, err := rand.Read(layer)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.AddImage("deis/large", "latest", true, []byte(`{"architecture":"amd64"}`), layer)
+		Expect(err).NotTo(HaveOccurred())
+		status, _, body := get("/v2/deis/large/manifests/latest", "")
+		Expect(status).To(Equal(http.StatusOK))
+		var m manifest
+		Expect(json.Unmarshal(body, &m)).To(Succeed())
+
+		slow, err := http.Get(r.URL + "/v2/deis/large/blobs/" + m.Layers[0].Digest)
+		Expect(err).NotTo(HaveOccurred())
+		defer slow.Body.Close()
+
+		done := make(chan int, 1)
+		go func() {
+			defer GinkgoRecover()
+			status, _, _ := get("/v2/deis/example/manifests/latest", "")
+			done <- status
+		}()
+		Eventually(done, 5*time.Second).Should(Receive(Equal(http.StatusOK)))
+	})
+
+	Context("with token authentication", func() {
+
+		token := func(auth string) string {
+			status, _, body := get("/token?service="+r.Host+"&scope=repository:deis/private:pull", auth)
+			Expect(status).To(Equal(http.StatusOK))
+			var t struct {
+				Token string `json:"token"`
+			}
+			Expect(json.Unmarshal(body, &t)).To(Succeed())
+			return "Bearer " + t.Token
+		}
+
+		BeforeEach(func() {
+			r.TokenAuth = true
+		})
+
+		Specify("clients are challenged to obtain a token", func() {
+			status, header, _ := get("/v2/deis/private/manifests/latest", basic(r.Username, r.Password))
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			Expect(header.Get("WWW-Authenticate")).To(Equal(
+				`Bearer realm="http://` + r.Host + `/token",service="` + r.Host + `",scope="repository:deis/private:pull"`))
+
+			status, _, _ = get("/token", basic(r.Username, "wrong"))
+			Expect(status).To(Equal(http.StatusUnauthorized))
+		})
+
+		Specify("a token issued for the registry's credentials grants access to private images", func() {
+			auth := token(basic(r.Username, r.Password))
+			status, _, _ := get("/v2/", auth)
+			Expect(status).To(Equal(http.StatusOK))
+			status, _, _ = get("/v2/deis/private/manifests/latest", auth)
+			Expect(status).To(Equal(http.StatusOK))
+		})
+
+		Specify("an anonymous token grants access to public images only", func() {
+			auth := token("")
+			status, _, _ := get("/v2/", auth)
+			Expect(status).To(Equal(http.StatusOK))
+			status, _, _ = get("/v2/deis/example/manifests/latest", auth)
+			Expect(status).To(Equal(http.StatusOK))
+			status, _, _ = get("/v2/deis/private/manifests/latest", auth)
+			Expect(status).To(Equal(http.StatusUnauthorized))
+		})
+
+	})
+
+})
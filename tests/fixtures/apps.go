@@ -0,0 +1,483 @@
+package fixtures
+
+// The fixtures in this file stand in for the example apps under the github/deis org. Apps listen
+// on $PORT, falling back to their configured port, and respond to every request with their banner.
+
+// Buildpack apps, one per language. Each declares a web process in its Procfile.
+var (
+	Go = Fixture{
+		Name:     "example-go",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner, LogsRequests: true},
+		files: map[string]string{
+			"Procfile": "web: example-go\n",
+			"Godeps/Godeps.json": `{
+	"ImportPath": "github.com/deis/example-go",
+	"GoVersion": "go1.7",
+	"Deps": []
+}
+`,
+			"main.go": `package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "{{port}}"
+	}
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if marker := r.URL.Query().Get("log"); marker != "" {
+			fmt.Println(marker)
+		}
+		fmt.Fprintln(w, "{{banner}}")
+	})
+	http.ListenAndServe(":"+port, nil)
+}
+`,
+		},
+	}
+
+	Java = Fixture{
+		Name:     "example-java",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile":          "web: java -cp target/classes Main\n",
+			"system.properties": "java.runtime.version=1.8\n",
+			"pom.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>io.deis</groupId>
+  <artifactId>example-java</artifactId>
+  <version>1.0</version>
+  <properties>
+    <maven.compiler.source>1.8</maven.compiler.source>
+    <maven.compiler.target>1.8</maven.compiler.target>
+  </properties>
+</project>
+`,
+			"src/main/java/Main.java": `import com.sun.net.httpserver.HttpServer;
+import java.io.OutputStream;
+import java.net.InetSocketAddress;
+
+public class Main {
+    public static void main(String[] args) throws Exception {
+        String port = System.getenv("PORT");
+        if (port == null) {
+            port = "{{port}}";
+        }
+        HttpServer server = HttpServer.create(new InetSocketAddress(Integer.parseInt(port)), 0);
+        server.createContext("/", exchange -> {
+            byte[] body = "{{banner}}\n".getBytes("UTF-8");
+            exchange.sendResponseHeaders(200, body.length);
+            try (OutputStream out = exchange.getResponseBody()) {
+                out.write(body);
+            }
+        });
+        server.start();
+    }
+}
+`,
+		},
+	}
+
+	NodeJS = Fixture{
+		Name:     "example-nodejs",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile": "web: node server.js\n",
+			"package.json": `{
+  "name": "example-nodejs",
+  "version": "1.0.0",
+  "private": true,
+  "engines": {
+    "node": "6.x"
+  }
+}
+`,
+			"server.js": `var http = require('http');
+
+http.createServer(function (req, res) {
+  res.writeHead(200, {'Content-Type': 'text/plain'});
+  res.end('{{banner}}\n');
+}).listen(process.env.PORT || {{port}});
+`,
+		},
+	}
+
+	PHP = Fixture{
+		Name:     "example-php",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			// The PHP buildpack tells its web server which port to listen on.
+			"Procfile":      "web: heroku-php-apache2\n",
+			"composer.json": "{}\n",
+			"index.php":     "<?php echo \"{{banner}}\\n\";\n",
+		},
+	}
+
+	Python = Fixture{
+		Name:     "example-python",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile":         "web: python app.py\n",
+			"requirements.txt": "",
+			"runtime.txt":      "python-3.5.2\n",
+			"app.py":           pythonApp,
+		},
+	}
+
+	Ruby = Fixture{
+		Name:     "example-ruby",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile":     "web: ruby app.rb\n",
+			"Gemfile":      rubyGemfile,
+			"Gemfile.lock": rubyGemfileLock,
+			"app.rb":       rubyApp,
+		},
+	}
+
+	Clojure = Fixture{
+		Name:     "example-clojure",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			// The Clojure buildpack builds an uberjar if the project names one.
+			"Procfile": "web: java $JVM_OPTS -jar target/example-clojure-standalone.jar\n",
+			"project.clj": `(defproject example-clojure "1.0.0"
+  :dependencies [[org.clojure/clojure "1.8.0"]]
+  :main example.core
+  :aot [example.core]
+  :min-lein-version "2.0.0"
+  :uberjar-name "example-clojure-standalone.jar")
+`,
+			"src/example/core.clj": `(ns example.core
+  (:import (com.sun.net.httpserver HttpHandler HttpServer)
+           (java.net InetSocketAddress))
+  (:gen-class))
+
+(defn -main [& args]
+  (let [port (Integer/parseInt (or (System/getenv "PORT") "{{port}}"))
+        server (HttpServer/create (InetSocketAddress. port) 0)
+        body (.getBytes "{{banner}}\n" "UTF-8")]
+    (.createContext server "/"
+                    (reify HttpHandler
+                      (handle [_ exchange]
+                        (.sendResponseHeaders exchange 200 (alength body))
+                        (with-open [out (.getResponseBody exchange)]
+                          (.write out body)))))
+    (.start server)))
+`,
+		},
+	}
+
+	JavaPlay = Fixture{
+		Name:     "example-play",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile": "web: target/universal/stage/bin/example-play -Dhttp.port=${PORT:-{{port}}}\n",
+			"build.sbt": `name := "example-play"
+
+version := "1.0"
+
+scalaVersion := "2.11.8"
+
+lazy val root = (project in file(".")).enablePlugins(PlayScala)
+`,
+			"project/build.properties": "sbt.version=0.13.13\n",
+			"project/plugins.sbt":      "addSbtPlugin(\"com.typesafe.play\" % \"sbt-plugin\" % \"2.5.10\")\n",
+			// Play refuses to start in production mode without a secret, which this app has no use for.
+			"conf/application.conf": "play.crypto.secret = \"not-a-secret\"\n",
+			"conf/routes":           "GET     /       controllers.Application.index\n",
+			"app/controllers/Application.scala": `package controllers
+
+import play.api.mvc._
+
+class Application extends Controller {
+  def index = Action {
+    Ok("{{banner}}\n")
+  }
+}
+`,
+		},
+	}
+
+	// Multipack is built by the multi buildpack, which runs each of the buildpacks that its
+	// .buildpacks file lists in turn.
+	Multipack = Fixture{
+		Name:     "example-multipack",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			".buildpacks": `https://github.com/heroku/heroku-buildpack-nodejs.git
+https://github.com/heroku/heroku-buildpack-ruby.git
+`,
+			"Procfile": "web: ruby app.rb\n",
+			"package.json": `{
+  "name": "example-multipack",
+  "version": "1.0.0",
+  "private": true,
+  "engines": {
+    "node": "6.x"
+  }
+}
+`,
+			"Gemfile":      rubyGemfile,
+			"Gemfile.lock": rubyGemfileLock,
+			"app.rb":       rubyApp,
+		},
+	}
+
+	// Perl is not detected by any of the buildpacks that the builder offers, so the app must be
+	// created with a custom one.
+	Perl = Fixture{
+		Name:      "example-perl",
+		Port:      DefaultPort,
+		Buildpack: "https://github.com/miyagawa/heroku-buildpack-perl.git",
+		Manifest:  Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile": "web: plackup --port ${PORT:-{{port}}} app.psgi\n",
+			"cpanfile": "requires 'Plack';\n",
+			"app.psgi": `my $app = sub {
+    return [200, ['Content-Type' => 'text/plain'], ["{{banner}}\n"]];
+};
+`,
+		},
+	}
+
+	PythonDjango = Fixture{
+		Name:     "example-python-django",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile":         "web: gunicorn app --bind 0.0.0.0:${PORT:-{{port}}}\n",
+			"requirements.txt": "Django==1.10.4\ngunicorn==19.6.0\n",
+			"runtime.txt":      "python-3.5.2\n",
+			// The app configures Django itself, rather than having a manage.py, so that the buildpack
+			// does not attempt to collect its static files.
+			"app.py": `from django.conf import settings
+from django.conf.urls import url
+from django.core.wsgi import get_wsgi_application
+from django.http import HttpResponse
+
+settings.configure(
+    DEBUG=False,
+    SECRET_KEY="not-a-secret",
+    ALLOWED_HOSTS=["*"],
+    ROOT_URLCONF=__name__,
+    MIDDLEWARE_CLASSES=[],
+)
+
+
+def index(request):
+    return HttpResponse("{{banner}}\n", content_type="text/plain")
+
+
+urlpatterns = [url(r"^", index)]
+
+application = get_wsgi_application()
+`,
+		},
+	}
+
+	PythonFlask = Fixture{
+		Name:     "example-python-flask",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Procfile":         "web: gunicorn app:app --bind 0.0.0.0:${PORT:-{{port}}}\n",
+			"requirements.txt": "Flask==0.11.1\ngunicorn==19.6.0\n",
+			"runtime.txt":      "python-3.5.2\n",
+			"app.py": `from flask import Flask
+
+app = Flask(__name__)
+
+
+@app.route("/", defaults={"path": ""})
+@app.route("/<path:path>")
+def index(path):
+    return "{{banner}}\n"
+`,
+		},
+	}
+
+	Scala = Fixture{
+		Name:     "example-scala",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			// The Scala buildpack runs `sbt compile stage`, for which the app uses sbt-native-packager.
+			"Procfile": "web: target/universal/stage/bin/example-scala\n",
+			"build.sbt": `name := "example-scala"
+
+version := "1.0"
+
+scalaVersion := "2.11.8"
+
+enablePlugins(JavaAppPackaging)
+`,
+			"project/build.properties": "sbt.version=0.13.13\n",
+			"project/plugins.sbt":      "addSbtPlugin(\"com.typesafe.sbt\" % \"sbt-native-packager\" % \"1.1.5\")\n",
+			"src/main/scala/Main.scala": `import com.sun.net.httpserver.{HttpExchange, HttpHandler, HttpServer}
+import java.net.InetSocketAddress
+
+object Main extends App {
+  val port = sys.env.getOrElse("PORT", "{{port}}").toInt
+  val server = HttpServer.create(new InetSocketAddress(port), 0)
+  server.createContext("/", new HttpHandler {
+    def handle(exchange: HttpExchange): Unit = {
+      val body = "{{banner}}\n".getBytes("UTF-8")
+      exchange.sendResponseHeaders(200, body.length)
+      val out = exchange.getResponseBody
+      try out.write(body) finally out.close()
+    }
+  })
+  server.start()
+}
+`,
+		},
+	}
+)
+
+// Dockerfile apps. Those without a Procfile run their image's CMD as the cmd process.
+var (
+	DockerfileHTTP = Fixture{
+		Name:     "example-dockerfile-http",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"cmd"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Dockerfile": busyboxDockerfile + "CMD httpd -f -p $PORT -h /www\n",
+			"index.html": "{{banner}}\n",
+		},
+	}
+
+	DockerfilePython = Fixture{
+		Name:     "example-dockerfile-python",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"cmd"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Dockerfile": `FROM python:3-alpine
+ENV PORT {{port}}
+EXPOSE {{port}}
+COPY app.py /app/app.py
+CMD ["python", "/app/app.py"]
+`,
+			"app.py": pythonApp,
+		},
+	}
+
+	DockerfileProcfile = Fixture{
+		Name:     "example-dockerfile-procfile-http",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Dockerfile": busyboxDockerfile,
+			"Procfile":   "web: httpd -f -p $PORT -h /www\n",
+			"index.html": "{{banner}}\n",
+		},
+	}
+
+	// ProcfileMulti declares a worker, which serves no requests, alongside its web process.
+	ProcfileMulti = Fixture{
+		Name:     "example-procfile-multi",
+		Port:     DefaultPort,
+		Manifest: Manifest{ProcessTypes: []string{"web", "worker"}, Response: DefaultBanner},
+		files: map[string]string{
+			"Dockerfile": busyboxDockerfile,
+			"Procfile":   "web: httpd -f -p $PORT -h /www\nworker: sh -c 'while true; do echo working; sleep 10; done'\n",
+			"index.html": "{{banner}}\n",
+		},
+	}
+)
+
+// Deliberately broken apps, which cannot be deployed.
+var (
+	BrokenDockerfile = Fixture{
+		Name:     "example-broken-dockerfile",
+		Port:     DefaultPort,
+		Manifest: Manifest{Response: DefaultBanner, FailsWith: "Unknown instruction: BOGUS"},
+		files: map[string]string{
+			"Dockerfile": busyboxDockerfile + "BOGUS command\nCMD httpd -f -p $PORT -h /www\n",
+			"index.html": "{{banner}}\n",
+		},
+	}
+
+	// Undetectable contains nothing that any buildpack recognizes.
+	Undetectable = Fixture{
+		Name:     "example-undetectable",
+		Port:     DefaultPort,
+		Manifest: Manifest{Response: DefaultBanner, FailsWith: "Unable to select a buildpack"},
+		files: map[string]string{
+			"README.md": "{{banner}}\n",
+		},
+	}
+)
+
+var (
+	// Buildpacks are the buildpack apps.
+	Buildpacks = []Fixture{
+		Clojure, Go, Java, JavaPlay, Multipack, NodeJS, Perl, PHP, Python, PythonDjango, PythonFlask,
+		Ruby, Scala,
+	}
+	// Dockerfiles are the Dockerfile apps.
+	Dockerfiles = []Fixture{DockerfileHTTP, DockerfilePython, DockerfileProcfile, ProcfileMulti}
+	// Broken are the apps that cannot be deployed.
+	Broken = []Fixture{BrokenDockerfile, Undetectable}
+)
+
+const busyboxDockerfile = `FROM busybox
+ENV PORT {{port}}
+EXPOSE {{port}}
+COPY index.html /www/index.html
+`
+
+const rubyGemfile = "source 'https://rubygems.org'\n"
+
+const rubyGemfileLock = `GEM
+  remote: https://rubygems.org/
+  specs:
+
+PLATFORMS
+  ruby
+
+DEPENDENCIES
+
+BUNDLED WITH
+   1.12.5
+`
+
+const rubyApp = `require 'webrick'
+
+server = WEBrick::HTTPServer.new(Port: (ENV['PORT'] || {{port}}).to_i)
+server.mount_proc('/') { |req, res| res.body = "{{banner}}\n" }
+trap('TERM') { server.shutdown }
+server.start
+`
+
+const pythonApp = `import os
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        body = b"{{banner}}\n"
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+
+HTTPServer(("", int(os.environ.get("PORT", "{{port}}"))), Handler).serve_forever()
+`
@@ -0,0 +1,135 @@
+package fixtures
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types and functions in this package generate minimal example apps, so that specs which
+// deploy source code using a git push need not clone the example apps from GitHub. Each fixture
+// responds to every HTTP request with a known banner, and describes in its Manifest how it is
+// expected to behave once deployed.
+
+const (
+	// DefaultBanner is the banner with which fixtures respond unless told otherwise. It is the
+	// banner that the example apps under the github/deis org respond with.
+	DefaultBanner = "Powered by Deis"
+	// DefaultPort is the port on which fixtures listen if $PORT is not set. Dockerfile fixtures
+	// also EXPOSE it.
+	DefaultPort = 5000
+
+	bannerPlaceholder = "{{banner}}"
+	portPlaceholder   = "{{port}}"
+)
+
+// Manifest describes how a fixture is expected to behave once it has been pushed.
+type Manifest struct {
+	// ProcessTypes are the process types that the app declares, in the order they are declared.
+	ProcessTypes []string
+	// Response is the banner that the app includes in its response to every HTTP request.
+	Response string
+	// LogsRequests is whether the app writes the log query parameter of each request it serves to
+	// stdout, so that specs may inject markers into its logs.
+	LogsRequests bool
+	// FailsWith is the error that a git push of a broken app writes to stderr. It is empty if the
+	// app can be deployed.
+	FailsWith string
+}
+
+// Fixture is a minimal example app.
+type Fixture struct {
+	// Name is the name of the directory that the app is written to.
+	Name string
+	// Port is the port on which the app listens if $PORT is not set.
+	Port int
+	// Buildpack is the URL of the custom buildpack with which the app must be created, if any.
+	Buildpack string
+	// Manifest describes how the app is expected to behave once it has been pushed.
+	Manifest Manifest
+	// files maps the paths of the app's files to their contents, which may contain placeholders
+	// for the banner and port.
+	files map[string]string
+}
+
+// WithBanner returns a copy of the fixture that responds with the specified banner.
+func (f Fixture) WithBanner(banner string) Fixture {
+	f.Manifest.Response = banner
+	return f
+}
+
+// WithPort returns a copy of the fixture that listens on the specified port if $PORT is not set.
+func (f Fixture) WithPort(port int) Fixture {
+	f.Port = port
+	return f
+}
+
+// WithProcess returns a copy of the fixture whose Procfile also declares a process of the specified
+// type, which runs the specified command.
+func (f Fixture) WithProcess(procType, command string) Fixture {
+	files := make(map[string]string, len(f.files)+1)
+	for p, contents := range f.files {
+		files[p] = contents
+	}
+	files["Procfile"] += fmt.Sprintf("%s: %s\n", procType, command)
+	f.files = files
+	f.Manifest.ProcessTypes = append(append([]string(nil), f.Manifest.ProcessTypes...), procType)
+	return f
+}
+
+// Files returns the paths of the app's files, sorted.
+func (f Fixture) Files() []string {
+	var paths []string
+	for p := range f.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Contents returns the contents of the specified file of the app, with its banner and port filled
+// in.
+func (f Fixture) Contents(path string) string {
+	return strings.NewReplacer(
+		bannerPlaceholder, f.Manifest.Response,
+		portPlaceholder, strconv.Itoa(f.Port),
+	).Replace(f.files[path])
+}
+
+// Materialize writes the app to a new directory named f.Name within parent, and makes that
+// directory a git repo whose master branch holds a single commit of the app's files. It returns
+// the path of the directory.
+func (f Fixture) Materialize(parent string) (string, error) {
+	dir := filepath.Join(parent, f.Name)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	for _, p := range f.Files() {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(path, []byte(f.Contents(p)), 0644); err != nil {
+			return "", err
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		// Specs push to master, whatever git's default branch may be.
+		{"symbolic-ref", "HEAD", "refs/heads/master"},
+		{"add", "-A"},
+		{"-c", "user.name=Deis E2E", "-c", "user.email=e2e@deis.io", "commit", "-q", "-m", "Initial commit"},
+	} {
+		git := exec.Command("git", args...)
+		git.Dir = dir
+		if output, err := git.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("git %s failed (%s): %s", strings.Join(args, " "), err, output)
+		}
+	}
+	return dir, nil
+}
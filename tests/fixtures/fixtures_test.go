@@ -0,0 +1,157 @@
+package fixtures
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fixtures", func() {
+
+	var parent string
+
+	BeforeEach(func() {
+		var err error
+		parent, err = ioutil.TempDir("", "fixtures")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(parent)
+	})
+
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+		return strings.TrimSpace(string(output))
+	}
+
+	It("materializes every fixture as a git repo with a single commit on master", func() {
+		for _, fixtures := range [][]Fixture{Buildpacks, Dockerfiles, Broken} {
+			for _, f := range fixtures {
+				dir, err := f.Materialize(parent)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dir).To(Equal(filepath.Join(parent, f.Name)))
+				Expect(git(dir, "rev-parse", "--abbrev-ref", "HEAD")).To(Equal("master"))
+				Expect(git(dir, "rev-list", "--count", "HEAD")).To(Equal("1"))
+				Expect(git(dir, "status", "--porcelain")).To(BeEmpty())
+				Expect(strings.Split(git(dir, "ls-files"), "\n")).To(ConsistOf(f.Files()))
+			}
+		}
+	})
+
+	It("describes what each fixture is expected to do", func() {
+		for _, f := range append(Buildpacks, Dockerfiles...) {
+			Expect(f.Manifest.ProcessTypes).NotTo(BeEmpty(), f.Name)
+			Expect(f.Manifest.Response).To(Equal(DefaultBanner), f.Name)
+			Expect(f.Manifest.FailsWith).To(BeEmpty(), f.Name)
+		}
+		for _, f := range Broken {
+			Expect(f.Manifest.FailsWith).NotTo(BeEmpty(), f.Name)
+		}
+	})
+
+	It("includes an app that needs a custom buildpack and one that needs several", func() {
+		var custom, multi []string
+		for _, f := range Buildpacks {
+			if f.Buildpack != "" {
+				custom = append(custom, f.Name)
+			}
+			if _, ok := f.files[".buildpacks"]; ok {
+				multi = append(multi, f.Name)
+			}
+		}
+		Expect(custom).To(Equal([]string{Perl.Name}))
+		Expect(multi).To(Equal([]string{Multipack.Name}))
+		Expect(Multipack.Buildpack).To(BeEmpty())
+	})
+
+	It("fills in the banner and port", func() {
+		f := Go.WithBanner("Powered by midi-chlorians").WithPort(8080)
+		Expect(f.Manifest.Response).To(Equal("Powered by midi-chlorians"))
+		Expect(Go.Manifest.Response).To(Equal(DefaultBanner))
+
+		dir, err := f.Materialize(parent)
+		Expect(err).NotTo(HaveOccurred())
+		src, err := ioutil.ReadFile(filepath.Join(dir, "main.go"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(src)).To(ContainSubstring(`"Powered by midi-chlorians"`))
+		Expect(string(src)).To(ContainSubstring(`"8080"`))
+		Expect(string(src)).NotTo(ContainSubstring("{{"))
+		_, err = parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(DockerfileHTTP.WithPort(8080).Contents("Dockerfile")).To(ContainSubstring("EXPOSE 8080\n"))
+	})
+
+	It("declares additional process types", func() {
+		f := Go.WithProcess("worker", "sleep 3600")
+		Expect(f.Manifest.ProcessTypes).To(Equal([]string{"web", "worker"}))
+		Expect(f.Contents("Procfile")).To(Equal("web: example-go\nworker: sleep 3600\n"))
+		Expect(Go.Manifest.ProcessTypes).To(Equal([]string{"web"}))
+		Expect(Go.Contents("Procfile")).To(Equal("web: example-go\n"))
+
+		f = DockerfileHTTP.WithProcess("web", "httpd -f -p $PORT -h /www")
+		Expect(f.Files()).To(ContainElement("Procfile"))
+		Expect(DockerfileHTTP.Files()).NotTo(ContainElement("Procfile"))
+	})
+
+	It("builds an image that runs a Go fixture and exposes its port", func() {
+		image, err := Go.WithPort(8080).Image()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image.Layers).To(HaveLen(1))
+
+		var config struct {
+			OS     string `json:"os"`
+			Config struct {
+				Cmd          []string
+				ExposedPorts map[string]struct{}
+			} `json:"config"`
+			RootFS struct {
+				DiffIDs []string `json:"diff_ids"`
+			} `json:"rootfs"`
+		}
+		Expect(json.Unmarshal(image.Config, &config)).To(Succeed())
+		Expect(config.OS).To(Equal("linux"))
+		Expect(config.Config.Cmd).To(Equal([]string{"/app"}))
+		Expect(config.Config.ExposedPorts).To(HaveKey("8080/tcp"))
+		Expect(config.RootFS.DiffIDs).To(Equal([]string{fmt.Sprintf("sha256:%x", sha256.Sum256(image.Layers[0]))}))
+
+		tr := tar.NewReader(bytes.NewReader(image.Layers[0]))
+		hdr, err := tr.Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hdr.Name).To(Equal("app"))
+		Expect(hdr.Mode & 0111).NotTo(BeZero())
+		binary, err := ioutil.ReadAll(tr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binary[:4]).To(Equal([]byte("\x7fELF")))
+		_, err = tr.Next()
+		Expect(err).To(Equal(io.EOF))
+
+		_, err = DockerfileHTTP.Image()
+		Expect(err).To(MatchError(ContainSubstring("not a Go app")))
+	})
+
+	It("refuses to overwrite an existing directory", func() {
+		_, err := Go.Materialize(parent)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Go.Materialize(parent)
+		Expect(err).To(HaveOccurred())
+	})
+
+})
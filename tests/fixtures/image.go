@@ -0,0 +1,85 @@
+package fixtures
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// The functions in this file build a Docker image from a Go fixture without a Docker daemon, so
+// that the suite may serve the image that specs deploy from a registry of its own.
+
+// imageBinary is the path of the fixture's binary within the images built by Image.
+const imageBinary = "/app"
+
+// Image is a Docker image in the form in which a registry stores it: its config, and its layers
+// as uncompressed tarballs, base layer first.
+type Image struct {
+	Config []byte
+	Layers [][]byte
+}
+
+// Image compiles the fixture, which must be a Go app such as Go, into a static linux/amd64 binary
+// and returns an image that runs it and exposes its port. The image has a single layer, which
+// holds nothing but the binary.
+func (f Fixture) Image() (Image, error) {
+	if _, ok := f.files["main.go"]; !ok {
+		return Image{}, fmt.Errorf("%s is not a Go app", f.Name)
+	}
+	dir, err := ioutil.TempDir("", f.Name)
+	if err != nil {
+		return Image{}, err
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(f.Contents("main.go")), 0644); err != nil {
+		return Image{}, err
+	}
+	build := exec.Command("go", "build", "-o", "app", "main.go")
+	build.Dir = dir
+	build.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux", "GOARCH=amd64")
+	if output, err := build.CombinedOutput(); err != nil {
+		return Image{}, fmt.Errorf("go build failed (%s): %s", err, output)
+	}
+	binary, err := ioutil.ReadFile(filepath.Join(dir, "app"))
+	if err != nil {
+		return Image{}, err
+	}
+
+	var layer bytes.Buffer
+	tw := tar.NewWriter(&layer)
+	hdr := &tar.Header{Name: imageBinary[1:], Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return Image{}, err
+	}
+	if _, err := tw.Write(binary); err != nil {
+		return Image{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return Image{}, err
+	}
+
+	port := strconv.Itoa(f.Port) + "/tcp"
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"config": map[string]interface{}{
+			"Cmd":          []string{imageBinary},
+			"ExposedPorts": map[string]struct{}{port: {}},
+		},
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": []string{fmt.Sprintf("sha256:%x", sha256.Sum256(layer.Bytes()))},
+		},
+	})
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Config: config, Layers: [][]byte{layer.Bytes()}}, nil
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/git"
 	"github.com/deis/workflow-e2e/tests/cmd/keys"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	"github.com/deis/workflow-e2e/tests/fixtures"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
 
@@ -43,8 +46,7 @@ var _ = Describe("git push deis master", func() {
 			Context("and who has a local git repo containing buildpack source code", func() {
 
 				BeforeEach(func() {
-					output, err := cmd.Execute(`git clone https://github.com/deis/example-go.git`)
-					Expect(err).NotTo(HaveOccurred(), output)
+					writeFixture(fixtures.Go)
 				})
 
 				Context("and has run `deis apps:create` from within that repo", func() {
@@ -52,7 +54,7 @@ var _ = Describe("git push deis master", func() {
 					var app model.App
 
 					BeforeEach(func() {
-						os.Chdir("example-go")
+						os.Chdir(fixtures.Go.Name)
 						app = apps.Create(user)
 					})
 
@@ -64,6 +66,16 @@ var _ = Describe("git push deis master", func() {
 						git.Push(user, keyPath, app, "Powered by Deis")
 					})
 
+					Specify("that app remains responsive while it is redeployed using a git push", func() {
+						git.Push(user, keyPath, app, "Powered by Deis")
+						output, err := cmd.Execute(`git commit --allow-empty -m "Redeploy"`)
+						Expect(err).NotTo(HaveOccurred(), output)
+
+						probe.ExpectAvailableDuring(app.URL, func() {
+							git.Push(user, keyPath, app, "Powered by Deis")
+						})
+					})
+
 					Specify("that user can interrupt the deploy of the app and recover", func() {
 						git.PushWithInterrupt(user, keyPath)
 
@@ -121,9 +133,7 @@ var _ = Describe("git push deis master", func() {
 					Context("and who has another local git repo containing buildpack source code", func() {
 
 						BeforeEach(func() {
-							os.Chdir("..")
-							output, err := cmd.Execute(`git clone https://github.com/deis/example-nodejs-express.git`)
-							Expect(err).NotTo(HaveOccurred(), output)
+							writeFixture(fixtures.NodeJS)
 						})
 
 						Context("and has run `deis apps:create` from within that repo", func() {
@@ -131,7 +141,7 @@ var _ = Describe("git push deis master", func() {
 							var app2 model.App
 
 							BeforeEach(func() {
-								os.Chdir("example-nodejs-express")
+								os.Chdir(filepath.Join("..", fixtures.NodeJS.Name))
 								app2 = apps.Create(user)
 							})
 
@@ -140,9 +150,9 @@ var _ = Describe("git push deis master", func() {
 							})
 
 							Specify("that user can deploy both apps concurrently", func() {
-								os.Chdir(filepath.Join("..", "example-go"))
+								os.Chdir(filepath.Join("..", fixtures.Go.Name))
 								sess := git.StartPush(user, keyPath)
-								os.Chdir(filepath.Join("..", "example-nodejs-express"))
+								os.Chdir(filepath.Join("..", fixtures.NodeJS.Name))
 								sess2 := git.StartPush(user, keyPath)
 								Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
 								Eventually(sess2, settings.MaxEventuallyTimeout).Should(Exit(0))
@@ -168,8 +178,7 @@ var _ = Describe("git push deis master", func() {
 			Context("and who has a local git repo containing dockerfile source code", func() {
 
 				BeforeEach(func() {
-					output, err := cmd.Execute(`git clone https://github.com/deis/example-dockerfile-http.git`)
-					Expect(err).NotTo(HaveOccurred(), output)
+					writeFixture(fixtures.DockerfileHTTP)
 				})
 
 				Context("and has run `deis apps:create` from within that repo", func() {
@@ -177,7 +186,7 @@ var _ = Describe("git push deis master", func() {
 					var app model.App
 
 					BeforeEach(func() {
-						os.Chdir("example-dockerfile-http")
+						os.Chdir(fixtures.DockerfileHTTP.Name)
 						app = apps.Create(user)
 					})
 
@@ -232,9 +241,7 @@ var _ = Describe("git push deis master", func() {
 					Context("and who has another local git repo containing dockerfile source code", func() {
 
 						BeforeEach(func() {
-							os.Chdir("..")
-							output, err := cmd.Execute(`git clone https://github.com/deis/example-dockerfile-procfile-http.git`)
-							Expect(err).NotTo(HaveOccurred(), output)
+							writeFixture(fixtures.DockerfileProcfile)
 						})
 
 						Context("and has run `deis apps:create` from within that repo", func() {
@@ -242,7 +249,7 @@ var _ = Describe("git push deis master", func() {
 							var app2 model.App
 
 							BeforeEach(func() {
-								os.Chdir("example-dockerfile-procfile-http")
+								os.Chdir(filepath.Join("..", fixtures.DockerfileProcfile.Name))
 								app2 = apps.Create(user)
 							})
 
@@ -251,15 +258,15 @@ var _ = Describe("git push deis master", func() {
 							})
 
 							Specify("that user can deploy both apps concurrently", func() {
-								os.Chdir(filepath.Join("..", "example-dockerfile-http"))
+								os.Chdir(filepath.Join("..", fixtures.DockerfileHTTP.Name))
 								sess := git.StartPush(user, keyPath)
-								os.Chdir(filepath.Join("..", "example-dockerfile-procfile-http"))
+								os.Chdir(filepath.Join("..", fixtures.DockerfileProcfile.Name))
 								sess2 := git.StartPush(user, keyPath)
 								Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
 								Eventually(sess2, settings.MaxEventuallyTimeout).Should(Exit(0))
 								git.Curl(app, "Powered by Deis")
 								git.Curl(app2, "Powered by Deis")
-								_ = listProcs(user, app2, "web")
+								Expect(ps.List(user, app2, "web")).NotTo(BeEmpty())
 							})
 
 						})
@@ -5,6 +5,7 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/kube"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
 
@@ -63,6 +64,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`Exec Probe\: Command=\[/bin/true]`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.LivenessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("exec /bin/true"))
 			})
 
 			// 1500 is the port of the app we are deploying deis/example-dockerfile-http
@@ -79,6 +86,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`HTTP GET Probe\: Path="/" Port=1500 HTTPHeaders=\[]`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.LivenessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("httpGet / 1500"))
 			})
 
 			Specify("that user can set a tcpSocket liveness healthcheck", func() {
@@ -94,6 +107,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`TCP Socket Probe\: Port=1500`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.LivenessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("tcpSocket 1500"))
 			})
 
 			Specify("that user can set an exec readiness healthcheck", func() {
@@ -109,6 +128,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`Exec Probe\: Command=\[/bin/true]`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.ReadinessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("exec /bin/true"))
 			})
 
 			Specify("that user can set a httpGet readiness healthcheck", func() {
@@ -124,6 +149,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`HTTP GET Probe\: Path="/" Port=1500 HTTPHeaders=\[]`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.ReadinessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("httpGet / 1500"))
 			})
 
 			Specify("that user can set a tcpSocket readiness healthcheck", func() {
@@ -139,6 +170,12 @@ var _ = Describe("deis healthchecks", func() {
 				Eventually(sess).Should(Say(`TCP Socket Probe\: Port=1500`))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the healthcheck was applied to the app's pods
+				Eventually(func() (string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.ReadinessProbe(spec), err
+				}, settings.MaxEventuallyTimeout).Should(Equal("tcpSocket 1500"))
 			})
 
 			Context("and already has a healthcheck set", func() {
@@ -164,6 +201,12 @@ var _ = Describe("deis healthchecks", func() {
 					Eventually(sess).ShouldNot(Say(`Exec Probe\: Command=\[/bin/true]`))
 					Expect(err).NotTo(HaveOccurred())
 					Eventually(sess).Should(Exit(0))
+
+					// Check that the healthcheck was removed from the app's pods
+					Eventually(func() (string, error) {
+						spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+						return kube.ReadinessProbe(spec), err
+					}, settings.MaxEventuallyTimeout).Should(BeEmpty())
 				})
 			})
 		})
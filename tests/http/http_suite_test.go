@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP")
+}
+
+// dialer connects to the host it is asked to, rather than to the router.
+var dialer = &net.Dialer{Timeout: 10 * time.Second}
+
+// direct returns the specified Probe, made to connect to the host in its URL rather than to the
+// router, so that specs reach their own servers however the environment routes requests.
+func direct(p Probe) Probe {
+	p.Dialer = dialer
+	return p
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The functions in this file generate load against an app in the background, so that its
+// availability can be measured while some operation is performed on it.
+
+// maxInFlight is how many of a Load's requests may be awaiting a response at once. If an app
+// stops responding, requests are not made without limit while they wait to time out.
+const maxInFlight = 100
+
+// Histogram records latencies. It keeps every one, so that percentiles are exact; a Load records
+// no more than a few thousand.
+type Histogram struct {
+	samples []time.Duration
+	Max     time.Duration
+}
+
+// Record adds a latency to the histogram.
+func (h *Histogram) Record(latency time.Duration) {
+	h.samples = append(h.samples, latency)
+	if latency > h.Max {
+		h.Max = latency
+	}
+}
+
+// Count returns the number of latencies recorded.
+func (h Histogram) Count() int {
+	return len(h.samples)
+}
+
+// Percentile returns the pth percentile (0 < p <= 100) of the recorded latencies: the lowest
+// latency that is at least as high as p percent of them. It returns 0 if none were recorded.
+func (h Histogram) Percentile(p float64) time.Duration {
+	if len(h.samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(h.samples))
+	copy(sorted, h.samples)
+	sort.Sort(byDuration(sorted))
+	rank := int(math.Ceil(float64(len(sorted)) * p / 100))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+type byDuration []time.Duration
+
+func (d byDuration) Len() int           { return len(d) }
+func (d byDuration) Less(i, j int) bool { return d[i] < d[j] }
+func (d byDuration) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// LoadReport describes every response received while generating load.
+type LoadReport struct {
+	// StatusCodes counts responses by their status code. Requests that failed without a response
+	// are counted under status code 0.
+	StatusCodes map[int]int
+	// Skipped counts the requests that were not made because too many were already awaiting a
+	// response. Each counts as an error.
+	Skipped  int
+	Latency  Histogram
+	Duration time.Duration
+}
+
+// Requests returns the number of requests that were made.
+func (r LoadReport) Requests() int {
+	requests := 0
+	for _, count := range r.StatusCodes {
+		requests += count
+	}
+	return requests
+}
+
+// ErrorRate returns the fraction of requests that failed without a response, received a 4xx or
+// 5xx response or were skipped.
+func (r LoadReport) ErrorRate() float64 {
+	attempts := r.Requests() + r.Skipped
+	if attempts == 0 {
+		return 0
+	}
+	errors := r.Skipped
+	for code, count := range r.StatusCodes {
+		if code == 0 || code >= 400 {
+			errors += count
+		}
+	}
+	return float64(errors) / float64(attempts)
+}
+
+// String returns the LoadReport in printable form.
+func (r LoadReport) String() string {
+	return fmt.Sprintf("[Requests: '%d', Skipped: '%d', Duration: '%s', StatusCodes: '%v', ErrorRate: '%.4f', P50: '%s', P99: '%s', Max: '%s']",
+		r.Requests(), r.Skipped, r.Duration, r.StatusCodes, r.ErrorRate(), r.Latency.Percentile(50), r.Latency.Percentile(99), r.Latency.Max)
+}
+
+// Load makes the request described by a Probe at a fixed rate in the background, until stopped.
+type Load struct {
+	probe  Probe
+	stop   chan struct{}
+	done   chan struct{}
+	mu     sync.Mutex
+	report LoadReport
+}
+
+// StartLoad starts making the request described by the specified Probe the specified number of
+// times per second. Requests are made concurrently, so slow responses do not lower the rate, but
+// a request is skipped rather than made if maxInFlight are already awaiting a response.
+func StartLoad(probe Probe, rate int) *Load {
+	return startLoad(probe, rate, maxInFlight)
+}
+
+func startLoad(probe Probe, rate, limit int) *Load {
+	l := &Load{
+		probe:  probe,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		report: LoadReport{StatusCodes: map[int]int{}},
+	}
+	go l.run(time.Second/time.Duration(rate), limit)
+	return l
+}
+
+func (l *Load) run(interval time.Duration, limit int) {
+	defer close(l.done)
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, limit)
+	start := time.Now()
+	tck := time.NewTicker(interval)
+	defer tck.Stop()
+	for {
+		select {
+		case <-tck.C:
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				l.mu.Lock()
+				l.report.Skipped++
+				l.mu.Unlock()
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-inFlight }()
+				response := l.probe.Do()
+				l.mu.Lock()
+				defer l.mu.Unlock()
+				l.report.StatusCodes[response.StatusCode]++
+				if response.Err == nil {
+					l.report.Latency.Record(response.Latency)
+				}
+			}()
+		case <-l.stop:
+			wg.Wait()
+			l.report.Duration = time.Since(start)
+			return
+		}
+	}
+}
+
+// Stop stops making requests, waits for those in flight to complete, and reports on all of them.
+func (l *Load) Stop() LoadReport {
+	close(l.stop)
+	<-l.done
+	return l.report
+}
+
+// Thresholds are the limits on a LoadReport that constitute an app remaining available.
+type Thresholds struct {
+	MaxErrorRate float64
+	MaxP99       time.Duration
+}
+
+// DefaultThresholds returns the Thresholds configured by $LOAD_MAX_ERROR_RATE and $LOAD_MAX_P99.
+func DefaultThresholds() Thresholds {
+	return Thresholds{MaxErrorRate: settings.LoadMaxErrorRate, MaxP99: settings.LoadMaxP99}
+}
+
+// ExpectAvailableDuring waits for the app at the specified URL to respond successfully, then
+// executes the specified operation while making requests of the app at the rate configured by
+// $LOAD_RATE. It fails the current spec if the responses received exceed DefaultThresholds.
+func ExpectAvailableDuring(url string, operation func()) {
+	ExpectAvailableDuringWith(Get(url), settings.LoadRate, DefaultThresholds(), operation)
+}
+
+// ExpectAvailableDuringWith is like ExpectAvailableDuring, but allows the request, rate and
+// thresholds to be specified.
+func ExpectAvailableDuringWith(probe Probe, rate int, thresholds Thresholds, operation func()) {
+	_, err := Until(probe, poll.New(settings.DefaultEventuallyTimeout), Satisfying(Expected{StatusCode: 200}))
+	Expect(err).NotTo(HaveOccurred(), "the app was not available to begin with")
+	load := StartLoad(probe, rate)
+	operation()
+	report := load.Stop()
+	fmt.Fprintf(ginkgo.GinkgoWriter, "Load against %s: %s\n", probe.URL, report)
+	Expect(report.Requests()).To(BeNumerically(">", 0), report.String())
+	Expect(report.ErrorRate()).To(BeNumerically("<=", thresholds.MaxErrorRate), report.String())
+	Expect(report.Latency.Percentile(99)).To(BeNumerically("<=", thresholds.MaxP99), report.String())
+}
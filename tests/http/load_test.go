@@ -0,0 +1,115 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Histogram", func() {
+
+	It("reports no latency if none were recorded", func() {
+		var h Histogram
+		Expect(h.Count()).To(Equal(0))
+		Expect(h.Percentile(50)).To(BeZero())
+		Expect(h.Percentile(99)).To(BeZero())
+	})
+
+	It("reports exact percentiles, however they are recorded", func() {
+		var h Histogram
+		for i := 100; i >= 1; i-- {
+			h.Record(time.Duration(i) * time.Millisecond)
+		}
+		Expect(h.Count()).To(Equal(100))
+		Expect(h.Percentile(50)).To(Equal(50 * time.Millisecond))
+		Expect(h.Percentile(99)).To(Equal(99 * time.Millisecond))
+		Expect(h.Percentile(100)).To(Equal(100 * time.Millisecond))
+		Expect(h.Percentile(0.1)).To(Equal(time.Millisecond))
+		Expect(h.Max).To(Equal(100 * time.Millisecond))
+	})
+
+	It("does not round a percentile up to a power of two", func() {
+		var h Histogram
+		for i := 0; i < 99; i++ {
+			h.Record(10 * time.Millisecond)
+		}
+		h.Record(1100 * time.Millisecond)
+		h.Record(1500 * time.Millisecond)
+		Expect(h.Percentile(99)).To(Equal(1100 * time.Millisecond))
+		Expect(h.Percentile(100)).To(Equal(1500 * time.Millisecond))
+		Expect(h.Percentile(99)).To(BeNumerically("<=", 2*time.Second))
+	})
+
+})
+
+var _ = Describe("LoadReport", func() {
+
+	It("counts requests that failed, received an error or were skipped as errors", func() {
+		report := LoadReport{StatusCodes: map[int]int{200: 6, 301: 1, 503: 1, 0: 1}, Skipped: 1}
+		Expect(report.Requests()).To(Equal(9))
+		Expect(report.ErrorRate()).To(BeNumerically("~", 0.3, 1e-9))
+		Expect(report.String()).To(ContainSubstring("Requests: '9', Skipped: '1'"))
+		Expect(report.String()).To(ContainSubstring("ErrorRate: '0.3000'"))
+	})
+
+	It("has no errors if no requests were made", func() {
+		Expect(LoadReport{}.ErrorRate()).To(BeZero())
+	})
+
+})
+
+var _ = Describe("Load", func() {
+
+	It("makes requests at the specified rate and reports on every response", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Powered by Deis"))
+		}))
+		defer server.Close()
+
+		load := StartLoad(direct(Get(server.URL)), 100)
+		time.Sleep(500 * time.Millisecond)
+		report := load.Stop()
+		Expect(report.Requests()).To(BeNumerically("~", 50, 25))
+		Expect(report.StatusCodes).To(HaveLen(1))
+		Expect(report.StatusCodes).To(HaveKey(http.StatusOK))
+		Expect(report.Skipped).To(BeZero())
+		Expect(report.Latency.Count()).To(Equal(report.Requests()))
+		Expect(report.ErrorRate()).To(BeZero())
+		Expect(report.Duration).To(BeNumerically("~", 500*time.Millisecond, 100*time.Millisecond))
+	})
+
+	It("skips requests rather than make more than the limit at once", func() {
+		var mu sync.Mutex
+		inFlight, maxSeen := 0, 0
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxSeen {
+				maxSeen = inFlight
+			}
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		load := startLoad(direct(Get(server.URL)), 100, 5)
+		time.Sleep(300 * time.Millisecond)
+		close(release)
+		report := load.Stop()
+		Expect(report.Requests()).To(Equal(5))
+		Expect(report.Skipped).To(BeNumerically(">", 0))
+		Expect(report.ErrorRate()).To(BeNumerically(">", 0.5))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(maxSeen).To(Equal(5))
+	})
+
+})
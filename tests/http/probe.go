@@ -0,0 +1,306 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/resolver"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// The functions in this file implement HTTP probing of apps, as an alternative to executing curl.
+// Requests are routed to the Workflow router (see the resolver package) unless a Probe says
+// otherwise.
+
+// DefaultSourceIPHeader is the header used to convey a Probe's SourceIP to the router.
+const DefaultSourceIPHeader = "X-Forwarded-For"
+
+// Probe describes an HTTP request to make of an app.
+type Probe struct {
+	URL    string
+	Method string
+	Header http.Header
+	// SourceIP, if set, is sent in the SourceIPHeader (or DefaultSourceIPHeader) so that the
+	// router treats the request as having originated from that address.
+	SourceIP       string
+	SourceIPHeader string
+	// FollowRedirects causes redirects to be followed, like `curl -L`. The Response then describes
+	// the final response, and its Redirects the URLs that were redirected to. Otherwise, the
+	// Response describes the redirect itself.
+	FollowRedirects bool
+	Timeout         time.Duration
+	// RootCAs, if set, causes the server's certificate to be verified against them, for the
+	// hostname in the URL. Otherwise, it is not verified at all.
+	RootCAs *x509.CertPool
+	// Dialer, if set, makes the connections over which the request is sent. Otherwise they are
+	// made to the router, by resolver.DialContext.
+	Dialer Dialer
+}
+
+// Dialer makes connections. *net.Dialer is one.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// Get returns a Probe that will GET the specified URL, following redirects as `curl -L` does.
+func Get(url string) Probe {
+	return Probe{URL: url, Method: "GET", Header: http.Header{}, FollowRedirects: true, Timeout: 10 * time.Second}
+}
+
+// Response is the outcome of a Probe. If the request failed entirely, Err is set and nothing else
+// is.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Redirects are the URLs redirected to, in order, if the Probe followed redirects.
+	Redirects []string
+	// PeerCertificates are the certificates presented by the server, if the final request was made
+	// over TLS.
+	PeerCertificates []*x509.Certificate
+	Latency          time.Duration
+	Err              error
+}
+
+// Do makes the request described by the Probe.
+func (p Probe) Do() Response {
+	return p.DoContext(context.Background())
+}
+
+// DoContext is like Do, but the request is abandoned if ctx expires before the response has been
+// read.
+func (p Probe) DoContext(ctx context.Context) Response {
+	method := p.Method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequest(method, p.URL, nil)
+	if err != nil {
+		return Response{Err: err}
+	}
+	req = req.WithContext(ctx)
+	for key, values := range p.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if p.SourceIP != "" {
+		header := p.SourceIPHeader
+		if header == "" {
+			header = DefaultSourceIPHeader
+		}
+		req.Header.Set(header, p.SourceIP)
+	}
+
+	start := time.Now()
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return Response{Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Response{Err: err}
+	}
+	// Each request made to follow a redirect refers to the response that caused it.
+	var redirects []string
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		redirects = append([]string{r.URL.String()}, redirects...)
+	}
+	response := Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Redirects:  redirects,
+		Latency:    time.Since(start),
+	}
+	if resp.TLS != nil {
+		response.PeerCertificates = resp.TLS.PeerCertificates
+	}
+	return response
+}
+
+// clientKey is the part of a Probe that determines how its client is configured.
+type clientKey struct {
+	followRedirects bool
+	timeout         time.Duration
+	rootCAs         *x509.CertPool
+	https           bool
+	dialer          Dialer
+}
+
+var (
+	clientsMu sync.Mutex
+	// clients are shared by every Probe configured alike, such as a Probe that is being polled, so
+	// that connections are reused instead of being left open by a new client for every request.
+	clients = map[clientKey]*http.Client{}
+)
+
+// client returns the client with which to make the request described by the Probe.
+func (p Probe) client() *http.Client {
+	key := clientKey{
+		followRedirects: p.FollowRedirects,
+		timeout:         p.Timeout,
+		rootCAs:         p.RootCAs,
+		https:           strings.HasPrefix(p.URL, "https:"),
+		dialer:          p.Dialer,
+	}
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if client, ok := clients[key]; ok {
+		return client
+	}
+	transport := resolver.Transport()
+	if key.dialer != nil {
+		transport.DialContext = key.dialer.DialContext
+	}
+	if key.rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: key.rootCAs}
+	}
+	// A connection that was kept alive would report the certificate served when it was made, not
+	// the one served now, so each request over TLS makes a new handshake.
+	transport.DisableKeepAlives = key.https
+	client := &http.Client{Transport: transport, Timeout: key.timeout}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !key.followRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+		return nil
+	}
+	clients[key] = client
+	return client
+}
+
+// Expected describes an expected Response. Only the fields that are set are checked.
+type Expected struct {
+	StatusCode int
+	// Header maps header names to substrings that their values must contain.
+	Header map[string]string
+	// Body is a substring that the body must contain.
+	Body string
+	// Redirects are substrings that the URLs redirected to must contain, in order.
+	Redirects []string
+	// PeerCommonName is the common name that the server's certificate must have.
+	PeerCommonName string
+	MaxLatency     time.Duration
+	// Err, if set, is a substring that the error with which the request failed must contain. The
+	// other expectations are then ignored.
+	Err string
+}
+
+// Satisfies returns whether or not the Response meets all of the expectations contained in the
+// Expected.
+func (r Response) Satisfies(e Expected) bool {
+	if e.Err != "" {
+		return r.Err != nil && strings.Contains(r.Err.Error(), e.Err)
+	}
+	if r.Err != nil {
+		return false
+	}
+	if e.StatusCode != 0 && r.StatusCode != e.StatusCode {
+		return false
+	}
+	for key, value := range e.Header {
+		if !strings.Contains(r.Header.Get(key), value) {
+			return false
+		}
+	}
+	if !bytes.Contains(r.Body, []byte(e.Body)) {
+		return false
+	}
+	if e.Redirects != nil {
+		if len(r.Redirects) != len(e.Redirects) {
+			return false
+		}
+		for i := range e.Redirects {
+			if !strings.Contains(r.Redirects[i], e.Redirects[i]) {
+				return false
+			}
+		}
+	}
+	if e.PeerCommonName != "" {
+		if len(r.PeerCertificates) == 0 || r.PeerCertificates[0].Subject.CommonName != e.PeerCommonName {
+			return false
+		}
+	}
+	if e.MaxLatency != 0 && r.Latency > e.MaxLatency {
+		return false
+	}
+	return true
+}
+
+// String returns the Response in printable form.
+func (r Response) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("[Err: '%s']", r.Err)
+	}
+	peer := ""
+	if len(r.PeerCertificates) > 0 {
+		peer = r.PeerCertificates[0].Subject.CommonName
+	}
+	return fmt.Sprintf("[StatusCode: '%d', Location: '%s', Redirects: '%v', PeerCommonName: '%s', Latency: '%s']",
+		r.StatusCode, r.Header.Get("Location"), r.Redirects, peer, r.Latency)
+}
+
+// String returns the Expected in printable form.
+func (e Expected) String() string {
+	if e.Err != "" {
+		return fmt.Sprintf("[Err: '%s']", e.Err)
+	}
+	return fmt.Sprintf("[StatusCode: '%d', Header: '%v', Body: '%s', Redirects: '%v', PeerCommonName: '%s', MaxLatency: '%s']",
+		e.StatusCode, e.Header, e.Body, e.Redirects, e.PeerCommonName, e.MaxLatency)
+}
+
+// Satisfying returns a predicate that holds for the results of probes whose Response satisfies e.
+func Satisfying(e Expected) poll.Predicate {
+	return func(r poll.Result) (bool, string) {
+		response, ok := r.Value.(Response)
+		if !ok {
+			return false, "not the result of a probe"
+		}
+		if !response.Satisfies(e) {
+			return false, fmt.Sprintf("%s does not satisfy %s", response, e)
+		}
+		return true, ""
+	}
+}
+
+// Until makes the request described by the Probe repeatedly, as the poller dictates, until the
+// result satisfies every one of preds, and returns the last Response. The body and status code of
+// each Response are the Out and Code of its poll.Result, and the Response itself is its Value. If
+// the poller gives up first, it also returns a *poll.Failure describing every response.
+func Until(probe Probe, poller poll.Poller, preds ...poll.Predicate) (Response, error) {
+	if poller.Log == nil {
+		poller.Log = ginkgo.GinkgoWriter
+	}
+	result, err := poller.Until(fmt.Sprintf("%s %s", probe.Method, probe.URL), func(ctx context.Context) poll.Result {
+		response := probe.DoContext(ctx)
+		return poll.Result{Out: response.Body, Code: response.StatusCode, Error: response.Err, Value: response}
+	}, preds...)
+	response, _ := result.Value.(Response)
+	return response, err
+}
+
+// ExpectEventually makes the request described by the Probe repeatedly, for up to
+// settings.DefaultEventuallyTimeout, until the response satisfies the expected response, failing
+// the spec if it never does.
+func ExpectEventually(probe Probe, expected Expected) {
+	_, err := Until(probe, poll.New(settings.DefaultEventuallyTimeout), Satisfying(expected))
+	gomega.ExpectWithOffset(1, err).NotTo(gomega.HaveOccurred())
+}
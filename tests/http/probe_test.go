@@ -0,0 +1,231 @@
+package http
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/pki"
+	"github.com/deis/workflow-e2e/tests/poll"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Probe", func() {
+
+	var server *httptest.Server
+	var mu sync.Mutex
+	var connections int
+
+	// countConnections records how many connections the server accepts.
+	countConnections := func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+
+	newConnections := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return connections
+	}
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Seen-Real-IP", r.Header.Get("X-Real-IP"))
+		w.Header().Set("X-Seen-Greeting", r.Header.Get("X-Greeting"))
+		w.Write([]byte("Powered by Deis"))
+	})
+	handler.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+	})
+	handler.HandleFunc("/moved-again", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	handler.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	// unavailable is how many more requests for /flaky will be refused.
+	var unavailable int
+	handler.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if unavailable > 0 {
+			unavailable--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("Powered by Deis"))
+	})
+
+	BeforeEach(func() {
+		connections = 0
+		unavailable = 2
+		server = httptest.NewUnstartedServer(handler)
+		server.Config.ConnState = countConnections
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("over HTTP", func() {
+
+		BeforeEach(func() {
+			server.Start()
+		})
+
+		It("describes the response to a request", func() {
+			p := direct(Get(server.URL))
+			p.Header.Set("X-Greeting", "hello")
+			response := p.Do()
+			Expect(response.Err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(response.Body)).To(Equal("Powered by Deis"))
+			Expect(response.Header.Get("X-Seen-Greeting")).To(Equal("hello"))
+			Expect(response.Redirects).To(BeEmpty())
+			Expect(response.PeerCertificates).To(BeEmpty())
+			Expect(response.Latency).To(BeNumerically(">", 0))
+		})
+
+		It("sends its source IP in the header the router trusts", func() {
+			p := direct(Get(server.URL))
+			p.SourceIP = "192.0.2.1"
+			Expect(p.Do().Header.Get("X-Seen-Forwarded-For")).To(Equal("192.0.2.1"))
+			p.SourceIPHeader = "X-Real-IP"
+			Expect(p.Do().Header.Get("X-Seen-Real-IP")).To(Equal("192.0.2.1"))
+		})
+
+		It("follows redirects like `curl -L`, recording where it was redirected to", func() {
+			response := direct(Get(server.URL + "/moved")).Do()
+			Expect(response.Err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Redirects).To(Equal([]string{server.URL + "/moved-again", server.URL + "/"}))
+			Expect(response.Satisfies(Expected{StatusCode: http.StatusOK, Redirects: []string{"/moved-again", "/"}})).To(BeTrue())
+		})
+
+		It("describes the redirect itself if it does not follow redirects", func() {
+			p := direct(Get(server.URL + "/moved"))
+			p.FollowRedirects = false
+			response := p.Do()
+			Expect(response.Err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusMovedPermanently))
+			Expect(response.Header.Get("Location")).To(Equal("/moved-again"))
+			Expect(response.Redirects).To(BeEmpty())
+		})
+
+		It("gives up on a redirect loop", func() {
+			response := direct(Get(server.URL + "/loop")).Do()
+			Expect(response.Err).To(MatchError(ContainSubstring("stopped after 10 redirects")))
+			Expect(response.Satisfies(Expected{Err: "stopped after"})).To(BeTrue())
+			Expect(response.Satisfies(Expected{StatusCode: http.StatusFound})).To(BeFalse())
+		})
+
+		It("reuses its connection when polled", func() {
+			p := direct(Get(server.URL))
+			for i := 0; i < 5; i++ {
+				Expect(p.Do().StatusCode).To(Equal(http.StatusOK))
+			}
+			Expect(newConnections()).To(Equal(1))
+		})
+
+		It("polls until the response is as expected", func() {
+			poller := poll.Every(10*time.Millisecond, time.Second)
+			poller.Log = GinkgoWriter
+			response, err := Until(direct(Get(server.URL+"/flaky")), poller, Satisfying(Expected{StatusCode: http.StatusOK, Body: "Deis"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			poller = poll.Every(10*time.Millisecond, 100*time.Millisecond)
+			poller.Log = GinkgoWriter
+			_, err = Until(direct(Get(server.URL)), poller, Satisfying(Expected{StatusCode: http.StatusNotFound}))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not satisfy"))
+		})
+
+	})
+
+	Context("over HTTPS", func() {
+
+		var authority *pki.Authority
+
+		BeforeEach(func() {
+			var err error
+			authority, err = pki.NewAuthority("Test Root")
+			Expect(err).NotTo(HaveOccurred())
+			leaf, err := authority.Issue(pki.Request{CommonName: "localhost", DNSNames: []string{"localhost"}})
+			Expect(err).NotTo(HaveOccurred())
+			key, err := leaf.KeyPEM()
+			Expect(err).NotTo(HaveOccurred())
+			cert, err := tls.X509KeyPair(leaf.CertPEM(), key)
+			Expect(err).NotTo(HaveOccurred())
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+			server.StartTLS()
+			server.URL = strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+		})
+
+		It("records the certificate served, verifying it only against the specified roots", func() {
+			response := direct(Get(server.URL)).Do()
+			Expect(response.Err).NotTo(HaveOccurred())
+			Expect(response.Satisfies(Expected{StatusCode: http.StatusOK, PeerCommonName: "localhost"})).To(BeTrue())
+
+			verified := direct(Get(server.URL))
+			verified.RootCAs = authority.Pool()
+			Expect(verified.Do().Err).NotTo(HaveOccurred())
+
+			other, err := pki.NewAuthority("Other Root")
+			Expect(err).NotTo(HaveOccurred())
+			verified.RootCAs = other.Pool()
+			Expect(verified.Do().Satisfies(Expected{Err: "certificate signed by unknown authority"})).To(BeTrue())
+		})
+
+		It("makes a new handshake for every request, so that the certificate served is current", func() {
+			p := direct(Get(server.URL))
+			for i := 0; i < 3; i++ {
+				Expect(p.Do().PeerCertificates).NotTo(BeEmpty())
+			}
+			Expect(newConnections()).To(Equal(3))
+		})
+
+	})
+
+	Describe("Expected", func() {
+
+		response := Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			Body:       []byte("Powered by Deis"),
+			Latency:    100 * time.Millisecond,
+		}
+
+		It("is satisfied by a response that meets every expectation that is set", func() {
+			Expect(response.Satisfies(Expected{})).To(BeTrue())
+			Expect(response.Satisfies(Expected{
+				StatusCode: http.StatusOK,
+				Header:     map[string]string{"Content-Type": "text/plain"},
+				Body:       "Deis",
+				MaxLatency: time.Second,
+			})).To(BeTrue())
+		})
+
+		It("is not satisfied by a response that misses any one of them", func() {
+			Expect(response.Satisfies(Expected{StatusCode: http.StatusNotFound})).To(BeFalse())
+			Expect(response.Satisfies(Expected{Header: map[string]string{"Content-Type": "json"}})).To(BeFalse())
+			Expect(response.Satisfies(Expected{Body: "Heroku"})).To(BeFalse())
+			Expect(response.Satisfies(Expected{Redirects: []string{"/"}})).To(BeFalse())
+			Expect(response.Satisfies(Expected{PeerCommonName: "localhost"})).To(BeFalse())
+			Expect(response.Satisfies(Expected{MaxLatency: time.Millisecond})).To(BeFalse())
+			Expect(response.Satisfies(Expected{Err: "refused"})).To(BeFalse())
+		})
+
+	})
+
+})
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/poll"
+	"github.com/deis/workflow-e2e/tests/resolver"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// The functions in this file implement TLS handshakes with the router, to check which certificates
+// it serves for a hostname without making an HTTP request at all.
+
+// Handshake describes a TLS handshake to make with the router.
+type Handshake struct {
+	// ServerName is the hostname sent by SNI, and the name for which the served certificate is
+	// verified.
+	ServerName string
+	Timeout    time.Duration
+}
+
+// HandshakeWith returns a Handshake that sends the specified hostname by SNI.
+func HandshakeWith(serverName string) Handshake {
+	return Handshake{ServerName: serverName, Timeout: 10 * time.Second}
+}
+
+// Served is the outcome of a Handshake. If the handshake failed, Err is set and nothing else is.
+type Served struct {
+	ServerName string
+	// Certificates are the certificates the router presented: the leaf, followed by its chain.
+	Certificates []*x509.Certificate
+	Err          error
+}
+
+// Do makes the handshake described by the Handshake. The certificates presented are recorded, but
+// not verified.
+func (h Handshake) Do() Served {
+	return h.DoContext(context.Background())
+}
+
+// DoContext is like Do, but the handshake is abandoned if ctx expires before it is complete.
+func (h Handshake) DoContext(ctx context.Context) Served {
+	u, err := url.Parse(resolver.URL("https", h.ServerName))
+	if err != nil {
+		return Served{Err: err}
+	}
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "443")
+	}
+	if h.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	raw, err := resolver.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return Served{Err: err}
+	}
+	defer raw.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		raw.SetDeadline(deadline)
+	}
+	conn := tls.Client(raw, &tls.Config{ServerName: h.ServerName, InsecureSkipVerify: true})
+	if err := conn.Handshake(); err != nil {
+		return Served{Err: err}
+	}
+	return Served{ServerName: h.ServerName, Certificates: conn.ConnectionState().PeerCertificates}
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the specified certificate, as colon-separated,
+// upper case hex pairs, the form in which `deis certs:info` prints it.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":")
+}
+
+// ExpectedCert describes the expected outcome of a Handshake. Only the fields that are set are
+// checked.
+type ExpectedCert struct {
+	// Fingerprint is the fingerprint that the leaf must have.
+	Fingerprint string
+	// NotFingerprint is a fingerprint that the leaf must not have, such as that of a certificate
+	// that has been detached.
+	NotFingerprint string
+	// Chain are the certificates that must follow the leaf, in order. If it is empty but not nil, the
+	// leaf must be presented alone.
+	Chain []*x509.Certificate
+	// Roots, if set, are the roots against which the certificates presented must verify, for the
+	// ServerName, using no intermediates other than those presented.
+	Roots *x509.CertPool
+	// Err, if set, is a substring that the error with which the handshake failed must contain. The
+	// other expectations are then ignored.
+	Err string
+}
+
+// Satisfies returns whether or not the Served meets all of the expectations contained in the
+// ExpectedCert.
+func (s Served) Satisfies(e ExpectedCert) bool {
+	if e.Err != "" {
+		return s.Err != nil && strings.Contains(s.Err.Error(), e.Err)
+	}
+	if s.Err != nil || len(s.Certificates) == 0 {
+		return false
+	}
+	leaf := s.Certificates[0]
+	if e.Fingerprint != "" && Fingerprint(leaf) != e.Fingerprint {
+		return false
+	}
+	if e.NotFingerprint != "" && Fingerprint(leaf) == e.NotFingerprint {
+		return false
+	}
+	if e.Chain != nil {
+		chain := s.Certificates[1:]
+		if len(chain) != len(e.Chain) {
+			return false
+		}
+		for i := range e.Chain {
+			if !bytes.Equal(chain[i].Raw, e.Chain[i].Raw) {
+				return false
+			}
+		}
+	}
+	if e.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range s.Certificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		opts := x509.VerifyOptions{DNSName: s.ServerName, Roots: e.Roots, Intermediates: intermediates}
+		if _, err := leaf.Verify(opts); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the Served in printable form.
+func (s Served) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("[Err: '%s']", s.Err)
+	}
+	names := make([]string, len(s.Certificates))
+	for i, cert := range s.Certificates {
+		names[i] = cert.Subject.CommonName
+	}
+	fingerprint := ""
+	if len(s.Certificates) > 0 {
+		fingerprint = Fingerprint(s.Certificates[0])
+	}
+	return fmt.Sprintf("[ServerName: '%s', Certificates: '%v', Fingerprint: '%s']", s.ServerName, names, fingerprint)
+}
+
+// String returns the ExpectedCert in printable form.
+func (e ExpectedCert) String() string {
+	if e.Err != "" {
+		return fmt.Sprintf("[Err: '%s']", e.Err)
+	}
+	names := make([]string, len(e.Chain))
+	for i, cert := range e.Chain {
+		names[i] = cert.Subject.CommonName
+	}
+	return fmt.Sprintf("[Fingerprint: '%s', NotFingerprint: '%s', Chain: '%v', Verified: '%t']",
+		e.Fingerprint, e.NotFingerprint, names, e.Roots != nil)
+}
+
+// Serving returns a predicate that holds for the results of handshakes whose Served satisfies e.
+func Serving(e ExpectedCert) poll.Predicate {
+	return func(r poll.Result) (bool, string) {
+		served, ok := r.Value.(Served)
+		if !ok {
+			return false, "not the result of a handshake"
+		}
+		if !served.Satisfies(e) {
+			return false, fmt.Sprintf("%s does not satisfy %s", served, e)
+		}
+		return true, ""
+	}
+}
+
+// UntilServed makes the handshake described by the Handshake repeatedly, as the poller dictates,
+// until the result satisfies every one of preds, and returns the last Served, which is the Value
+// of each poll.Result. If the poller gives up first, it also returns a *poll.Failure describing
+// every handshake.
+func UntilServed(handshake Handshake, poller poll.Poller, preds ...poll.Predicate) (Served, error) {
+	if poller.Log == nil {
+		poller.Log = ginkgo.GinkgoWriter
+	}
+	result, err := poller.Until(fmt.Sprintf("TLS handshake with %s", handshake.ServerName), func(ctx context.Context) poll.Result {
+		served := handshake.DoContext(ctx)
+		return poll.Result{Out: []byte(served.String()), Error: served.Err, Value: served}
+	}, preds...)
+	served, _ := result.Value.(Served)
+	return served, err
+}
+
+// ExpectServedEventually makes the handshake described by the Handshake repeatedly, for up to
+// settings.DefaultEventuallyTimeout, until the certificates served satisfy the expected ones,
+// failing the spec if they never do.
+func ExpectServedEventually(handshake Handshake, expected ExpectedCert) {
+	_, err := UntilServed(handshake, poll.New(settings.DefaultEventuallyTimeout), Serving(expected))
+	gomega.ExpectWithOffset(1, err).NotTo(gomega.HaveOccurred())
+}
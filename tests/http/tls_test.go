@@ -0,0 +1,74 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/deis/workflow-e2e/tests/pki"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Served", func() {
+
+	var root *pki.Authority
+	var leaf *pki.Leaf
+	var served Served
+
+	BeforeEach(func() {
+		var err error
+		root, err = pki.NewAuthority("Test Root")
+		Expect(err).NotTo(HaveOccurred())
+		intermediate, err := root.Intermediate("Test Intermediate")
+		Expect(err).NotTo(HaveOccurred())
+		leaf, err = intermediate.Issue(pki.Request{CommonName: "www.foo.com", DNSNames: []string{"www.foo.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		served = Served{ServerName: "www.foo.com", Certificates: append([]*x509.Certificate{leaf.Cert}, leaf.Chain...)}
+	})
+
+	It("fingerprints certificates as `deis certs:info` does", func() {
+		fingerprint := Fingerprint(leaf.Cert)
+		Expect(fingerprint).To(MatchRegexp(`^([0-9A-F]{2}:){31}[0-9A-F]{2}$`))
+		sum := sha256.Sum256(leaf.Cert.Raw)
+		Expect(strings.Replace(fingerprint, ":", "", -1)).To(Equal(fmt.Sprintf("%X", sum)))
+	})
+
+	It("is satisfied by the certificate and chain that were expected", func() {
+		Expect(served.Satisfies(ExpectedCert{})).To(BeTrue())
+		Expect(served.Satisfies(ExpectedCert{
+			Fingerprint:    Fingerprint(leaf.Cert),
+			NotFingerprint: Fingerprint(root.Cert),
+			Chain:          leaf.Chain,
+			Roots:          root.Pool(),
+		})).To(BeTrue())
+	})
+
+	It("is not satisfied by any other", func() {
+		Expect(served.Satisfies(ExpectedCert{Fingerprint: Fingerprint(root.Cert)})).To(BeFalse())
+		Expect(served.Satisfies(ExpectedCert{NotFingerprint: Fingerprint(leaf.Cert)})).To(BeFalse())
+		Expect(served.Satisfies(ExpectedCert{Chain: []*x509.Certificate{}})).To(BeFalse())
+
+		other, err := pki.NewAuthority("Other Root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(served.Satisfies(ExpectedCert{Roots: other.Pool()})).To(BeFalse())
+
+		served.ServerName = "www.bar.com"
+		Expect(served.Satisfies(ExpectedCert{Roots: root.Pool()})).To(BeFalse())
+	})
+
+	It("is not satisfied by a leaf presented without the intermediate that issued it", func() {
+		served.Certificates = served.Certificates[:1]
+		Expect(served.Satisfies(ExpectedCert{Roots: root.Pool()})).To(BeFalse())
+	})
+
+	It("is satisfied by a failed handshake only if one was expected", func() {
+		failed := Served{Err: fmt.Errorf("remote error: tls: unrecognized name")}
+		Expect(failed.Satisfies(ExpectedCert{})).To(BeFalse())
+		Expect(failed.Satisfies(ExpectedCert{Err: "unrecognized name"})).To(BeTrue())
+		Expect(served.Satisfies(ExpectedCert{Err: "unrecognized name"})).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,175 @@
+package janitor
+
+import (
+	"fmt"
+	"io"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/auth"
+	"github.com/deis/controller-sdk-go/certs"
+	"github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/controller-sdk-go/keys"
+	"github.com/deis/controller-sdk-go/perms"
+	"github.com/deis/controller-sdk-go/users"
+	"github.com/deis/workflow-e2e/tests/model"
+)
+
+// listLimit is the number of results requested from the controller when first listing a type of
+// resource. If there are more than this, they are listed again in full.
+const listLimit = 100
+
+// Janitor deletes resources created by the tests. It does not use Gomega, so it is safe to use
+// outside of specs; instead, failures are reported as errors. Its client should be authenticated
+// as an admin, so that it may delete resources belonging to any user. Keys are the exception:
+// only their owner may delete them, so the Janitor logs in as the owner of each key it deletes.
+type Janitor struct {
+	client *deis.Client
+	out    io.Writer
+	// owners holds a client for each user the Janitor has logged in as, keyed by username.
+	owners map[string]*deis.Client
+}
+
+// New returns a Janitor that acts using the specified client and logs what it deletes to out.
+func New(client *deis.Client, out io.Writer) *Janitor {
+	return &Janitor{client: client, out: out, owners: map[string]*deis.Client{}}
+}
+
+// Sweep deletes the specified resources in dependency order. Resources that no longer exist are
+// ignored.
+func (j *Janitor) Sweep(resources []model.Resource) []error {
+	sorted := make([]model.Resource, len(resources))
+	copy(sorted, resources)
+	model.SortForTeardown(sorted)
+
+	var errs []error
+	for _, r := range sorted {
+		err := j.remove(r)
+		if err == deis.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not remove %s %s (%s)", r.Kind, r.Name, err))
+			continue
+		}
+		fmt.Fprintf(j.out, "Removed %s %s\n", r.Kind, r.Name)
+	}
+	return errs
+}
+
+// SweepAll deletes every user, app and cert on the controller whose name matches the naming scheme
+// used by the tests, along with any matching keys belonging to the Janitor's own user. Keys,
+// domains and perms belonging to other users and apps are deleted by the controller along with
+// them.
+func (j *Janitor) SweepAll() []error {
+	resources, err := j.listAll()
+	if err != nil {
+		return []error{err}
+	}
+	return j.Sweep(resources)
+}
+
+func (j *Janitor) remove(r model.Resource) error {
+	switch r.Kind {
+	case model.PermKind:
+		return perms.Delete(j.client, r.App, r.Name)
+	case model.DomainKind:
+		return domains.Delete(j.client, r.App, r.Name)
+	case model.CertKind:
+		return certs.Delete(j.client, r.Name)
+	case model.KeyKind:
+		client, err := j.ownerClient(r.Owner)
+		if err != nil {
+			return err
+		}
+		return keys.Delete(client, r.Name)
+	case model.AppKind:
+		return apps.Delete(j.client, r.Name)
+	case model.UserKind:
+		return auth.Delete(j.client, r.Name)
+	}
+	return fmt.Errorf("unknown resource kind %d", r.Kind)
+}
+
+// ownerClient returns a client authenticated as the specified owner, by logging in with their
+// password. Owners whose password is not known, such as those of the keys found by SweepAll, are
+// assumed to be the Janitor's own user.
+func (j *Janitor) ownerClient(owner model.User) (*deis.Client, error) {
+	if owner.Password == "" {
+		return j.client, nil
+	}
+	if client, ok := j.owners[owner.Username]; ok {
+		return client, nil
+	}
+	token, err := auth.Login(j.client, owner.Username, owner.Password)
+	if err != nil {
+		return nil, fmt.Errorf("could not log in as %s (%s)", owner.Username, err)
+	}
+	client, err := deis.New(j.client.VerifySSL, j.client.ControllerURL.String(), token)
+	if err != nil {
+		return nil, err
+	}
+	client.HTTPClient = j.client.HTTPClient
+	j.owners[owner.Username] = client
+	return client, nil
+}
+
+func (j *Janitor) listAll() ([]model.Resource, error) {
+	var resources []model.Resource
+
+	userList, count, err := users.List(j.client, listLimit)
+	if err == nil && count > len(userList) {
+		userList, _, err = users.List(j.client, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list users (%s)", err)
+	}
+	for _, u := range userList {
+		if model.UserNameRegexp.MatchString(u.Username) {
+			resources = append(resources, model.UserResource(model.User{Username: u.Username}))
+		}
+	}
+
+	appList, count, err := apps.List(j.client, listLimit)
+	if err == nil && count > len(appList) {
+		appList, _, err = apps.List(j.client, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list apps (%s)", err)
+	}
+	for _, a := range appList {
+		if model.AppNameRegexp.MatchString(a.ID) {
+			owner := model.User{Username: a.Owner}
+			resources = append(resources, model.AppResource(owner, model.App{Name: a.ID}))
+		}
+	}
+
+	certList, count, err := certs.List(j.client, listLimit)
+	if err == nil && count > len(certList) {
+		certList, _, err = certs.List(j.client, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list certs (%s)", err)
+	}
+	for _, c := range certList {
+		if model.CertNameRegexp.MatchString(c.Name) {
+			owner := model.User{Username: c.Owner}
+			resources = append(resources, model.CertResource(owner, model.Cert{Name: c.Name}))
+		}
+	}
+
+	keyList, count, err := keys.List(j.client, listLimit)
+	if err == nil && count > len(keyList) {
+		keyList, _, err = keys.List(j.client, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list keys (%s)", err)
+	}
+	for _, k := range keyList {
+		if model.KeyNameRegexp.MatchString(k.ID) {
+			resources = append(resources, model.KeyResource(model.User{Username: k.Owner}, k.ID))
+		}
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,13 @@
+package janitor
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestJanitor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Janitor")
+}
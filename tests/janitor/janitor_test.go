@@ -0,0 +1,163 @@
+package janitor
+
+import (
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/auth"
+	"github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/controller-sdk-go/keys"
+	"github.com/deis/controller-sdk-go/perms"
+	"github.com/deis/controller-sdk-go/users"
+	"github.com/deis/workflow-e2e/tests/fake"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Janitor", func() {
+
+	var controller *fake.Controller
+	var admin *deis.Client
+	var out *Buffer
+
+	// register registers the specified user with the fake controller and returns a client
+	// authenticated as them.
+	register := func(user model.User) *deis.Client {
+		client, err := deis.New(false, controller.URL, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(auth.Register(client, user.Username, user.Password, user.Email)).To(Succeed())
+		token, err := auth.Login(client, user.Username, user.Password)
+		Expect(err).NotTo(HaveOccurred())
+		client, err = deis.New(false, controller.URL, token)
+		Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+
+	usernames := func() []string {
+		list, _, err := users.List(admin, 100)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, u := range list {
+			names = append(names, u.Username)
+		}
+		return names
+	}
+
+	appNames := func() []string {
+		list, _, err := apps.List(admin, 100)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, a := range list {
+			names = append(names, a.ID)
+		}
+		return names
+	}
+
+	keyNames := func(client *deis.Client) []string {
+		list, _, err := keys.List(client, 100)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, k := range list {
+			names = append(names, k.ID)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		controller = fake.NewController("k8s.local")
+		// The first user to register is an admin.
+		admin = register(model.Admin)
+		out = NewBuffer()
+	})
+
+	AfterEach(func() {
+		controller.Close()
+	})
+
+	Context("with a user who owns an app, a collaborator on it and a key", func() {
+
+		owner := model.User{Username: "test-1", Password: "asdf1234", Email: "test-1@deis.io"}
+		collaborator := model.User{Username: "test-2", Password: "asdf1234", Email: "test-2@deis.io"}
+		app := model.App{Name: "test-10"}
+
+		var ownerClient *deis.Client
+
+		BeforeEach(func() {
+			ownerClient = register(owner)
+			register(collaborator)
+			_, err := apps.New(ownerClient, app.Name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = domains.New(ownerClient, app.Name, "www.foo.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(perms.New(ownerClient, app.Name, collaborator.Username)).To(Succeed())
+			_, err = keys.New(ownerClient, "deiskey-1", "ssh-rsa AAAAB3NzaC1yc2E test-1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes a key as its owner, which only they may do", func() {
+			Expect(keys.Delete(admin, "deiskey-1")).To(Equal(deis.ErrNotFound))
+			errs := New(admin, out).Sweep([]model.Resource{model.KeyResource(owner, "deiskey-1")})
+			Expect(errs).To(BeEmpty())
+			Expect(out).To(Say("Removed key deiskey-1"))
+			Expect(keyNames(ownerClient)).To(BeEmpty())
+		})
+
+		It("deletes resources in dependency order, whatever order they are given in", func() {
+			errs := New(admin, out).Sweep([]model.Resource{
+				model.UserResource(owner),
+				model.AppResource(owner, app),
+				model.KeyResource(owner, "deiskey-1"),
+				model.PermResource(owner, app, collaborator),
+				model.DomainResource(owner, app, "www.foo.com"),
+			})
+			Expect(errs).To(BeEmpty())
+			Expect(out).To(Say("Removed perm test-2"))
+			Expect(out).To(Say("Removed domain www.foo.com"))
+			Expect(out).To(Say("Removed key deiskey-1"))
+			Expect(out).To(Say("Removed app test-10"))
+			Expect(out).To(Say("Removed user test-1"))
+			Expect(usernames()).To(ConsistOf(model.Admin.Username, collaborator.Username))
+			Expect(appNames()).To(BeEmpty())
+		})
+
+		It("ignores resources that no longer exist", func() {
+			Expect(apps.Delete(ownerClient, app.Name)).To(Succeed())
+			errs := New(admin, out).Sweep([]model.Resource{
+				model.DomainResource(owner, app, "www.foo.com"),
+				model.AppResource(owner, app),
+			})
+			Expect(errs).To(BeEmpty())
+			Expect(out.Contents()).To(BeEmpty())
+		})
+
+		It("reports the resources it could not delete and carries on", func() {
+			impostor := model.User{Username: owner.Username, Password: "wrong"}
+			errs := New(admin, out).Sweep([]model.Resource{
+				model.KeyResource(impostor, "deiskey-1"),
+				model.AppResource(owner, app),
+			})
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Error()).To(HavePrefix("could not remove key deiskey-1 (could not log in as test-1"))
+			Expect(out).To(Say("Removed app test-10"))
+		})
+
+		It("sweeps everything named like the tests' resources, and nothing else", func() {
+			bystander := model.User{Username: "bystander", Password: "asdf1234", Email: "bystander@deis.io"}
+			_, err := apps.New(register(bystander), "keep-me")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = keys.New(admin, "deiskey-2", "ssh-rsa AAAAB3NzaC1yc2E admin")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = keys.New(admin, "admin-key", "ssh-rsa AAAAB3NzaC1yc2E admin-2")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(New(admin, out).SweepAll()).To(BeEmpty())
+			Expect(usernames()).To(ConsistOf(model.Admin.Username, bystander.Username))
+			Expect(appNames()).To(ConsistOf("keep-me"))
+			Expect(keyNames(admin)).To(ConsistOf("admin-key"))
+		})
+
+	})
+
+})
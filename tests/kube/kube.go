@@ -0,0 +1,137 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"k8s.io/client-go/1.4/kubernetes"
+	"k8s.io/client-go/1.4/pkg/api"
+	"k8s.io/client-go/1.4/pkg/api/v1"
+	"k8s.io/client-go/1.4/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/1.4/pkg/labels"
+	"k8s.io/client-go/1.4/rest"
+	"k8s.io/client-go/1.4/tools/clientcmd"
+)
+
+// The functions in this file inspect the Kubernetes resources that Workflow creates for apps, so
+// that specs may verify that changes made using the deis CLI actually landed in the cluster.
+// Workflow deploys each app to a namespace of the same name, and each of the app's process types
+// to a Deployment named <app>-<type> whose pods are labeled app=<app> and type=<type>.
+
+// ErrNoPods is returned when an app has no live pods of the requested process type.
+var ErrNoPods = errors.New("no live pods found")
+
+// Cluster inspects the resources belonging to apps. It does not use Gomega, so that it may be
+// tested, and polled using Eventually, without failing the current spec.
+type Cluster struct {
+	client kubernetes.Interface
+}
+
+// New returns a Cluster that inspects resources using the specified client, which may be a fake
+// clientset.
+func New(client kubernetes.Interface) *Cluster {
+	return &Cluster{client: client}
+}
+
+// Connect returns a Cluster that inspects the cluster described by the specified kubeconfig. If
+// there is no such file, as when the tests run in a pod, it inspects the cluster the pod runs in.
+func Connect(kubeconfig string) (*Cluster, error) {
+	config, err := buildConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return New(client), nil
+}
+
+func buildConfig(kubeconfig string) (*rest.Config, error) {
+	if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// Deployment returns the Deployment for the specified app's process type.
+func (c *Cluster) Deployment(app, procType string) (*v1beta1.Deployment, error) {
+	return c.client.Extensions().Deployments(app).Get(deploymentName(app, procType))
+}
+
+// ReplicaSets returns the ReplicaSets belonging to the specified app's process type, one for each
+// of the app's releases that has been rolled out.
+func (c *Cluster) ReplicaSets(app, procType string) ([]v1beta1.ReplicaSet, error) {
+	list, err := c.client.Extensions().ReplicaSets(app).List(listOptions(app, procType))
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// Pods returns the pods belonging to the specified app's process type, newest first. Pods that are
+// being deleted, such as those belonging to a previous release, are omitted.
+func (c *Cluster) Pods(app, procType string) ([]v1.Pod, error) {
+	list, err := c.client.Core().Pods(app).List(listOptions(app, procType))
+	if err != nil {
+		return nil, err
+	}
+	var pods []v1.Pod
+	for _, pod := range list.Items {
+		if pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Sort(newestFirst(pods))
+	return pods, nil
+}
+
+// PodSpec returns the spec of the newest live pod belonging to the specified app's process type.
+// After a change to the app, it describes the new release once the first of its pods has started.
+func (c *Cluster) PodSpec(app, procType string) (v1.PodSpec, error) {
+	pods, err := c.Pods(app, procType)
+	if err != nil {
+		return v1.PodSpec{}, err
+	}
+	if len(pods) == 0 {
+		return v1.PodSpec{}, fmt.Errorf("%s (app %s, type %s)", ErrNoPods, app, procType)
+	}
+	return pods[0].Spec, nil
+}
+
+// NodeLabels returns the labels of every node in the cluster.
+func (c *Cluster) NodeLabels() ([]map[string]string, error) {
+	list, err := c.client.Core().Nodes().List(api.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var labels []map[string]string
+	for _, node := range list.Items {
+		labels = append(labels, node.Labels)
+	}
+	return labels, nil
+}
+
+func deploymentName(app, procType string) string {
+	return fmt.Sprintf("%s-%s", app, procType)
+}
+
+func listOptions(app, procType string) api.ListOptions {
+	return api.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{"app": app, "type": procType})}
+}
+
+// newestFirst sorts pods by creation time, newest first. Pods created in the same second are
+// sorted by name, so that the order is stable.
+type newestFirst []v1.Pod
+
+func (p newestFirst) Len() int      { return len(p) }
+func (p newestFirst) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p newestFirst) Less(i, j int) bool {
+	ti, tj := p[i].CreationTimestamp, p[j].CreationTimestamp
+	if !ti.Equal(tj) {
+		return tj.Before(ti)
+	}
+	return p[i].Name < p[j].Name
+}
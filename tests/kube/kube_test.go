@@ -0,0 +1,177 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/1.4/kubernetes/fake"
+	"k8s.io/client-go/1.4/pkg/api/resource"
+	"k8s.io/client-go/1.4/pkg/api/unversioned"
+	"k8s.io/client-go/1.4/pkg/api/v1"
+	"k8s.io/client-go/1.4/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/1.4/pkg/util/intstr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("kube", func() {
+
+	const app = "test-app"
+
+	var epoch = time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// pod returns a pod belonging to the test app's cmd process type, created the specified number
+	// of seconds after epoch, whose container has the specified env.
+	pod := func(name string, created int, env ...v1.EnvVar) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:              name,
+				Namespace:         app,
+				Labels:            map[string]string{"app": app, "type": "cmd", "heritage": "deis"},
+				CreationTimestamp: unversioned.NewTime(epoch.Add(time.Duration(created) * time.Second)),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: app + "-cmd", Env: env}},
+			},
+		}
+	}
+
+	Context("with an app that has been deployed", func() {
+
+		var cluster *Cluster
+
+		BeforeEach(func() {
+			terminating := pod("test-app-cmd-v3-old", 30, v1.EnvVar{Name: "VERSION", Value: "v3"})
+			deleted := unversioned.NewTime(epoch.Add(time.Minute))
+			terminating.DeletionTimestamp = &deleted
+			web := pod("test-app-web-v1", 40)
+			web.Labels["type"] = "web"
+
+			cluster = New(fake.NewSimpleClientset(
+				&v1beta1.Deployment{ObjectMeta: v1.ObjectMeta{Name: "test-app-cmd", Namespace: app}},
+				&v1beta1.ReplicaSet{ObjectMeta: v1.ObjectMeta{
+					Name: "test-app-cmd-v2", Namespace: app, Labels: map[string]string{"app": app, "type": "cmd"},
+				}},
+				pod("test-app-cmd-v1", 10, v1.EnvVar{Name: "VERSION", Value: "v1"}),
+				pod("test-app-cmd-v2", 20, v1.EnvVar{Name: "VERSION", Value: "v2"}),
+				terminating,
+				web,
+				&v1.Node{ObjectMeta: v1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "worker1"}}},
+			))
+		})
+
+		It("finds that app's deployment by process type", func() {
+			deployment, err := cluster.Deployment(app, "cmd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployment.Name).To(Equal("test-app-cmd"))
+
+			_, err = cluster.Deployment(app, "web")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("finds that app's replica sets by label", func() {
+			replicaSets, err := cluster.ReplicaSets(app, "cmd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replicaSets).To(HaveLen(1))
+			Expect(replicaSets[0].Name).To(Equal("test-app-cmd-v2"))
+		})
+
+		It("lists that app's live pods, newest first", func() {
+			pods, err := cluster.Pods(app, "cmd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pods).To(HaveLen(2))
+			Expect(pods[0].Name).To(Equal("test-app-cmd-v2"))
+			Expect(pods[1].Name).To(Equal("test-app-cmd-v1"))
+		})
+
+		It("returns the spec of that app's newest live pod", func() {
+			spec, err := cluster.PodSpec(app, "cmd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Env(spec)).To(HaveKeyWithValue("VERSION", "v2"))
+		})
+
+		It("fails to return a pod spec for a process type with no pods", func() {
+			_, err := cluster.PodSpec(app, "worker")
+			Expect(err).To(MatchError(ContainSubstring(ErrNoPods.Error())))
+		})
+
+		It("lists the labels of every node", func() {
+			labels, err := cluster.NodeLabels()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(ConsistOf(HaveKeyWithValue("node", "worker1")))
+		})
+
+	})
+
+	Context("with a pod spec", func() {
+
+		var spec v1.PodSpec
+
+		BeforeEach(func() {
+			spec = v1.PodSpec{
+				NodeSelector: map[string]string{"node": "worker1"},
+				Containers: []v1.Container{{
+					Env: []v1.EnvVar{{Name: "POWERED_BY", Value: "midi-chlorians"}},
+					Resources: v1.ResourceRequirements{Limits: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("500m"),
+						v1.ResourceMemory: resource.MustParse("64M"),
+					}},
+					LivenessProbe: &v1.Probe{Handler: v1.Handler{
+						HTTPGet: &v1.HTTPGetAction{Path: "/", Port: intstr.FromInt(1500)},
+					}},
+					ReadinessProbe: &v1.Probe{Handler: v1.Handler{
+						Exec: &v1.ExecAction{Command: []string{"/bin/true"}},
+					}},
+				}},
+			}
+		})
+
+		It("extracts its env, limits and node selector", func() {
+			Expect(Env(spec)).To(Equal(map[string]string{"POWERED_BY": "midi-chlorians"}))
+			Expect(Limits(spec)).To(Equal(map[string]string{"cpu": "500m", "memory": "64M"}))
+			Expect(NodeSelector(spec)).To(Equal(map[string]string{"node": "worker1"}))
+		})
+
+		It("describes its probes", func() {
+			Expect(LivenessProbe(spec)).To(Equal("httpGet / 1500"))
+			Expect(ReadinessProbe(spec)).To(Equal("exec /bin/true"))
+			Expect(DescribeProbe(&v1.Probe{Handler: v1.Handler{
+				TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(1500)},
+			}})).To(Equal("tcpSocket 1500"))
+		})
+
+		It("handles a pod spec with no containers", func() {
+			empty := v1.PodSpec{}
+			Expect(Env(empty)).To(BeEmpty())
+			Expect(Limits(empty)).To(BeEmpty())
+			Expect(NodeSelector(empty)).To(BeEmpty())
+			Expect(LivenessProbe(empty)).To(BeEmpty())
+		})
+
+	})
+
+	Context("without a kubeconfig", func() {
+
+		var host string
+
+		BeforeEach(func() {
+			host = os.Getenv("KUBERNETES_SERVICE_HOST")
+			os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		})
+
+		AfterEach(func() {
+			if host != "" {
+				os.Setenv("KUBERNETES_SERVICE_HOST", host)
+			}
+		})
+
+		It("connects to the cluster it runs in", func() {
+			_, err := Connect(filepath.Join(os.TempDir(), "no-such-kubeconfig"))
+			Expect(err).To(MatchError(ContainSubstring("unable to load in-cluster configuration")))
+		})
+
+	})
+
+})
@@ -0,0 +1,74 @@
+package kube
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/1.4/pkg/api/v1"
+)
+
+// The functions in this file extract the parts of a pod spec that Workflow configures on behalf of
+// users, in forms that are easy to match using Gomega.
+
+// AppContainer returns the container that runs the app in the specified pod spec.
+func AppContainer(spec v1.PodSpec) v1.Container {
+	if len(spec.Containers) == 0 {
+		return v1.Container{}
+	}
+	return spec.Containers[0]
+}
+
+// Env returns the environment set on the app's container, as set by `deis config:set`.
+func Env(spec v1.PodSpec) map[string]string {
+	env := map[string]string{}
+	for _, v := range AppContainer(spec).Env {
+		env[v.Name] = v.Value
+	}
+	return env
+}
+
+// Limits returns the resource limits set on the app's container, as set by `deis limits:set`,
+// keyed by resource name ("cpu" or "memory").
+func Limits(spec v1.PodSpec) map[string]string {
+	limits := map[string]string{}
+	for name, quantity := range AppContainer(spec).Resources.Limits {
+		limits[string(name)] = quantity.String()
+	}
+	return limits
+}
+
+// NodeSelector returns the node selector of the specified pod spec, as set by `deis tags:set`.
+func NodeSelector(spec v1.PodSpec) map[string]string {
+	if spec.NodeSelector == nil {
+		return map[string]string{}
+	}
+	return spec.NodeSelector
+}
+
+// LivenessProbe returns a description of the app container's liveness probe (see DescribeProbe).
+func LivenessProbe(spec v1.PodSpec) string {
+	return DescribeProbe(AppContainer(spec).LivenessProbe)
+}
+
+// ReadinessProbe returns a description of the app container's readiness probe (see
+// DescribeProbe).
+func ReadinessProbe(spec v1.PodSpec) string {
+	return DescribeProbe(AppContainer(spec).ReadinessProbe)
+}
+
+// DescribeProbe describes a probe in terms of the arguments to `deis healthchecks:set` that would
+// have configured it, such as "exec /bin/true", "httpGet / 1500" or "tcpSocket 1500". It returns
+// the empty string if there is no probe.
+func DescribeProbe(probe *v1.Probe) string {
+	switch {
+	case probe == nil:
+		return ""
+	case probe.Exec != nil:
+		return "exec " + strings.Join(probe.Exec.Command, " ")
+	case probe.HTTPGet != nil:
+		return fmt.Sprintf("httpGet %s %s", probe.HTTPGet.Path, probe.HTTPGet.Port.String())
+	case probe.TCPSocket != nil:
+		return fmt.Sprintf("tcpSocket %s", probe.TCPSocket.Port.String())
+	}
+	return "unknown"
+}
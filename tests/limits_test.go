@@ -7,7 +7,9 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/kube"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/ginkgo"
@@ -16,7 +18,6 @@ import (
 	. "github.com/onsi/gomega/gexec"
 )
 
-// TODO (bacongobbler): inspect kubectl for limits being applied to manifest
 var _ = Describe("deis limits", func() {
 
 	Context("with an existing user", func() {
@@ -47,30 +48,42 @@ var _ = Describe("deis limits", func() {
 			Specify("that user can list that app's limits", func() {
 				sess, err := cmd.Start("deis limits:list -a %s", &user, app.Name)
 				Eventually(sess).Should(Say(fmt.Sprintf("=== %s Limits", app.Name)))
-				Eventually(sess).Should(Say("--- Memory\nUnlimited"))
-				Eventually(sess).Should(Say("--- CPU\nUnlimited"))
-				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+				Expect(listedLimits(sess).Memory).To(BeEmpty())
+				Expect(listedLimits(sess).CPU).To(BeEmpty())
+				Expect(err).NotTo(HaveOccurred())
 			})
 
 			Specify("that user can set a memory limit on that application", func() {
 				sess, err := cmd.Start("deis limits:set cmd=64M -a %s", &user, app.Name)
-				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("--- Memory\ncmd     64M"))
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(listedLimits(sess).Memory).To(HaveKeyWithValue("cmd", "64M"))
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(0))
 
 				// Check that --memory also works
 				sess, err = cmd.Start("deis limits:set --memory cmd=128M -a %s", &user, app.Name)
-				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("--- Memory\ncmd     128M"))
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(listedLimits(sess).Memory).To(HaveKeyWithValue("cmd", "128M"))
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(0))
+
+				// Check that the limit was applied to the app's pods
+				Eventually(func() (map[string]string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.Limits(spec), err
+				}, settings.MaxEventuallyTimeout).Should(HaveKeyWithValue("memory", MatchRegexp(`^128Mi?$`)))
 			})
 
 			Specify("that user can set a CPU limit on that application", func() {
 				sess, err := cmd.Start("deis limits:set --cpu cmd=500m -a %s", &user, app.Name)
-				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("--- CPU\ncmd     500m"))
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(listedLimits(sess).CPU).To(HaveKeyWithValue("cmd", "500m"))
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(0))
+
+				// Check that the limit was applied to the app's pods
+				Eventually(func() (map[string]string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.Limits(spec), err
+				}, settings.MaxEventuallyTimeout).Should(HaveKeyWithValue("cpu", "500m"))
 			})
 
 			Specify("that user can unset a memory limit on that application", func() {
@@ -81,13 +94,19 @@ var _ = Describe("deis limits", func() {
 
 				// Check that --memory also works
 				sess, err = cmd.Start("deis limits:set --memory cmd=64M -a %s", &user, app.Name)
-				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("--- Memory\ncmd     64M"))
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(listedLimits(sess).Memory).To(HaveKeyWithValue("cmd", "64M"))
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(0))
 				sess, err = cmd.Start("deis limits:unset --memory cmd -a %s", &user, app.Name)
-				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("--- Memory\nUnlimited"))
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(listedLimits(sess).Memory).To(BeEmpty())
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(sess).Should(Exit(0))
+
+				// Check that the limit was removed from the app's pods
+				Eventually(func() (map[string]string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.Limits(spec), err
+				}, settings.MaxEventuallyTimeout).ShouldNot(HaveKey("memory"))
 			})
 
 			Specify("that user can unset a CPU limit on that application", func() {
@@ -102,3 +121,10 @@ var _ = Describe("deis limits", func() {
 	})
 
 })
+
+// listedLimits returns the limits printed by a deis limits command that has exited.
+func listedLimits(sess *Session) parse.Limits {
+	limits, err := parse.LimitsList(sess.Out.Contents())
+	Expect(err).NotTo(HaveOccurred())
+	return limits
+}
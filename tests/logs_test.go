@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"os"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/workflow-e2e/tests/cmd/apps"
+	"github.com/deis/workflow-e2e/tests/cmd/auth"
+	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/cmd/git"
+	"github.com/deis/workflow-e2e/tests/cmd/keys"
+	"github.com/deis/workflow-e2e/tests/cmd/logs"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	"github.com/deis/workflow-e2e/tests/fixtures"
+	"github.com/deis/workflow-e2e/tests/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deis logs", func() {
+
+	Context("with an existing user", func() {
+
+		var user model.User
+
+		BeforeEach(func() {
+			user = auth.Register()
+		})
+
+		AfterEach(func() {
+			auth.Cancel(user)
+		})
+
+		Context("who owns an existing app that has already been deployed with `deis pull`", func() {
+
+			var app model.App
+
+			BeforeEach(func() {
+				app = apps.Create(user, "--no-remote")
+				builds.Pull(user, app)
+			})
+
+			AfterEach(func() {
+				if app.Name != "" {
+					apps.Destroy(user, app)
+				}
+			})
+
+			Specify("that user can follow the output of a command run in that app to its logs", func() {
+				marker := logs.EmitByRun(user, app)
+				received := logs.ExpectEventually(user, app, marker, 10)
+				Expect(received.Line.Source).To(Equal(app.Name))
+				Expect(logs.Get(user, app, 0)).To(ContainElement(received.Line))
+			})
+
+			Specify("that user can no longer retrieve that app's logs once it has been destroyed", func() {
+				logs.ExpectEventually(user, app, logs.EmitByRun(user, app), 0)
+				apps.Destroy(user, app)
+				logs.GetExpectingError(user, app.Name, deis.ErrNotFound)
+				app = model.App{}
+			})
+
+		})
+
+		Context("who owns an existing app with several web processes that has already been deployed", func() {
+
+			var app model.App
+
+			BeforeEach(func() {
+				_, keyPath := keys.Add(user)
+				f := fixtures.Go
+				Expect(f.Manifest.LogsRequests).To(BeTrue())
+				os.Chdir(writeFixture(f))
+				app = apps.Create(user)
+				git.Push(user, keyPath, app, f.Manifest.Response)
+				ps.Scale(user, app, "web", 2)
+			})
+
+			AfterEach(func() {
+				apps.Destroy(user, app)
+			})
+
+			Specify("that user can follow requests served by that app to its logs, by the process that served them", func() {
+				web := ps.Names(ps.List(user, app, "web"))
+				for i := 0; i < 3; i++ {
+					received := logs.ExpectEventually(user, app, logs.EmitByRequest(app), 100)
+					Expect(web).To(ContainElement(received.Line.Process))
+				}
+			})
+
+		})
+
+	})
+
+})
@@ -1,13 +1,11 @@
 package tests
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
 
@@ -52,10 +50,8 @@ var _ = Describe("deis maintenance", func() {
 			})
 
 			Specify("can view app when maintenance mode is off", func() {
-				// curl the app's root URL and print just the HTTP response code
-				cmdRetryTimeout := 60
-				curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-				Eventually(cmd.Retry(curlCmd, strconv.Itoa(200), cmdRetryTimeout)).Should(BeTrue())
+				// request the app's root URL and check just the HTTP response code
+				probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: 200})
 			})
 
 			Specify("can enable/disable maintenance", func() {
@@ -69,10 +65,8 @@ var _ = Describe("deis maintenance", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
-				// curl the app's root URL and print just the HTTP response code
-				cmdRetryTimeout := 60
-				curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-				Eventually(cmd.Retry(curlCmd, strconv.Itoa(503), cmdRetryTimeout)).Should(BeTrue())
+				// request the app's root URL and check just the HTTP response code
+				probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: 503})
 
 				sess, err = cmd.Start("deis maintenance:off --app=%s", &user, app.Name)
 				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("done"))
@@ -84,9 +78,7 @@ var _ = Describe("deis maintenance", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
-				cmdRetryTimeout = 60
-				curlCmd = model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-				Eventually(cmd.Retry(curlCmd, strconv.Itoa(200), cmdRetryTimeout)).Should(BeTrue())
+				probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: 200})
 			})
 		})
 	})
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/pki"
+	"github.com/deis/workflow-e2e/tests/settings"
+)
+
+// The functions in this file issue the certificates that specs upload to the controller. Each is
+// issued at runtime by a certificate authority created once per process, so no certificate ever
+// needs to be checked in or renewed.
+
+// DefaultCertCommonName is the common name of the certificates returned by NewCert.
+const DefaultCertCommonName = "www.foo.com"
+
+// Cert is a certificate, and its private key, written to files from which they can be uploaded.
+type Cert struct {
+	Name     string
+	CertPath string
+	KeyPath  string
+	// Leaf is the certificate itself.
+	Leaf *x509.Certificate
+	// Chain are the certificates of the intermediate authorities that issued Leaf, in order, which
+	// follow it in the file at CertPath (unless CertOptions.ReverseChain was set).
+	Chain []*x509.Certificate
+}
+
+// CertOptions describes a certificate to issue with NewCertWith. The zero value describes a
+// certificate for no names, valid from an hour ago for pki.DefaultValidity, with an RSA key,
+// issued directly by CertAuthority().
+type CertOptions struct {
+	CommonName string
+	// DNSNames are the certificate's subject alternative names. If none are set, the common name
+	// is used.
+	DNSNames []string
+	// NotBefore and NotAfter bound the certificate's validity. Either may lie in the past or the
+	// future, to issue certificates that have expired or are not yet valid.
+	NotBefore time.Time
+	NotAfter  time.Time
+	KeyType   pki.KeyType
+	// Intermediates is the number of intermediate authorities between CertAuthority() and the
+	// certificate.
+	Intermediates int
+	// ReverseChain writes the chain to the certificate file before the certificate, instead of
+	// after it.
+	ReverseChain bool
+}
+
+var (
+	authority     *pki.Authority
+	authorityOnce sync.Once
+)
+
+// CertAuthority returns the root certificate authority that issues every Cert. It is created on
+// first use and lives as long as this process.
+func CertAuthority() *pki.Authority {
+	authorityOnce.Do(func() {
+		var err error
+		if authority, err = pki.NewAuthority("Deis Workflow E2E Root"); err != nil {
+			panic(err)
+		}
+	})
+	return authority
+}
+
+// NewCert returns a new, valid certificate for DefaultCertCommonName.
+func NewCert() Cert {
+	return NewCertWith(CertOptions{CommonName: DefaultCertCommonName})
+}
+
+// NewCertWith returns a new certificate issued as described by opts.
+func NewCertWith(opts CertOptions) Cert {
+	issuer := CertAuthority()
+	for i := 1; i <= opts.Intermediates; i++ {
+		var err error
+		if issuer, err = issuer.Intermediate(fmt.Sprintf("Deis Workflow E2E Intermediate %d", i)); err != nil {
+			panic(err)
+		}
+	}
+	dnsNames := opts.DNSNames
+	if len(dnsNames) == 0 && opts.CommonName != "" {
+		dnsNames = []string{opts.CommonName}
+	}
+	leaf, err := issuer.Issue(pki.Request{
+		CommonName: opts.CommonName,
+		DNSNames:   dnsNames,
+		NotBefore:  opts.NotBefore,
+		NotAfter:   opts.NotAfter,
+		KeyType:    opts.KeyType,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	certPEM := leaf.CertPEM()
+	if opts.ReverseChain {
+		certs := []*x509.Certificate{}
+		for i := len(leaf.Chain) - 1; i >= 0; i-- {
+			certs = append(certs, leaf.Chain[i])
+		}
+		certPEM = pki.EncodeCerts(append(certs, leaf.Cert)...)
+	}
+	keyPEM, err := leaf.KeyPEM()
+	if err != nil {
+		panic(err)
+	}
+
+	cert := Cert{Name: getRandCertName(), Leaf: leaf.Cert, Chain: leaf.Chain}
+	dir := certDir()
+	cert.CertPath = path.Join(dir, cert.Name+".cert")
+	cert.KeyPath = path.Join(dir, cert.Name+".key")
+	if err := ioutil.WriteFile(cert.CertPath, certPEM, 0644); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(cert.KeyPath, keyPEM, 0600); err != nil {
+		panic(err)
+	}
+	return cert
+}
+
+// certDir returns the directory to which certificates are written, creating it if necessary.
+func certDir() string {
+	parent := settings.TestHome
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	dir := path.Join(parent, "certs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+func getRandCertName() string {
+	return fmt.Sprintf("%d-cert", rand.Intn(999999999))
+}
@@ -3,13 +3,21 @@ package model
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math/rand"
-	"path"
-	"runtime"
+	"regexp"
 	"strings"
 
 	"github.com/deis/workflow-e2e/tests/settings"
-	"github.com/deis/workflow-e2e/tests/util"
+)
+
+// These match the names given to users, apps, certs and keys created by the tests, so that any
+// left behind by a previous run can be recognized as such.
+var (
+	UserNameRegexp = regexp.MustCompile(`^test-\d+$`)
+	AppNameRegexp  = regexp.MustCompile(`^test-\d+$`)
+	CertNameRegexp = regexp.MustCompile(`^\d+-cert$`)
+	KeyNameRegexp  = regexp.MustCompile(`^deiskey-\d+$`)
 )
 
 var Admin = User{
@@ -24,6 +32,9 @@ type User struct {
 	Password    string
 	Email       string
 	IsSuperuser bool
+	// Home is the user's private home directory, if any, in which their profile, SSH keys and git
+	// configuration are kept (see cmd.CreateHome).
+	Home string
 }
 
 func NewUser() User {
@@ -43,47 +54,75 @@ type App struct {
 
 func NewApp() App {
 	name := fmt.Sprintf("test-%d", rand.Intn(999999999))
-	app := App{
-		Name: name,
-		URL:  strings.Replace(settings.DeisControllerURL, "deis", name, 1),
+	if settings.UseFakeController {
+		// The fake controller has no router in front of it, so its URL cannot be used as a template.
+		return App{Name: name, URL: fmt.Sprintf("http://%s.%s", name, settings.DeisRootHostname)}
 	}
-	// try adding the URL to /etc/hosts but don't cry if it's not in there because the user may
-	// have other plans in store for DNS
-	if err := util.AddToEtcHosts(fmt.Sprintf("%s.%s", name, settings.DeisRootHostname)); err != nil {
-		fmt.Printf("WARNING: could not write %s to /etc/hosts (%s), continuing anyways\n",
-			app.URL,
-			err)
+	return App{
+		Name: name,
+		URL:  strings.Replace(settings.RoutedControllerURL, "deis", name, 1),
 	}
-	return app
 }
 
+// BuilderSSHPort is the port on which the builder accepts git pushes over SSH.
+const BuilderSSHPort = 2222
+
+// BuilderHost returns the hostname of the builder, which is that of the controller with "-builder"
+// appended to its first label, as the CLI derives it for git remotes.
+func BuilderHost() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(settings.RoutedControllerURL, "http://"), "https://")
+	host = strings.Split(strings.Split(host, "/")[0], ":")[0]
+	labels := strings.Split(host, ".")
+	labels[0] = labels[0] + "-builder"
+	return strings.Join(labels, ".")
+}
+
+// Cmd describes a command to execute. Unless Path is set, CommandLineString is interpreted by
+// /bin/sh. Otherwise, the binary at Path is executed directly with Args, so that each argument
+// reaches it byte for byte, however many quotes, spaces or dollar signs it contains.
 type Cmd struct {
+	// Env is the environment of the command, or nil to inherit that of this process.
 	Env               []string
 	CommandLineString string
+	// Path is the name of the binary to execute, which is looked up in $PATH if it contains no
+	// slashes.
+	Path string
+	Args []string
+	// EnvOverrides are "key=value" strings that take precedence over Env.
+	EnvOverrides []string
+	// Dir is the working directory of the command, or "" for that of this process.
+	Dir string
+	// Stdin is written to the command's standard input.
+	Stdin []byte
+	// Stderr, if set, is also written everything the command writes to its standard error, as it
+	// is written, when the command is started with cmd.StartCmd.
+	Stderr io.Writer
 }
 
-type Cert struct {
-	Name     string
-	CertPath string
-	KeyPath  string
-}
-
-func NewCert() Cert {
-	certPath := path.Join(getDir(), "..", "files", "certs")
-	return Cert{
-		Name:     getRandCertName(),
-		CertPath: fmt.Sprintf("%s/www.foo.com.cert", certPath),
-		KeyPath:  fmt.Sprintf("%s/www.foo.com.key", certPath),
+// String returns the command as it would be typed into a shell.
+func (c Cmd) String() string {
+	if c.Path == "" {
+		return c.CommandLineString
 	}
+	words := []string{shellQuote(c.Path)}
+	for _, arg := range c.Args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
 }
 
-func getRandCertName() string {
-	return fmt.Sprintf("%d-cert", rand.Intn(999999999))
+var shellSafeRegexp = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// shellQuote quotes s, if necessary, so that a shell would read it as a single word.
+func shellQuote(s string) string {
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
 }
 
-func getDir() string {
-	_, filename, _, _ := runtime.Caller(1)
-	return path.Dir(filename)
+func NewKeyName() string {
+	return fmt.Sprintf("deiskey-%v", rand.Intn(999999999))
 }
 
 // CmdResult represents a generic command result, with expected Out, Err and
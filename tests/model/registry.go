@@ -0,0 +1,147 @@
+package model
+
+import (
+	"sort"
+	"sync"
+)
+
+// ResourceKind identifies the type of a Resource. Kinds are declared in the order in which
+// resources must be torn down, such that nothing is deleted before the things that depend on it.
+type ResourceKind int
+
+const (
+	PermKind ResourceKind = iota
+	DomainKind
+	CertKind
+	KeyKind
+	AppKind
+	UserKind
+)
+
+var resourceKindNames = map[ResourceKind]string{
+	PermKind:   "perm",
+	DomainKind: "domain",
+	CertKind:   "cert",
+	KeyKind:    "key",
+	AppKind:    "app",
+	UserKind:   "user",
+}
+
+func (k ResourceKind) String() string {
+	return resourceKindNames[k]
+}
+
+// Resource is something created on the controller by a test user. Name is the username, app
+// name, cert name, key name or domain name; for perms it is the username of the collaborator. App
+// is only set for domains and perms.
+type Resource struct {
+	Kind  ResourceKind
+	Owner User
+	App   string
+	Name  string
+}
+
+func UserResource(user User) Resource {
+	return Resource{Kind: UserKind, Owner: user, Name: user.Username}
+}
+
+func AppResource(owner User, app App) Resource {
+	return Resource{Kind: AppKind, Owner: owner, Name: app.Name}
+}
+
+func CertResource(owner User, cert Cert) Resource {
+	return Resource{Kind: CertKind, Owner: owner, Name: cert.Name}
+}
+
+func KeyResource(owner User, keyName string) Resource {
+	return Resource{Kind: KeyKind, Owner: owner, Name: keyName}
+}
+
+func DomainResource(owner User, app App, domain string) Resource {
+	return Resource{Kind: DomainKind, Owner: owner, App: app.Name, Name: domain}
+}
+
+func PermResource(owner User, app App, grantUser User) Resource {
+	return Resource{Kind: PermKind, Owner: owner, App: app.Name, Name: grantUser.Username}
+}
+
+// same returns true if r and other identify the same thing on the controller, regardless of who
+// created or deleted it.
+func (r Resource) same(other Resource) bool {
+	return r.Kind == other.Kind && r.App == other.App && r.Name == other.Name
+}
+
+// dependsOn returns true if r is implicitly deleted by the controller when other is deleted.
+func (r Resource) dependsOn(other Resource) bool {
+	switch other.Kind {
+	case AppKind:
+		return r.App == other.Name
+	case UserKind:
+		return r.Owner.Username == other.Name || (r.Kind == PermKind && r.Name == other.Name)
+	}
+	return false
+}
+
+// Registry keeps track of the resources that exist on the controller because the tests/cmd
+// helpers created them and have not yet deleted them. Anything still in the registry after all
+// specs have run was leaked by a spec that failed before it could clean up after itself.
+type Registry struct {
+	mu        sync.Mutex
+	resources []Resource
+}
+
+// Created is the registry that the tests/cmd helpers record to.
+var Created = &Registry{}
+
+// Track records that the specified resource was created. The tests/cmd helpers call it before
+// they create a resource, so that one whose creation fails part way is still torn down; tearing
+// down something that was never created is harmless.
+func (reg *Registry) Track(r Resource) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for _, existing := range reg.resources {
+		if existing.same(r) {
+			return
+		}
+	}
+	reg.resources = append(reg.resources, r)
+}
+
+// Forget records that the specified resource was deleted, along with anything the controller
+// deletes along with it.
+func (reg *Registry) Forget(r Resource) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	var remaining []Resource
+	for _, existing := range reg.resources {
+		if !existing.same(r) && !existing.dependsOn(r) {
+			remaining = append(remaining, existing)
+		}
+	}
+	reg.resources = remaining
+}
+
+// Leftovers returns every resource that has been created but not deleted, in the order in which
+// they should be torn down.
+func (reg *Registry) Leftovers() []Resource {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	leftovers := make([]Resource, len(reg.resources))
+	// Within each kind, tear down the most recently created resources first.
+	for i, r := range reg.resources {
+		leftovers[len(leftovers)-1-i] = r
+	}
+	SortForTeardown(leftovers)
+	return leftovers
+}
+
+// SortForTeardown sorts the specified resources into the order in which they should be deleted.
+func SortForTeardown(resources []Resource) {
+	sort.Stable(byKind(resources))
+}
+
+type byKind []Resource
+
+func (s byKind) Len() int           { return len(s) }
+func (s byKind) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byKind) Less(i, j int) bool { return s[i].Kind < s[j].Kind }
@@ -0,0 +1,79 @@
+package model
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+
+	owner := User{Username: "test-1"}
+	collaborator := User{Username: "test-2"}
+	app := App{Name: "test-10"}
+	otherApp := App{Name: "test-11"}
+
+	var reg *Registry
+
+	BeforeEach(func() {
+		reg = &Registry{}
+	})
+
+	It("tracks each resource once, however many times it is created", func() {
+		reg.Track(AppResource(owner, app))
+		reg.Track(AppResource(collaborator, app))
+		Expect(reg.Leftovers()).To(Equal([]Resource{AppResource(owner, app)}))
+	})
+
+	It("forgets a resource however it is identified, whoever deletes it", func() {
+		reg.Track(AppResource(owner, app))
+		reg.Track(AppResource(owner, otherApp))
+		reg.Forget(AppResource(Admin, app))
+		Expect(reg.Leftovers()).To(Equal([]Resource{AppResource(owner, otherApp)}))
+	})
+
+	It("forgets the domains and perms of an app along with it", func() {
+		reg.Track(AppResource(owner, app))
+		reg.Track(DomainResource(owner, app, "www.foo.com"))
+		reg.Track(PermResource(owner, app, collaborator))
+		reg.Track(AppResource(owner, otherApp))
+		reg.Track(DomainResource(owner, otherApp, "www.bar.com"))
+		reg.Forget(AppResource(owner, app))
+		Expect(reg.Leftovers()).To(Equal([]Resource{
+			DomainResource(owner, otherApp, "www.bar.com"),
+			AppResource(owner, otherApp),
+		}))
+	})
+
+	It("forgets what a user owns, and the perms granted to them, along with them", func() {
+		reg.Track(UserResource(owner))
+		reg.Track(UserResource(collaborator))
+		reg.Track(KeyResource(collaborator, "deiskey-1"))
+		reg.Track(CertResource(collaborator, Cert{Name: "1-cert"}))
+		reg.Track(AppResource(owner, app))
+		reg.Track(PermResource(owner, app, collaborator))
+		reg.Forget(UserResource(collaborator))
+		Expect(reg.Leftovers()).To(Equal([]Resource{AppResource(owner, app), UserResource(owner)}))
+	})
+
+	It("returns leftovers in teardown order, the most recently created of each kind first", func() {
+		reg.Track(UserResource(owner))
+		reg.Track(AppResource(owner, app))
+		reg.Track(PermResource(owner, app, collaborator))
+		reg.Track(KeyResource(owner, "deiskey-1"))
+		reg.Track(AppResource(owner, otherApp))
+		reg.Track(DomainResource(owner, app, "www.foo.com"))
+		reg.Track(CertResource(owner, Cert{Name: "1-cert"}))
+		reg.Track(UserResource(collaborator))
+		Expect(reg.Leftovers()).To(Equal([]Resource{
+			PermResource(owner, app, collaborator),
+			DomainResource(owner, app, "www.foo.com"),
+			CertResource(owner, Cert{Name: "1-cert"}),
+			KeyResource(owner, "deiskey-1"),
+			AppResource(owner, otherApp),
+			AppResource(owner, app),
+			UserResource(collaborator),
+			UserResource(owner),
+		}))
+	})
+
+})
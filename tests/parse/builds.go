@@ -0,0 +1,281 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The types and functions in this file follow the output of the builder during a `git push`,
+// which the push writes to stderr, to tell which phase of the build it is in and how long each
+// phase took.
+
+// Phase is a phase of a build, as the builder's output reveals it.
+type Phase string
+
+const (
+	// PhaseReceive is git sending the push to the builder.
+	PhaseReceive Phase = "receive"
+	// PhaseBuild is the builder preparing a slug build or running a docker build.
+	PhaseBuild Phase = "build"
+	// PhaseDetect is the buildpacks being tried against the app, or a custom one being fetched.
+	PhaseDetect Phase = "detect"
+	// PhaseCompile is the detected buildpack compiling the app.
+	PhaseCompile Phase = "compile"
+	// PhasePush is the slug or image being pushed to storage or the registry.
+	PhasePush Phase = "push"
+	// PhaseRelease is the controller creating a release of the build.
+	PhaseRelease Phase = "release"
+	// PhaseLaunch is the release being rolled out.
+	PhaseLaunch Phase = "launch"
+)
+
+// BuildKind is the kind of build the builder performed.
+type BuildKind string
+
+const (
+	BuildpackBuild  BuildKind = "buildpack"
+	DockerfileBuild BuildKind = "dockerfile"
+)
+
+// PhaseTiming is when a phase began, and how long it lasted.
+type PhaseTiming struct {
+	Phase    Phase         `json:"phase"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BuildReport describes a build, as followed by a BuildLog.
+type BuildReport struct {
+	Kind BuildKind `json:"kind,omitempty"`
+	// Buildpack is the language the buildpack detected, such as "Go", or the URL of a custom
+	// buildpack if it detected none.
+	Buildpack string `json:"buildpack,omitempty"`
+	// ImageDigest is the digest of the image pushed to the registry, if it was reported.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Phases are the phases the build went through, in order.
+	Phases []PhaseTiming `json:"phases"`
+	// App and Version identify the release that deployed the build, if it succeeded.
+	App     string `json:"app,omitempty"`
+	Version int    `json:"version,omitempty"`
+	// Failure is the line of output that explains why the build failed, if it did and one does.
+	Failure   string `json:"failure,omitempty"`
+	Succeeded bool   `json:"succeeded"`
+}
+
+// Duration returns how long the specified phase lasted, or 0 if the build never reached it.
+func (r BuildReport) Duration(phase Phase) time.Duration {
+	for _, p := range r.Phases {
+		if p.Phase == phase {
+			return p.Duration
+		}
+	}
+	return 0
+}
+
+// Total returns how long the build lasted, from the first phase to the end of the last.
+func (r BuildReport) Total() time.Duration {
+	var total time.Duration
+	for _, p := range r.Phases {
+		total += p.Duration
+	}
+	return total
+}
+
+// Reached returns whether the build reached the specified phase.
+func (r BuildReport) Reached(phase Phase) bool {
+	for _, p := range r.Phases {
+		if p.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the report in printable form, with the duration of each phase.
+func (r BuildReport) String() string {
+	var phases []string
+	for _, p := range r.Phases {
+		phases = append(phases, fmt.Sprintf("%s %s", p.Phase, p.Duration))
+	}
+	outcome := "succeeded"
+	if !r.Succeeded {
+		outcome = "failed"
+		if r.Failure != "" {
+			outcome += fmt.Sprintf(" (%s)", r.Failure)
+		}
+	}
+	return fmt.Sprintf("[Kind: '%s', Buildpack: '%s', Outcome: '%s', Total: '%s', Phases: '%s']",
+		r.Kind, r.Buildpack, outcome, r.Total(), strings.Join(phases, ", "))
+}
+
+// marker is a line of builder output that begins a phase.
+type marker struct {
+	phase  Phase
+	regexp *regexp.Regexp
+}
+
+var (
+	// phaseMarkers are tried in order against every line of output. A phase only begins if the
+	// build has not yet reached it or any later phase, so markers that recur, such as the
+	// "-----> " lines of a buildpack, never send the build backwards.
+	phaseMarkers = []marker{
+		{PhaseBuild, regexp.MustCompile(`^Starting build\.\.\. but first, coffee!`)},
+		{PhaseDetect, regexp.MustCompile(`^-----> (?:Restoring cache|Fetching custom buildpack)`)},
+		{PhaseCompile, regexp.MustCompile(`^-----> .+ app detected`)},
+		{PhasePush, regexp.MustCompile(`^(?:-----> Compiled slug size|Pushing to registry|The push refers to)`)},
+		{PhaseRelease, regexp.MustCompile(`^Build complete\.`)},
+		{PhaseLaunch, regexp.MustCompile(`^Launching App\.\.\.`)},
+	}
+	phaseOrder = []Phase{PhaseReceive, PhaseBuild, PhaseDetect, PhaseCompile, PhasePush, PhaseRelease, PhaseLaunch}
+
+	detectedRegexp        = regexp.MustCompile(`^-----> (.+) app detected`)
+	customBuildpackRegexp = regexp.MustCompile(`^-----> Fetching custom buildpack(?:\s+'?([^\s']+)'?)?`)
+	dockerStepRegexp      = regexp.MustCompile(`^Step \d+(?:/\d+)? :`)
+	digestRegexp          = regexp.MustCompile(`digest: (sha256:[0-9a-f]{64})`)
+	doneRegexp            = regexp.MustCompile(`^Done, (\S+):v(\d+) deployed to (?:Workflow|Deis)`)
+	failureRegexps        = []*regexp.Regexp{
+		regexp.MustCompile(`exited with code \d+, stopping build`),
+		regexp.MustCompile(`Unable to select a buildpack`),
+		regexp.MustCompile(`returned a non-zero code: \d+`),
+		regexp.MustCompile(`(?i)unknown instruction: `),
+		regexp.MustCompile(`^Timed out\b`),
+		regexp.MustCompile(`^(?:Error|error|fatal): `),
+	}
+)
+
+// BuildLog follows the output of a build as it is written, noting when each phase begins. It is an
+// io.Writer, so that it may be handed the stderr of a `git push` as the push runs.
+type BuildLog struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	partial []byte
+	report  BuildReport
+}
+
+// NewBuildLog returns a BuildLog for a push that began at the specified time, which is when the
+// receive phase began.
+func NewBuildLog(start time.Time) *BuildLog {
+	l := &BuildLog{now: time.Now}
+	l.report.Phases = []PhaseTiming{{Phase: PhaseReceive, Start: start}}
+	return l
+}
+
+// Write notes each complete line of p as having been written now. Progress that git rewrites in
+// place, by way of carriage returns, counts as separate lines.
+func (l *BuildLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at := l.now()
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexAny(l.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		l.line(at, string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Line notes a single line of output as having been written at the specified time.
+func (l *BuildLog) Line(at time.Time, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.line(at, line)
+}
+
+// Finish ends the last phase at the specified time, when the push exited, and returns the report
+// of the build.
+func (l *BuildLog) Finish(at time.Time) BuildReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.partial) > 0 {
+		l.line(at, string(l.partial))
+		l.partial = nil
+	}
+	l.endPhase(at)
+	report := l.report
+	report.Phases = append([]PhaseTiming(nil), l.report.Phases...)
+	return report
+}
+
+func (l *BuildLog) line(at time.Time, line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "remote:"))
+	if line == "" {
+		return
+	}
+	r := &l.report
+	for _, m := range phaseMarkers {
+		if m.regexp.MatchString(line) && l.before(m.phase) {
+			l.endPhase(at)
+			r.Phases = append(r.Phases, PhaseTiming{Phase: m.phase, Start: at})
+			break
+		}
+	}
+	if match := detectedRegexp.FindStringSubmatch(line); match != nil {
+		r.Kind, r.Buildpack = BuildpackBuild, match[1]
+	} else if match := customBuildpackRegexp.FindStringSubmatch(line); match != nil {
+		r.Kind = BuildpackBuild
+		if r.Buildpack == "" {
+			r.Buildpack = match[1]
+		}
+	} else if strings.HasPrefix(line, "-----> ") && r.Kind == "" {
+		r.Kind = BuildpackBuild
+	} else if dockerStepRegexp.MatchString(line) {
+		r.Kind = DockerfileBuild
+	}
+	if match := digestRegexp.FindStringSubmatch(line); match != nil {
+		r.ImageDigest = match[1]
+	}
+	if match := doneRegexp.FindStringSubmatch(line); match != nil {
+		r.App = match[1]
+		r.Version, _ = strconv.Atoi(match[2])
+		r.Succeeded = true
+	}
+	if r.Failure == "" {
+		for _, re := range failureRegexps {
+			if re.MatchString(line) {
+				r.Failure = line
+				break
+			}
+		}
+	}
+}
+
+// before returns whether the build has yet to reach the specified phase or any later one.
+func (l *BuildLog) before(phase Phase) bool {
+	current := l.report.Phases[len(l.report.Phases)-1].Phase
+	return phaseIndex(phase) > phaseIndex(current)
+}
+
+// endPhase ends the current phase at the specified time.
+func (l *BuildLog) endPhase(at time.Time) {
+	current := &l.report.Phases[len(l.report.Phases)-1]
+	if at.After(current.Start) {
+		current.Duration = at.Sub(current.Start)
+	}
+}
+
+func phaseIndex(phase Phase) int {
+	for i, p := range phaseOrder {
+		if p == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+// BuildOutput parses the complete output of a build, such as the stderr of a `git push`. Since
+// the output carries no timestamps, every phase in the report lasts no time at all.
+func BuildOutput(output []byte) BuildReport {
+	l := NewBuildLog(time.Time{})
+	l.now = func() time.Time { return time.Time{} }
+	l.Write(output)
+	return l.Finish(time.Time{})
+}
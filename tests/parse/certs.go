@@ -0,0 +1,151 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// columnRegexp matches a table heading, which may contain single spaces, such as "Common Name".
+	columnRegexp = regexp.MustCompile(`\S+(?: \S+)*`)
+	ruleRegexp   = regexp.MustCompile(`^[-+| ]+$`)
+)
+
+// Cert is one of the certs listed by `deis certs:list`.
+type Cert struct {
+	Name           string
+	CommonName     string
+	SubjectAltName string
+	Expires        string
+	Fingerprint    string
+	Domains        string
+	Updated        string
+	Created        string
+}
+
+// CertInfo is the cert described by `deis certs:info`.
+type CertInfo struct {
+	Name             string
+	CommonName       string
+	ExpiresAt        string
+	StartsAt         string
+	Fingerprint      string
+	SubjectAltName   string
+	Issuer           string
+	Subject          string
+	ConnectedDomains string
+	Owner            string
+	Created          string
+	Updated          string
+}
+
+// CertsList parses the output of `deis certs:list`, which is a table whose columns are separated
+// either by pipes or by aligned whitespace.
+func CertsList(output []byte) ([]Cert, error) {
+	rows, err := table(lines(output), "Common Name")
+	if err != nil {
+		return nil, err
+	}
+	var certs []Cert
+	for _, row := range rows {
+		certs = append(certs, Cert{
+			Name:           row["Name"],
+			CommonName:     row["Common Name"],
+			SubjectAltName: row["SubjectAltName"],
+			Expires:        row["Expires"],
+			Fingerprint:    row["Fingerprint"],
+			Domains:        row["Domains"],
+			Updated:        row["Updated"],
+			Created:        row["Created"],
+		})
+	}
+	return certs, nil
+}
+
+// CertsInfo parses the output of `deis certs:info`.
+func CertsInfo(output []byte) (CertInfo, error) {
+	title, body, err := section(output, `(.+) Certificate`)
+	if err != nil {
+		return CertInfo{}, err
+	}
+	values := fields(body)
+	return CertInfo{
+		Name:             title[1],
+		CommonName:       values["Common Name(s)"],
+		ExpiresAt:        values["Expires At"],
+		StartsAt:         values["Starts At"],
+		Fingerprint:      values["Fingerprint"],
+		SubjectAltName:   values["Subject Alt Name"],
+		Issuer:           values["Issuer"],
+		Subject:          values["Subject"],
+		ConnectedDomains: values["Connected Domains"],
+		Owner:            values["Owner"],
+		Created:          values["Created"],
+		Updated:          values["Updated"],
+	}, nil
+}
+
+// table parses a table whose header row contains the specified heading into one map per row,
+// keyed by heading. Rows that consist only of rules, such as "+----+----", are skipped.
+func table(ls []string, heading string) ([]map[string]string, error) {
+	start := -1
+	for i, line := range ls {
+		if strings.Contains(line, heading) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no table with a %q column found in output:\n%s", heading, strings.Join(ls, "\n"))
+	}
+
+	header := ls[start]
+	var split func(string) []string
+	var headings []string
+	if strings.Contains(header, "|") {
+		split = func(line string) []string {
+			cells := strings.Split(line, "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			return cells
+		}
+		headings = split(header)
+	} else {
+		// Columns are aligned, so each cell starts where its heading does.
+		locs := columnRegexp.FindAllStringIndex(header, -1)
+		for _, loc := range locs {
+			headings = append(headings, header[loc[0]:loc[1]])
+		}
+		split = func(line string) []string {
+			cells := make([]string, len(locs))
+			for i, loc := range locs {
+				if loc[0] >= len(line) {
+					break
+				}
+				end := len(line)
+				if i+1 < len(locs) && locs[i+1][0] < end {
+					end = locs[i+1][0]
+				}
+				cells[i] = strings.TrimSpace(line[loc[0]:end])
+			}
+			return cells
+		}
+	}
+
+	var rows []map[string]string
+	for _, line := range ls[start+1:] {
+		if strings.TrimSpace(line) == "" || ruleRegexp.MatchString(line) {
+			continue
+		}
+		row := map[string]string{}
+		for i, cell := range split(line) {
+			if i < len(headings) && headings[i] != "" {
+				row[headings[i]] = cell
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,126 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	configKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	limitRegexp     = regexp.MustCompile(`^--- (.+)$`)
+)
+
+// Limits are the limits listed by `deis limits:list`, keyed by process type. A process type with
+// no limit is absent.
+type Limits struct {
+	Memory map[string]string
+	CPU    map[string]string
+}
+
+// ConfigList parses the output of `deis config:list` or `deis config:set` into a map of
+// environment variables. Values may contain whitespace and span multiple lines.
+func ConfigList(output []byte) (map[string]string, error) {
+	_, body, err := section(output, `(.+) Config`)
+	if err != nil {
+		return nil, err
+	}
+	return config(body), nil
+}
+
+// config parses key-value lines whose values may span multiple lines. Keys and values are
+// separated by tabs if any line contains one, in which case any line without one continues the
+// value before it. Otherwise, they are separated by spaces, with every value aligned to the same
+// column, and any line whose value does not start at that column continues the value before it.
+func config(body []string) map[string]string {
+	tabs := false
+	for _, line := range body {
+		if strings.Contains(line, "\t") {
+			tabs = true
+		}
+	}
+
+	values := map[string]string{}
+	key := ""
+	column := -1
+	for _, line := range body {
+		var k, v string
+		var ok bool
+		if tabs {
+			if i := strings.Index(line, "\t"); i >= 0 {
+				k, v, ok = line[:i], strings.TrimLeft(line[i:], "\t "), true
+			}
+		} else if loc := whitespaceRegexp.FindStringIndex(line); loc != nil && loc[0] > 0 {
+			k, v = line[:loc[0]], line[loc[1]:]
+			if column < 0 && configKeyRegexp.MatchString(k) {
+				column = loc[1]
+			}
+			ok = loc[1] == column && configKeyRegexp.MatchString(k)
+		}
+		switch {
+		case ok:
+			key = k
+			values[key] = v
+		case key != "":
+			values[key] += "\n" + line
+		}
+	}
+	for k, v := range values {
+		values[k] = strings.TrimRight(v, "\n")
+	}
+	return values
+}
+
+// RegistryList parses the output of `deis registry:list` or `deis registry:set`.
+func RegistryList(output []byte) (map[string]string, error) {
+	_, body, err := section(output, `(.+) Registry`)
+	if err != nil {
+		return nil, err
+	}
+	return pairs(body), nil
+}
+
+// TagsList parses the output of `deis tags:list` or `deis tags:set`.
+func TagsList(output []byte) (map[string]string, error) {
+	_, body, err := section(output, `(.+) Tags`)
+	if err != nil {
+		return nil, err
+	}
+	return pairs(body), nil
+}
+
+// LabelsList parses the output of `deis labels:list`.
+func LabelsList(output []byte) (map[string]string, error) {
+	_, body, err := section(output, `(.+) Labels?`)
+	if err != nil {
+		return nil, err
+	}
+	return pairs(body), nil
+}
+
+// LimitsList parses the output of `deis limits:list`, `deis limits:set` or `deis limits:unset`.
+func LimitsList(output []byte) (Limits, error) {
+	_, body, err := section(output, `(.+) Limits`)
+	if err != nil {
+		return Limits{}, err
+	}
+	limits := Limits{Memory: map[string]string{}, CPU: map[string]string{}}
+	var current map[string]string
+	for _, line := range body {
+		if match := limitRegexp.FindStringSubmatch(line); match != nil {
+			switch strings.ToLower(match[1]) {
+			case "memory":
+				current = limits.Memory
+			case "cpu":
+				current = limits.CPU
+			default:
+				current = nil
+			}
+			continue
+		}
+		// Lines that consist of a single field, such as "Unlimited", are skipped.
+		if key, value, ok := columns(line); ok && current != nil {
+			current[key] = value
+		}
+	}
+	return limits, nil
+}
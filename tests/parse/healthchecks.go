@@ -0,0 +1,62 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	probeSectionRegexp = regexp.MustCompile(`^--- (Liveness|Readiness)`)
+	probeRegexp        = regexp.MustCompile(`^(Exec|HTTP GET|TCP Socket) Probe: ?(.*)$`)
+)
+
+// Probe is a healthcheck listed by `deis healthchecks:list`.
+type Probe struct {
+	// Type is "Exec", "HTTP GET" or "TCP Socket".
+	Type string
+	// Action describes what the probe does, such as "Command=[/bin/true]" or "Port=1500".
+	Action string
+	// Settings holds the remaining settings listed, such as "Timeout (seconds)", keyed by name.
+	Settings map[string]string
+}
+
+// Healthchecks are the healthchecks listed by `deis healthchecks:list`. Those that are not
+// configured are nil.
+type Healthchecks struct {
+	Liveness  *Probe
+	Readiness *Probe
+}
+
+// HealthchecksList parses the output of `deis healthchecks:list`, `deis healthchecks:set` or
+// `deis healthchecks:unset`.
+func HealthchecksList(output []byte) (Healthchecks, error) {
+	_, body, err := section(output, `(.+) Healthchecks`)
+	if err != nil {
+		return Healthchecks{}, err
+	}
+	checks := Healthchecks{}
+	var current **Probe
+	for _, line := range nonEmpty(body) {
+		if match := probeSectionRegexp.FindStringSubmatch(line); match != nil {
+			if match[1] == "Liveness" {
+				current = &checks.Liveness
+			} else {
+				current = &checks.Readiness
+			}
+			continue
+		}
+		if current == nil || strings.HasPrefix(line, "No ") {
+			continue
+		}
+		if *current == nil {
+			*current = &Probe{Settings: map[string]string{}}
+		}
+		if match := probeRegexp.FindStringSubmatch(line); match != nil {
+			(*current).Type = match[1]
+			(*current).Action = match[2]
+		} else if key, value, ok := field(line); ok {
+			(*current).Settings[key] = value
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Subcommands parses the output of `deis help` into the families of commands, such as "apps" and
+// "config", that it lists as subcommands. Unlike other commands, `deis help` prints no "=== "
+// header; the list follows a line that begins "Subcommands" and ends at the next line that is not
+// indented.
+func Subcommands(output []byte) ([]string, error) {
+	var families []string
+	listing, found := false, false
+	for _, line := range lines(output) {
+		switch {
+		case strings.HasPrefix(line, "Subcommands"):
+			listing, found = true, true
+		case line == "":
+		case !strings.HasPrefix(line, " "):
+			listing = false
+		case listing:
+			family, _, _ := columns(line)
+			families = append(families, family)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no list of subcommands found in output:\n%s", output)
+	}
+	return families, nil
+}
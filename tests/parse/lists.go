@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Key is one of the SSH keys listed by `deis keys:list`.
+type Key struct {
+	ID string
+	// Preview is the abbreviated public key, such as "ssh-rsa AAAAB3Nz...Ed9J user@host".
+	Preview string
+}
+
+// DomainsList parses the output of `deis domains:list` into the app's domains.
+func DomainsList(output []byte) ([]string, error) {
+	_, body, err := section(output, `(.+) Domains`)
+	if err != nil {
+		return nil, err
+	}
+	return nonEmpty(body), nil
+}
+
+// PermsList parses the output of `deis perms:list` or `deis perms:list --admin` into the
+// usernames listed.
+func PermsList(output []byte) ([]string, error) {
+	_, body, err := section(output, `Administrators|(.+)'s Users`)
+	if err != nil {
+		return nil, err
+	}
+	return nonEmpty(body), nil
+}
+
+// KeysList parses the output of `deis keys:list`.
+func KeysList(output []byte) ([]Key, error) {
+	_, body, err := section(output, `(.+) Keys`)
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	for _, line := range nonEmpty(body) {
+		id, preview, ok := columns(line)
+		if !ok || !strings.Contains(preview, "...") {
+			return nil, fmt.Errorf("unexpected line in key list: %q", line)
+		}
+		keys = append(keys, Key{ID: id, Preview: preview})
+	}
+	return keys, nil
+}
@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	logLineRegexp    = regexp.MustCompile(`^(\S+) ([^\s\[]+)\[([^\]]+)\]: (.*)$`)
+	podVersionRegexp = regexp.MustCompile(`^v\d+-`)
+)
+
+// LogLine is one of the lines printed by `deis logs`.
+type LogLine struct {
+	Time string
+	// Source is what wrote the line: the app, for the output of its processes, or "deis", for
+	// messages from the controller.
+	Source string
+	// Process is the name of the process that wrote the line, such as a pod name or "controller".
+	Process string
+	Message string
+}
+
+// ProcessType returns the type of the process of the specified app that wrote the line, such as
+// "web" for a line written by test-app-v2-web-1791523346-bdwht, or "" if the app did not write it.
+func (l LogLine) ProcessType(app string) string {
+	if l.Source != app || !strings.HasPrefix(l.Process, app+"-") {
+		return ""
+	}
+	name := podVersionRegexp.ReplaceAllString(strings.TrimPrefix(l.Process, app+"-"), "")
+	return strings.SplitN(name, "-", 2)[0]
+}
+
+// Logs parses the output of `deis logs`. Lines that are not in the form "<time> <source>[<process>]:
+// <message>", such as the continuations of multi-line messages, are skipped. Unlike the other
+// parsers, Logs expects no header, since `deis logs` prints none.
+func Logs(output []byte) []LogLine {
+	var logs []LogLine
+	for _, line := range lines(output) {
+		match := logLineRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		logs = append(logs, LogLine{Time: match[1], Source: match[2], Process: match[3], Message: match[4]})
+	}
+	return logs
+}
@@ -0,0 +1,109 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The functions in this package turn the output of deis CLI commands into data, so that specs may
+// compare values instead of matching regular expressions against whitespace. Each parser finds the
+// "=== <title>" header printed by its command and reads the lines following it, so output that
+// precedes the header, such as the progress messages printed by `deis limits:set`, is ignored.
+// Parsers return an error if the header is not found.
+
+var (
+	headerRegexp     = regexp.MustCompile(`^=== (.*?)(?: \(\d+ of \d+\))?$`)
+	whitespaceRegexp = regexp.MustCompile(`[ \t]+`)
+)
+
+// lines splits output into lines, with trailing whitespace removed.
+func lines(output []byte) []string {
+	var ls []string
+	for _, line := range strings.Split(strings.Replace(string(output), "\r\n", "\n", -1), "\n") {
+		ls = append(ls, strings.TrimRight(line, " \t"))
+	}
+	return ls
+}
+
+// section finds the first "=== <title>" header whose title matches the specified regular
+// expression, and returns the submatches of the title along with the lines that follow it, up to
+// the next header.
+func section(output []byte, title string) ([]string, []string, error) {
+	titleRegexp := regexp.MustCompile("^" + title + "$")
+	ls := lines(output)
+	for i, line := range ls {
+		header := headerRegexp.FindStringSubmatch(line)
+		if header == nil {
+			continue
+		}
+		match := titleRegexp.FindStringSubmatch(header[1])
+		if match == nil {
+			continue
+		}
+		body := ls[i+1:]
+		for j, line := range body {
+			if headerRegexp.MatchString(line) {
+				body = body[:j]
+				break
+			}
+		}
+		return match, body, nil
+	}
+	return nil, nil, fmt.Errorf("no header matching %q found in output:\n%s", title, output)
+}
+
+// nonEmpty returns the non-blank lines in ls, with leading whitespace removed.
+func nonEmpty(ls []string) []string {
+	var result []string
+	for _, line := range ls {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// columns splits a line into its first whitespace-separated field and the remainder, which may
+// itself contain whitespace. ok is false if the line has only one field.
+func columns(line string) (first, rest string, ok bool) {
+	line = strings.TrimSpace(line)
+	loc := whitespaceRegexp.FindStringIndex(line)
+	if loc == nil {
+		return line, "", false
+	}
+	return line[:loc[0]], line[loc[1]:], true
+}
+
+// field splits a "key: value" line. ok is false if the line contains no colon.
+func field(line string) (key, value string, ok bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// fields parses "key: value" lines into a map, ignoring any other lines.
+func fields(ls []string) map[string]string {
+	values := map[string]string{}
+	for _, line := range ls {
+		if key, value, ok := field(line); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// pairs parses lines of a key followed by whitespace and a value, as printed by commands such as
+// `deis tags:list`, into a map. A trailing colon on a key is dropped. "Unlimited" and other lines
+// that consist of a single field are ignored.
+func pairs(ls []string) map[string]string {
+	values := map[string]string{}
+	for _, line := range nonEmpty(ls) {
+		if key, value, ok := columns(line); ok {
+			values[strings.TrimSuffix(key, ":")] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,13 @@
+package parse
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CLI Output Parsers")
+}
@@ -0,0 +1,303 @@
+package parse
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// golden returns the contents of the named sample of deis CLI output in testdata.
+func golden(name string) []byte {
+	output, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	Expect(err).NotTo(HaveOccurred())
+	return output
+}
+
+var _ = Describe("parse", func() {
+
+	It("parses the processes listed after scaling", func() {
+		procs, err := PsList(golden("ps_scale.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(procs).To(Equal([]Process{
+			{Name: "test-app-v2-cmd-1791523346-bdwht", Type: "cmd", State: "up", Release: 2},
+			{Name: "test-app-v2-cmd-1791523346-x9ks1", Type: "cmd", State: "starting", Release: 2},
+			{Name: "test-app-v2-worker-2113463428-rmv8j", Type: "worker", State: "up", Release: 2},
+		}))
+		Expect(ProcessNames(procs, "worker")).To(Equal([]string{"test-app-v2-worker-2113463428-rmv8j"}))
+		Expect(ProcessNames(procs, "")).To(HaveLen(3))
+	})
+
+	It("parses a list of releases", func() {
+		releases, err := ReleasesList(golden("releases_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(releases).To(HaveLen(3))
+		Expect(releases[0]).To(Equal(Release{Version: 3, Created: "2016-08-26T16:41:56UTC", Summary: "test-4137 added POWERED_BY"}))
+		Expect(releases[2].Summary).To(Equal("test-4137 created initial release"))
+	})
+
+	It("parses a release's info", func() {
+		info, err := ReleasesInfo(golden("releases_info.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(ReleaseInfo{
+			App:     "test-app",
+			Version: 2,
+			Build:   "a5ff6c3b-1e39-4c4b-bcb3-5e87ef7b2a7b",
+			Config:  "d2bd9aa2-1f43-4b4e-b1d6-9e0b1c8a8b2f",
+			Owner:   "test-4137",
+			Created: "2016-08-26T16:40:12UTC",
+			Summary: "test-4137 deployed deis/example-go",
+			Updated: "2016-08-26T16:40:12UTC",
+			UUID:    "6e1ecb7e-0be5-4a3b-9a8d-1c3cb8a4f6a3",
+		}))
+	})
+
+	It("parses tab-separated config, including multi-line and non-ASCII values", func() {
+		config, err := ConfigList(golden("config_set.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(map[string]string{
+			"FOO":        "This is\na\nmultiline string.",
+			"POWERED_BY": "the Deis team",
+			"UNICODE":    "讲台",
+		}))
+	})
+
+	It("parses space-separated config", func() {
+		config, err := ConfigList(golden("config_list_spaces.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(map[string]string{
+			"BAR":            "nil",
+			"DEPLOY_BATCHES": "5",
+			"FOO":            "This is\na\nmultiline string.",
+		}))
+	})
+
+	It("parses limits", func() {
+		limits, err := LimitsList(golden("limits_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.Memory).To(Equal(map[string]string{"cmd": "64M", "worker": "128M"}))
+		Expect(limits.CPU).To(BeEmpty())
+	})
+
+	It("parses a list of certs separated by pipes", func() {
+		certs, err := CertsList(golden("certs_list_pipes.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs).To(Equal([]Cert{
+			{
+				Name:        "1470247815-cert",
+				CommonName:  "www.foo.com",
+				Expires:     "31 Dec 2017 (in 1 year)",
+				Fingerprint: "8F:8E[...]CD:EB",
+				Domains:     "www.foo.com",
+				Updated:     "3 Aug 2016",
+				Created:     "3 Aug 2016",
+			},
+			{
+				Name:           "1470247816-cert",
+				CommonName:     "www.bar.com",
+				SubjectAltName: "bar.com",
+				Expires:        "1 Jan 2018 (in 1 year)",
+				Fingerprint:    "1A:2B[...]3C:4D",
+				Updated:        "3 Aug 2016",
+				Created:        "3 Aug 2016",
+			},
+		}))
+	})
+
+	It("parses a list of certs aligned by whitespace", func() {
+		certs, err := CertsList(golden("certs_list_aligned.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs).To(Equal([]Cert{{
+			Name:        "1470247815-cert",
+			CommonName:  "www.foo.com",
+			Expires:     "31 Dec 2017 (in 1 year)",
+			Fingerprint: "8F:8E[...]CD:EB",
+			Domains:     "www.foo.com",
+			Updated:     "3 Aug 2016",
+			Created:     "3 Aug 2016",
+		}}))
+	})
+
+	It("parses a cert's info", func() {
+		info, err := CertsInfo(golden("certs_info.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(CertInfo{
+			Name:             "1470247815-cert",
+			CommonName:       "www.foo.com",
+			ExpiresAt:        "31 Dec 2017 (in 1 year)",
+			StartsAt:         "1 Jan 2016 (7 months ago)",
+			Fingerprint:      "8F:8E:35:10:2C:CB:C4:54:CD:EB",
+			Issuer:           "/C=US/ST=CA/L=San Francisco/O=Deis/CN=www.foo.com",
+			Subject:          "/C=US/ST=CA/L=San Francisco/O=Deis/CN=www.foo.com",
+			ConnectedDomains: "www.foo.com",
+			Owner:            "test-4137",
+			Created:          "3 Aug 2016 (just now)",
+			Updated:          "3 Aug 2016 (just now)",
+		}))
+	})
+
+	It("parses a list of domains", func() {
+		domains, err := DomainsList(golden("domains_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domains).To(Equal([]string{"test-app", "www.foo.com"}))
+	})
+
+	It("parses a list of an app's collaborators", func() {
+		users, err := PermsList(golden("perms_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(Equal([]string{"test-4138", "test-4139"}))
+	})
+
+	It("parses a list of administrators", func() {
+		users, err := PermsList(golden("perms_list_admin.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(Equal([]string{"admin", "test-4137"}))
+	})
+
+	It("parses a list of keys", func() {
+		keys, err := KeysList(golden("keys_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]Key{
+			{ID: "deiskey-8492", Preview: "ssh-rsa AAAAB3Nz...Ed9J9Kq1Zm deiskey-8492"},
+			{ID: "deiskey-8493", Preview: "ssh-rsa AAAAB3Nz...4fEu7xAQcN deiskey-8493"},
+		}))
+	})
+
+	It("parses tags", func() {
+		tags, err := TagsList(golden("tags_set.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tags).To(Equal(map[string]string{"kubernetes.io/hostname": "192.168.64.2", "node": "worker1"}))
+	})
+
+	It("parses labels", func() {
+		labels, err := LabelsList(golden("labels_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(Equal(map[string]string{"service": "frontend", "team": "bi"}))
+	})
+
+	It("parses registry info", func() {
+		registry, err := RegistryList(golden("registry_list.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry).To(Equal(map[string]string{"password": "s3cr3t", "username": "bob"}))
+	})
+
+	It("parses healthchecks", func() {
+		checks, err := HealthchecksList(golden("healthchecks_set.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks.Readiness).To(BeNil())
+		Expect(checks.Liveness).NotTo(BeNil())
+		Expect(checks.Liveness.Type).To(Equal("HTTP GET"))
+		Expect(checks.Liveness.Action).To(Equal(`Path="/" Port=1500 HTTPHeaders=[]`))
+		Expect(checks.Liveness.Settings).To(HaveKeyWithValue("Timeout (seconds)", "50"))
+		Expect(checks.Liveness.Settings).To(HaveLen(5))
+	})
+
+	It("parses the output of a buildpack build", func() {
+		report := BuildOutput(golden("git_push_buildpack.txt"))
+		Expect(report.Kind).To(Equal(BuildpackBuild))
+		Expect(report.Buildpack).To(Equal("Go"))
+		Expect(report.Succeeded).To(BeTrue())
+		Expect(report.App).To(Equal("test-app"))
+		Expect(report.Version).To(Equal(2))
+		Expect(report.Failure).To(BeEmpty())
+		Expect(phases(report)).To(Equal([]Phase{
+			PhaseReceive, PhaseBuild, PhaseDetect, PhaseCompile, PhasePush, PhaseRelease, PhaseLaunch,
+		}))
+	})
+
+	It("parses the output of a dockerfile build", func() {
+		report := BuildOutput(golden("git_push_dockerfile.txt"))
+		Expect(report.Kind).To(Equal(DockerfileBuild))
+		Expect(report.Buildpack).To(BeEmpty())
+		Expect(report.ImageDigest).To(Equal("sha256:8f9b7c1e2d3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c"))
+		Expect(report.Succeeded).To(BeTrue())
+		Expect(phases(report)).To(Equal([]Phase{PhaseReceive, PhaseBuild, PhasePush, PhaseRelease, PhaseLaunch}))
+	})
+
+	It("parses the output of a failed build", func() {
+		report := BuildOutput(golden("git_push_undetectable.txt"))
+		Expect(report.Kind).To(Equal(BuildpackBuild))
+		Expect(report.Succeeded).To(BeFalse())
+		Expect(report.Failure).To(Equal("-----> Unable to select a buildpack"))
+		Expect(report.Reached(PhaseDetect)).To(BeTrue())
+		Expect(report.Reached(PhaseCompile)).To(BeFalse())
+	})
+
+	It("times each phase of a build from when its lines were written", func() {
+		start := time.Now()
+		at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+		log := NewBuildLog(start)
+		log.Line(at(2), "Starting build... but first, coffee!")
+		log.Line(at(3), "remote: -----> Restoring cache...")
+		log.Line(at(5), "-----> Ruby app detected")
+		log.Line(at(6), "-----> Restoring cache...")
+		log.Line(at(45), "-----> Compiled slug size is 24M")
+		log.Line(at(50), "Build complete.")
+		log.Line(at(51), "Launching App...")
+		log.Line(at(60), "Done, test-app:v2 deployed to Workflow")
+		report := log.Finish(at(61))
+
+		Expect(report.Buildpack).To(Equal("Ruby"))
+		Expect(report.Duration(PhaseReceive)).To(Equal(2 * time.Second))
+		Expect(report.Duration(PhaseDetect)).To(Equal(2 * time.Second))
+		Expect(report.Duration(PhaseCompile)).To(Equal(40 * time.Second))
+		Expect(report.Duration(PhaseLaunch)).To(Equal(10 * time.Second))
+		Expect(report.Total()).To(Equal(61 * time.Second))
+	})
+
+	It("parses logs, attributing each line to the process that wrote it", func() {
+		logs := Logs(append(golden("logs.txt"), "  continued\n"...))
+		Expect(logs).To(HaveLen(7))
+		Expect(logs[0]).To(Equal(LogLine{
+			Time:    "2016-08-26T16:40:02UTC",
+			Source:  "deis",
+			Process: "controller",
+			Message: "INFO config test-app-4c5ef2 updated",
+		}))
+		Expect(logs[3].Message).To(Equal("listening on :5000"))
+		var types []string
+		for _, l := range logs {
+			types = append(types, l.ProcessType("test-app"))
+		}
+		Expect(types).To(Equal([]string{"", "", "", "web", "worker", "run", "web"}))
+	})
+
+	It("fails to parse output without the expected header", func() {
+		_, err := ConfigList(golden("tags_set.txt"))
+		Expect(err).To(HaveOccurred())
+		_, err = CertsList(golden("domains_list.txt"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("parses the families of commands listed by help", func() {
+		families, err := Subcommands(golden("help.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(families).To(HaveLen(22))
+		Expect(families[:3]).To(Equal([]string{"apps", "autoscale", "builds"}))
+		Expect(families).To(ContainElement("whitelist"))
+		Expect(families).NotTo(ContainElement("register"))
+		Expect(families).NotTo(ContainElement("create"))
+
+		_, err = Subcommands(golden("domains_list.txt"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not read past the next header", func() {
+		output := append(golden("domains_list.txt"), golden("perms_list.txt")...)
+		domains, err := DomainsList(output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domains).To(Equal([]string{"test-app", "www.foo.com"}))
+	})
+
+})
+
+// phases returns the phases that a build went through, in order.
+func phases(report BuildReport) []Phase {
+	var phases []Phase
+	for _, p := range report.Phases {
+		phases = append(phases, p.Phase)
+	}
+	return phases
+}
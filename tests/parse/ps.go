@@ -0,0 +1,56 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	procTypeRegexp = regexp.MustCompile(`^--- (.+):$`)
+	processRegexp  = regexp.MustCompile(`^(\S+) (\S+) \(v(\d+)\)$`)
+)
+
+// Process is one of the processes listed by `deis ps:list`.
+type Process struct {
+	Name    string
+	Type    string
+	State   string
+	Release int
+}
+
+// PsList parses the output of `deis ps:list`, `deis ps:scale` or `deis ps:restart`.
+func PsList(output []byte) ([]Process, error) {
+	_, body, err := section(output, `(.+) Processes`)
+	if err != nil {
+		return nil, err
+	}
+	var procs []Process
+	procType := ""
+	for _, line := range nonEmpty(body) {
+		if match := procTypeRegexp.FindStringSubmatch(line); match != nil {
+			procType = match[1]
+			continue
+		}
+		match := processRegexp.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("unexpected line in process list: %q", line)
+		}
+		release, _ := strconv.Atoi(match[3])
+		procs = append(procs, Process{Name: match[1], Type: procType, State: match[2], Release: release})
+	}
+	return procs, nil
+}
+
+// ProcessNames returns the names of the specified processes that are of the specified type, or of
+// all of them if procType is empty.
+func ProcessNames(procs []Process, procType string) []string {
+	var names []string
+	for _, proc := range procs {
+		if procType == "" || strings.EqualFold(proc.Type, procType) {
+			names = append(names, proc.Name)
+		}
+	}
+	return names
+}
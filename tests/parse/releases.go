@@ -0,0 +1,68 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Release is one of the releases listed by `deis releases:list`.
+type Release struct {
+	Version int
+	Created string
+	Summary string
+}
+
+// ReleaseInfo is the release described by `deis releases:info`.
+type ReleaseInfo struct {
+	App     string
+	Version int
+	Build   string
+	Config  string
+	Owner   string
+	Created string
+	Summary string
+	Updated string
+	UUID    string
+}
+
+// ReleasesList parses the output of `deis releases:list`. Releases are returned in the order
+// listed, which is newest first.
+func ReleasesList(output []byte) ([]Release, error) {
+	_, body, err := section(output, `(.+) Releases`)
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	for _, line := range nonEmpty(body) {
+		version, rest, _ := columns(line)
+		created, summary, _ := columns(rest)
+		v, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected line in release list: %q", line)
+		}
+		releases = append(releases, Release{Version: v, Created: created, Summary: summary})
+	}
+	return releases, nil
+}
+
+// ReleasesInfo parses the output of `deis releases:info`.
+func ReleasesInfo(output []byte) (ReleaseInfo, error) {
+	title, body, err := section(output, `(.+) Release v(\d+)`)
+	if err != nil {
+		return ReleaseInfo{}, err
+	}
+	version, _ := strconv.Atoi(title[2])
+	values := fields(body)
+	return ReleaseInfo{
+		App:     title[1],
+		Version: version,
+		Build:   values["build"],
+		Config:  values["config"],
+		Owner:   values["owner"],
+		Created: values["created"],
+		Summary: values["summary"],
+		Updated: values["updated"],
+		UUID:    values["uuid"],
+	}, nil
+}
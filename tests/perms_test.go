@@ -233,6 +233,10 @@ var _ = Describe("deis perms", func() {
 					auth.Cancel(otherUser)
 				})
 
+				Specify("the second user cannot grant themself permissions on that app", func() {
+					perms.CreateExpectingError(otherUser, app, otherUser, deis.ErrForbidden)
+				})
+
 				Specify("that first user can grant permissions on that app to the second user", func() {
 					perms.Create(user, app, otherUser)
 					sess, err := cmd.Start("deis perms:list --app=%s", &user, app.Name)
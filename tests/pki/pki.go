@@ -0,0 +1,228 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// The types and functions in this package issue X.509 certificates at runtime, so that specs need
+// not rely on checked-in certificates that will one day expire. An Authority is a certificate
+// authority: a root, or an intermediate signed by another Authority. It issues leaf certificates
+// for whatever names, validity window and type of key a spec requires.
+
+// KeyType is the type of a certificate's key.
+type KeyType int
+
+const (
+	// RSA is a 2048-bit RSA key.
+	RSA KeyType = iota
+	// ECDSA is an ECDSA key on the P-256 curve.
+	ECDSA
+)
+
+func (k KeyType) String() string {
+	if k == ECDSA {
+		return "ECDSA"
+	}
+	return "RSA"
+}
+
+// DefaultValidity is how long certificates are valid for, unless requested otherwise.
+const DefaultValidity = 24 * time.Hour
+
+// authorityValidity is how long before and after its creation an Authority is valid, so that it
+// may issue certificates whose validity windows lie well in the past or the future.
+const authorityValidity = 365 * 24 * time.Hour
+
+// Authority is a certificate authority that issues certificates.
+type Authority struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// Chain are the certificates of the authorities above this one, from its issuer up to, but
+	// not including, the root. It is empty for a root.
+	Chain []*x509.Certificate
+}
+
+// NewAuthority returns a new, self-signed root Authority with the specified common name.
+func NewAuthority(commonName string) (*Authority, error) {
+	key, err := generateKey(RSA)
+	if err != nil {
+		return nil, err
+	}
+	template, err := caTemplate(commonName)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := sign(template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &Authority{Cert: cert, Key: key}, nil
+}
+
+// Intermediate returns a new Authority with the specified common name, signed by this one.
+func (a *Authority) Intermediate(commonName string) (*Authority, error) {
+	key, err := generateKey(RSA)
+	if err != nil {
+		return nil, err
+	}
+	template, err := caTemplate(commonName)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := sign(template, a.Cert, key.Public(), a.Key)
+	if err != nil {
+		return nil, err
+	}
+	chain := a.Chain
+	if !isRoot(a.Cert) {
+		chain = append([]*x509.Certificate{a.Cert}, a.Chain...)
+	}
+	return &Authority{Cert: cert, Key: key, Chain: chain}, nil
+}
+
+// Pool returns a certificate pool containing only this Authority, for use as the trusted roots
+// when verifying certificates it issued.
+func (a *Authority) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.Cert)
+	return pool
+}
+
+// Request describes a leaf certificate to issue.
+type Request struct {
+	CommonName string
+	// DNSNames are the certificate's subject alternative names.
+	DNSNames []string
+	// NotBefore and NotAfter bound the certificate's validity. If NotBefore is zero, it is valid
+	// from an hour ago, and if NotAfter is zero, for DefaultValidity after NotBefore.
+	NotBefore time.Time
+	NotAfter  time.Time
+	KeyType   KeyType
+}
+
+// Leaf is an issued leaf certificate.
+type Leaf struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// Chain are the certificates of the authorities that issued the leaf, from its issuer up to,
+	// but not including, the root.
+	Chain []*x509.Certificate
+}
+
+// Issue issues a leaf certificate as requested.
+func (a *Authority) Issue(req Request) (*Leaf, error) {
+	key, err := generateKey(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	notBefore := req.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now().Add(-time.Hour)
+	}
+	notAfter := req.NotAfter
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(DefaultValidity)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.CommonName, Organization: []string{"Deis"}},
+		DNSNames:     req.DNSNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	cert, err := sign(template, a.Cert, key.Public(), a.Key)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	if !isRoot(a.Cert) {
+		chain = append([]*x509.Certificate{a.Cert}, a.Chain...)
+	}
+	return &Leaf{Cert: cert, Key: key, Chain: chain}, nil
+}
+
+// CertPEM returns the leaf followed by its chain, PEM encoded, as a server presents them.
+func (l *Leaf) CertPEM() []byte {
+	return EncodeCerts(append([]*x509.Certificate{l.Cert}, l.Chain...)...)
+}
+
+// KeyPEM returns the leaf's private key, PEM encoded.
+func (l *Leaf) KeyPEM() ([]byte, error) {
+	switch key := l.Key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", l.Key)
+}
+
+// EncodeCerts returns the specified certificates, PEM encoded, in the order given.
+func EncodeCerts(certs ...*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+func caTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"Deis"}},
+		NotBefore:             time.Now().Add(-authorityValidity),
+		NotAfter:              time.Now().Add(authorityValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil
+}
+
+func sign(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	if keyType == ECDSA {
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// isRoot returns whether cert is self-signed.
+func isRoot(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
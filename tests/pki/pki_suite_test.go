@@ -0,0 +1,13 @@
+package pki
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPKI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PKI")
+}
@@ -0,0 +1,130 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("an authority", func() {
+
+	var root *Authority
+
+	BeforeEach(func() {
+		var err error
+		root, err = NewAuthority("Deis E2E Root")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	verify := func(leaf *Leaf, name string, at time.Time) error {
+		intermediates := x509.NewCertPool()
+		for _, cert := range leaf.Chain {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Cert.Verify(x509.VerifyOptions{
+			DNSName:       name,
+			Roots:         root.Pool(),
+			Intermediates: intermediates,
+			CurrentTime:   at,
+		})
+		return err
+	}
+
+	It("is a self-signed root", func() {
+		Expect(root.Cert.IsCA).To(BeTrue())
+		Expect(root.Cert.CheckSignatureFrom(root.Cert)).To(Succeed())
+		Expect(root.Chain).To(BeEmpty())
+	})
+
+	It("issues certificates for the requested names that verify against it", func() {
+		leaf, err := root.Issue(Request{CommonName: "www.foo.com", DNSNames: []string{"www.foo.com", "foo.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaf.Cert.Subject.CommonName).To(Equal("www.foo.com"))
+		Expect(leaf.Chain).To(BeEmpty())
+		Expect(verify(leaf, "www.foo.com", time.Now())).To(Succeed())
+		Expect(verify(leaf, "foo.com", time.Now())).To(Succeed())
+		Expect(verify(leaf, "bar.com", time.Now())).To(HaveOccurred())
+	})
+
+	It("issues wildcard certificates", func() {
+		leaf, err := root.Issue(Request{CommonName: "*.foo.com", DNSNames: []string{"*.foo.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verify(leaf, "www.foo.com", time.Now())).To(Succeed())
+		Expect(verify(leaf, "foo.com", time.Now())).To(HaveOccurred())
+	})
+
+	It("issues certificates valid for the requested window", func() {
+		expired, err := root.Issue(Request{
+			CommonName: "expired.foo.com",
+			DNSNames:   []string{"expired.foo.com"},
+			NotBefore:  time.Now().Add(-48 * time.Hour),
+			NotAfter:   time.Now().Add(-24 * time.Hour),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verify(expired, "expired.foo.com", time.Now())).To(HaveOccurred())
+		Expect(verify(expired, "expired.foo.com", time.Now().Add(-36*time.Hour))).To(Succeed())
+
+		leaf, err := root.Issue(Request{CommonName: "www.foo.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaf.Cert.NotAfter.Sub(leaf.Cert.NotBefore)).To(Equal(DefaultValidity))
+		Expect(leaf.Cert.NotBefore).To(BeTemporally("<", time.Now()))
+	})
+
+	It("issues certificates with RSA or ECDSA keys", func() {
+		for keyType, expected := range map[KeyType]interface{}{RSA: &rsa.PrivateKey{}, ECDSA: &ecdsa.PrivateKey{}} {
+			leaf, err := root.Issue(Request{CommonName: "www.foo.com", KeyType: keyType})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leaf.Key).To(BeAssignableToTypeOf(expected), keyType.String())
+
+			keyPEM, err := leaf.KeyPEM()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = tls.X509KeyPair(leaf.CertPEM(), keyPEM)
+			Expect(err).NotTo(HaveOccurred(), keyType.String())
+		}
+	})
+
+	Context("with intermediates", func() {
+
+		It("issues certificates whose chain leads back to the root", func() {
+			first, err := root.Intermediate("Deis E2E Intermediate 1")
+			Expect(err).NotTo(HaveOccurred())
+			second, err := first.Intermediate("Deis E2E Intermediate 2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.Chain).To(Equal([]*x509.Certificate{first.Cert}))
+
+			leaf, err := second.Issue(Request{CommonName: "www.foo.com", DNSNames: []string{"www.foo.com"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leaf.Chain).To(Equal([]*x509.Certificate{second.Cert, first.Cert}))
+			Expect(verify(leaf, "www.foo.com", time.Now())).To(Succeed())
+
+			pair, err := tls.X509KeyPair(leaf.CertPEM(), mustKeyPEM(leaf))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Certificate).To(HaveLen(3))
+			Expect(pair.Certificate[0]).To(Equal(leaf.Cert.Raw))
+		})
+
+		It("encodes certificates in the order given", func() {
+			intermediate, err := root.Intermediate("Deis E2E Intermediate")
+			Expect(err).NotTo(HaveOccurred())
+			leaf, err := intermediate.Issue(Request{CommonName: "www.foo.com"})
+			Expect(err).NotTo(HaveOccurred())
+
+			// With the chain first, the first certificate no longer matches the key.
+			_, err = tls.X509KeyPair(EncodeCerts(intermediate.Cert, leaf.Cert), mustKeyPEM(leaf))
+			Expect(err).To(HaveOccurred())
+		})
+
+	})
+
+})
+
+func mustKeyPEM(leaf *Leaf) []byte {
+	keyPEM, err := leaf.KeyPEM()
+	Expect(err).NotTo(HaveOccurred())
+	return keyPEM
+}
@@ -0,0 +1,204 @@
+package poll
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The types and functions in this package poll for a condition: they make an attempt, such as
+// executing a command or requesting a URL, repeatedly, backing off between attempts, until the
+// result satisfies a set of predicates or a budget of time or attempts is spent. Every attempt is
+// kept, so that when polling fails, the error describes what each attempt saw.
+
+// Result is the outcome of a single attempt.
+type Result struct {
+	// Out is what the attempt produced, such as a command's stdout or an HTTP response body.
+	Out []byte
+	// Err is a command's stderr.
+	Err []byte
+	// Code is a command's exit code or an HTTP response's status code.
+	Code int
+	// Error is set if the attempt could not be made at all.
+	Error error
+	// Value is the attempt's outcome in full, such as an HTTP response, for predicates that need
+	// more than the fields above.
+	Value interface{}
+	// Attempt is the number of the attempt that produced the result, counting from 1. Until fills
+	// it in.
+	Attempt int
+}
+
+// Attempt is a record of a single attempt.
+type Attempt struct {
+	Number int
+	// Start is when the attempt was made, relative to the start of polling.
+	Start    time.Duration
+	Duration time.Duration
+	Result   Result
+	// Reason explains why the result did not satisfy the predicates. It is empty if it did.
+	Reason string
+}
+
+// Backoff determines how long to wait between attempts. The first interval is Initial, and each
+// one after is Factor times the last, up to Max. Each interval is then lengthened or shortened at
+// random by up to Jitter times itself, so that specs polling in parallel spread out.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+	Jitter  float64
+}
+
+// Constant returns a Backoff that always waits for the specified interval.
+func Constant(interval time.Duration) Backoff {
+	return Backoff{Initial: interval, Max: interval, Factor: 1}
+}
+
+// Exponential returns a Backoff that starts at 500ms and grows by half each attempt, up to 10s,
+// with 20% jitter.
+func Exponential() Backoff {
+	return Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Factor: 1.5, Jitter: 0.2}
+}
+
+// Interval returns how long to wait after the specified attempt, counting from 1.
+func (b Backoff) Interval(attempt int) time.Duration {
+	interval := float64(b.Initial)
+	for i := 1; i < attempt && interval < float64(b.Max); i++ {
+		interval *= b.Factor
+	}
+	if b.Max > 0 && interval > float64(b.Max) {
+		interval = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		interval += interval * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(interval)
+}
+
+// Poller polls for a condition within a budget. At least one of Timeout and MaxAttempts must be
+// set. An attempt is always made, however small the budget.
+type Poller struct {
+	Backoff Backoff
+	// Timeout bounds the time spent polling. The context passed to each attempt expires when it
+	// is spent.
+	Timeout time.Duration
+	// MaxAttempts bounds the number of attempts.
+	MaxAttempts int
+	// Log, if set, is written a line describing each attempt as it is made.
+	Log io.Writer
+}
+
+// New returns a Poller that backs off exponentially (see Exponential) for up to timeout.
+func New(timeout time.Duration) Poller {
+	return Poller{Backoff: Exponential(), Timeout: timeout}
+}
+
+// Every returns a Poller that makes an attempt every interval for up to timeout.
+func Every(interval, timeout time.Duration) Poller {
+	return Poller{Backoff: Constant(interval), Timeout: timeout}
+}
+
+// Failure is the error returned when polling fails to satisfy its predicates within its budget.
+type Failure struct {
+	// Description describes what was being polled for.
+	Description string
+	Attempts    []Attempt
+	Elapsed     time.Duration
+}
+
+// maxOutput is how much of each attempt's output a Failure describes.
+const maxOutput = 200
+
+func (f *Failure) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "gave up waiting for %s after %d attempts in %s:", f.Description, len(f.Attempts), f.Elapsed)
+	for _, a := range f.Attempts {
+		fmt.Fprintf(&buf, "\n  %s", a)
+	}
+	return buf.String()
+}
+
+// String describes the attempt on a single line.
+func (a Attempt) String() string {
+	outcome := "ok"
+	if a.Reason != "" {
+		outcome = a.Reason
+	}
+	return fmt.Sprintf("#%d at %s (took %s): %s", a.Number, a.Start-a.Start%time.Millisecond,
+		a.Duration-a.Duration%time.Millisecond, outcome)
+}
+
+// ErrNoBudget is returned by Until, without making any attempt, when a Poller has neither a
+// Timeout nor MaxAttempts, and so would poll forever.
+var ErrNoBudget = errors.New("poller has neither a Timeout nor MaxAttempts")
+
+// Until makes attempts until a result satisfies every predicate, returning that result. If the
+// budget is spent first, it returns the last result and a *Failure describing every attempt. With
+// no predicates, any result without an Error is satisfactory.
+func (p Poller) Until(description string, attempt func(ctx context.Context) Result, preds ...Predicate) (Result, error) {
+	if p.Timeout <= 0 && p.MaxAttempts <= 0 {
+		return Result{}, fmt.Errorf("cannot wait for %s: %s", description, ErrNoBudget)
+	}
+	ctx := context.Background()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	pred := All(append([]Predicate{NoError()}, preds...)...)
+
+	start := time.Now()
+	var history []Attempt
+	for n := 1; ; n++ {
+		a := Attempt{Number: n, Start: time.Since(start)}
+		a.Result = attempt(ctx)
+		a.Result.Attempt = n
+		a.Duration = time.Since(start) - a.Start
+		if ok, reason := pred(a.Result); !ok {
+			a.Reason = reason
+		}
+		history = append(history, a)
+		if p.Log != nil {
+			fmt.Fprintf(p.Log, "Waiting for %s: %s\n", description, a)
+		}
+		if a.Reason == "" {
+			return a.Result, nil
+		}
+		if p.MaxAttempts > 0 && n >= p.MaxAttempts || !sleep(ctx, p.Backoff.Interval(n)) {
+			break
+		}
+	}
+	return history[len(history)-1].Result, &Failure{Description: description, Attempts: history, Elapsed: time.Since(start)}
+}
+
+// sleep waits for the specified interval, returning false if ctx expires first.
+func sleep(ctx context.Context, interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// truncate shortens output for inclusion in a Failure, without splitting a UTF-8 encoded rune.
+func truncate(output []byte) string {
+	s := strings.TrimSpace(string(output))
+	if len(s) <= maxOutput {
+		return s
+	}
+	n := maxOutput
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,13 @@
+package poll
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPoll(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Poll")
+}
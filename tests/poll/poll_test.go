@@ -0,0 +1,180 @@
+package poll
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("poll", func() {
+
+	// sequence returns an attempt function that returns each of the specified outputs in turn,
+	// repeating the last one forever, along with a pointer to the number of attempts made.
+	sequence := func(outputs ...string) (func(context.Context) Result, *int) {
+		n := 0
+		return func(context.Context) Result {
+			i := n
+			if i >= len(outputs) {
+				i = len(outputs) - 1
+			}
+			n++
+			return Result{Out: []byte(outputs[i])}
+		}, &n
+	}
+
+	fast := Poller{Backoff: Constant(time.Millisecond), Timeout: time.Second}
+
+	Describe("Backoff", func() {
+
+		It("grows exponentially up to its maximum", func() {
+			b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+			Expect(b.Interval(1)).To(Equal(100 * time.Millisecond))
+			Expect(b.Interval(2)).To(Equal(200 * time.Millisecond))
+			Expect(b.Interval(4)).To(Equal(800 * time.Millisecond))
+			Expect(b.Interval(5)).To(Equal(time.Second))
+			Expect(b.Interval(100)).To(Equal(time.Second))
+			Expect(Constant(time.Second).Interval(7)).To(Equal(time.Second))
+		})
+
+		It("applies jitter within its bounds", func() {
+			b := Backoff{Initial: time.Second, Max: time.Second, Factor: 1, Jitter: 0.2}
+			for i := 0; i < 100; i++ {
+				Expect(b.Interval(1)).To(BeNumerically("~", time.Second, 200*time.Millisecond))
+			}
+		})
+
+	})
+
+	Describe("Poller", func() {
+
+		It("returns the first result that satisfies every predicate", func() {
+			attempt, n := sequence("starting", "up v1", "up v2")
+			r, err := fast.Until("v2", attempt, Contains("up"), Contains("v2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(r.Out)).To(Equal("up v2"))
+			Expect(*n).To(Equal(3))
+		})
+
+		It("gives up after its maximum number of attempts, describing each one", func() {
+			attempt, n := sequence("starting")
+			p := Poller{Backoff: Constant(time.Millisecond), MaxAttempts: 3}
+			var log bytes.Buffer
+			p.Log = &log
+			r, err := p.Until("the app to be up", attempt, Contains("up"))
+			Expect(*n).To(Equal(3))
+			Expect(string(r.Out)).To(Equal("starting"))
+			failure, ok := err.(*Failure)
+			Expect(ok).To(BeTrue())
+			Expect(failure.Attempts).To(HaveLen(3))
+			Expect(failure.Attempts[2].Number).To(Equal(3))
+			Expect(err.Error()).To(HavePrefix("gave up waiting for the app to be up after 3 attempts"))
+			Expect(err.Error()).To(ContainSubstring(`#3 at`))
+			Expect(err.Error()).To(ContainSubstring(`output "starting" does not contain "up"`))
+			Expect(log.String()).To(ContainSubstring("Waiting for the app to be up: #1 at"))
+		})
+
+		It("gives up when its timeout is spent, cancelling the attempt's context", func() {
+			p := Poller{Backoff: Constant(time.Hour), Timeout: 50 * time.Millisecond}
+			start := time.Now()
+			_, err := p.Until("nothing", func(ctx context.Context) Result {
+				return Result{Error: errors.New("refused")}
+			})
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Expect(err).To(MatchError(ContainSubstring("error: refused")))
+
+			var attemptErr error
+			_, err = p.Until("the attempt to finish", func(ctx context.Context) Result {
+				<-ctx.Done()
+				attemptErr = ctx.Err()
+				return Result{Error: ctx.Err()}
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(attemptErr).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("refuses to poll without a budget", func() {
+			attempt, n := sequence("up")
+			_, err := Poller{Backoff: Constant(time.Millisecond)}.Until("forever", attempt)
+			Expect(err).To(MatchError(ContainSubstring(ErrNoBudget.Error())))
+			Expect(*n).To(BeZero())
+		})
+
+	})
+
+	Describe("predicates", func() {
+
+		It("checks exit and status codes", func() {
+			ok, _ := ExitCode(0)(Result{Code: 0})
+			Expect(ok).To(BeTrue())
+			ok, reason := ExitCode(0)(Result{Code: 128, Err: []byte("fatal")})
+			Expect(ok).To(BeFalse())
+			Expect(reason).To(Equal(`exit code 128, not 0 (stderr "fatal")`))
+			ok, reason = StatusCode(200)(Result{Code: 503})
+			Expect(ok).To(BeFalse())
+			Expect(reason).To(Equal("status code 503, not 200"))
+			ok, _ = StderrContains("up-to-date")(Result{Err: []byte("Everything up-to-date")})
+			Expect(ok).To(BeTrue())
+		})
+
+		It("truncates long output without splitting a rune", func() {
+			Expect(truncate([]byte("  short\n"))).To(Equal("short"))
+			long := strings.Repeat("a", maxOutput-1) + "世界"
+			Expect(truncate([]byte(long))).To(Equal(strings.Repeat("a", maxOutput-1) + "..."))
+			Expect(utf8.ValidString(truncate([]byte(strings.Repeat("世", maxOutput))))).To(BeTrue())
+		})
+
+		It("composes", func() {
+			up := Result{Out: []byte("up"), Code: 0}
+			ok, _ := All(Contains("up"), ExitCode(0))(up)
+			Expect(ok).To(BeTrue())
+			ok, reason := All(Contains("up"), ExitCode(1))(up)
+			Expect(ok).To(BeFalse())
+			Expect(reason).To(HavePrefix("exit code 0, not 1"))
+			ok, _ = All()(up)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("waits for a result to be stable", func() {
+			attempt, n := sequence("1 up", "2 up", "2 up", "2 starting", "2 up", "2 up", "2 up")
+			r, err := fast.Until("2 procs to be stable", attempt, Stable(3, Contains("2")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*n).To(Equal(7))
+			Expect(string(r.Out)).To(Equal("2 up"))
+
+			stable := Stable(2, Contains("up"))
+			for i, expected := range []bool{false, true, true, false, false} {
+				ok, _ := stable(Result{Attempt: i + 1, Out: []byte([]string{"up", "up", "up", "down", "up"}[i])})
+				Expect(ok).To(Equal(expected), fmt.Sprint(i))
+			}
+		})
+
+		It("starts counting again after an attempt that an earlier predicate rejected", func() {
+			// Each of these is rejected before Stable sees it, though it has the same output.
+			for _, rejected := range []Result{
+				{Out: []byte("up"), Error: errors.New("connection refused")},
+				{Out: []byte("up"), Code: 1},
+			} {
+				attempt, n := sequence("up")
+				interrupted := func(ctx context.Context) Result {
+					if *n == 1 {
+						*n++
+						return rejected
+					}
+					return attempt(ctx)
+				}
+				r, err := fast.Until("the app to be up", interrupted, ExitCode(0), Stable(2, Contains("up")))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r.Attempt).To(Equal(4))
+			}
+		})
+
+	})
+
+})
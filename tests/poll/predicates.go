@@ -0,0 +1,104 @@
+package poll
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Predicate decides whether a Result is the one being polled for. If it is not, the predicate
+// returns a reason, which should describe what the result held instead.
+type Predicate func(Result) (ok bool, reason string)
+
+// NoError holds for results of attempts that could be made.
+func NoError() Predicate {
+	return func(r Result) (bool, string) {
+		if r.Error != nil {
+			return false, fmt.Sprintf("error: %s", r.Error)
+		}
+		return true, ""
+	}
+}
+
+// Contains holds for results whose Out contains s.
+func Contains(s string) Predicate {
+	return func(r Result) (bool, string) {
+		if !bytes.Contains(r.Out, []byte(s)) {
+			return false, fmt.Sprintf("output %q does not contain %q", truncate(r.Out), s)
+		}
+		return true, ""
+	}
+}
+
+// StderrContains holds for results whose Err contains s.
+func StderrContains(s string) Predicate {
+	return func(r Result) (bool, string) {
+		if !bytes.Contains(r.Err, []byte(s)) {
+			return false, fmt.Sprintf("stderr %q does not contain %q", truncate(r.Err), s)
+		}
+		return true, ""
+	}
+}
+
+// ExitCode holds for results of commands that exited with the specified code.
+func ExitCode(code int) Predicate {
+	return func(r Result) (bool, string) {
+		if r.Code != code {
+			return false, fmt.Sprintf("exit code %d, not %d (stderr %q)", r.Code, code, truncate(r.Err))
+		}
+		return true, ""
+	}
+}
+
+// StatusCode holds for results of HTTP requests that were answered with the specified status
+// code.
+func StatusCode(code int) Predicate {
+	return func(r Result) (bool, string) {
+		if r.Code != code {
+			return false, fmt.Sprintf("status code %d, not %d", r.Code, code)
+		}
+		return true, ""
+	}
+}
+
+// All holds for results for which every one of preds holds. The reason given is that of the first
+// predicate that does not.
+func All(preds ...Predicate) Predicate {
+	return func(r Result) (bool, string) {
+		for _, pred := range preds {
+			if ok, reason := pred(r); !ok {
+				if reason == "" {
+					reason = "not satisfied"
+				}
+				return false, reason
+			}
+		}
+		return true, ""
+	}
+}
+
+// Stable holds once pred has held, with identical Out and Code, for the results of n consecutive
+// attempts. Use it to wait for a condition to settle rather than merely to occur. An attempt whose
+// result another predicate rejected before this one saw it breaks the run all the same, since the
+// attempts that this one sees are then not consecutive. The predicate counts the results it has
+// seen, so each call to Until needs a Stable of its own.
+func Stable(n int, pred Predicate) Predicate {
+	var last *Result
+	count := 0
+	return func(r Result) (bool, string) {
+		ok, reason := pred(r)
+		if !ok {
+			last, count = nil, 0
+			return false, reason
+		}
+		if last != nil && r.Attempt == last.Attempt+1 && bytes.Equal(last.Out, r.Out) && last.Code == r.Code {
+			count++
+		} else {
+			count = 1
+		}
+		last = &r
+		if count < n {
+			return false, fmt.Sprintf("stable for %d of %d consecutive attempts", count, n)
+		}
+		return true, ""
+	}
+}
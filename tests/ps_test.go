@@ -1,26 +1,25 @@
 package tests
 
 import (
-	"fmt"
 	"math/rand"
-	"net/http"
-	"regexp"
-	"sort"
-	"strconv"
+	"os"
 	"time"
 
 	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/cmd/git"
+	"github.com/deis/workflow-e2e/tests/cmd/keys"
+	"github.com/deis/workflow-e2e/tests/cmd/ps"
+	"github.com/deis/workflow-e2e/tests/fixtures"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
-	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
-	. "github.com/onsi/gomega/gexec"
 )
 
 var _ = Describe("deis ps", func() {
@@ -37,168 +36,65 @@ var _ = Describe("deis ps", func() {
 			auth.Cancel(user)
 		})
 
-		Context("who owns an existing app that has already been deployed", func() {
+		Context("who owns an existing app that has already been deployed with `deis pull`", func() {
 
 			var app model.App
+			var version int
 
 			BeforeEach(func() {
 				app = apps.Create(user, "--no-remote")
-				builds.Create(user, app)
+				version = builds.Pull(user, app)
 			})
 
 			AfterEach(func() {
 				apps.Destroy(user, app)
 			})
 
-			DescribeTable("that user can scale that app up and down",
-				func(scaleTo, respCode int) {
-					sess, err := cmd.Start("deis ps:scale cmd=%d --app=%s", &user, scaleTo, app.Name)
-					Eventually(sess).Should(Say("Scaling processes... but first,"))
-					Eventually(sess, settings.MaxEventuallyTimeout).Should(Say(`done in \d+s`))
-					Eventually(sess).Should(Say("=== %s Processes", app.Name))
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(0))
-
-					// test that there are the right number of processes listed
-					procsListing := listProcs(user, app, "").Out.Contents()
-					procs := scrapeProcs(app.Name, procsListing)
-					Expect(procs).To(HaveLen(scaleTo))
-
-					// curl the app's root URL and print just the HTTP response code
-					cmdRetryTimeout := 60
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(respCode), cmdRetryTimeout)).Should(BeTrue())
-				},
-				Entry("scales to 1", 1, 200),
-				Entry("scales to 3", 3, 200),
-				Entry("scales to 0", 0, 503),
-			)
-
-			DescribeTable("that user can interrupt a scaling event",
-				func(scaleTo, respCode int) {
-
-					sess, err := cmd.Start("deis ps:scale cmd=%d --app=%s", &user, scaleTo, app.Name)
-					Eventually(sess).Should(Say("Scaling processes... but first,"))
-
-					Expect(err).NotTo(HaveOccurred())
-
-					// Sleep for a split second to ensure scale command makes it to the server.
-					time.Sleep(200 * time.Millisecond)
-
-					// Interrupt and wait for exit.
-					sess = sess.Interrupt().Wait()
-
-					// Ensure the right number of processes listed.
-					Eventually(func() []string {
-						procsListing := listProcs(user, app, "").Out.Contents()
-						return scrapeProcs(app.Name, procsListing)
-					}, settings.MaxEventuallyTimeout).Should(HaveLen(scaleTo))
-
-					// curl the app's root URL and print just the HTTP response code
-					cmdRetryTimeout := 60
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(respCode), cmdRetryTimeout)).Should(BeTrue())
-				},
-				Entry("scales to 3", 3, 200),
-				Entry("scales to 0", 0, 503),
-			)
-
-			// TODO: Test is broken
-			XIt("that app remains responsive during a scaling event", func() {
-				stopCh := make(chan struct{})
-				doneCh := make(chan struct{})
-
-				// start scaling the app
-				go func() {
-					for range stopCh {
-						sess, err := cmd.Start("deis ps:scale web=4 -a %s", &user, app.Name)
-						Eventually(sess).Should(Exit(0))
-						Expect(err).NotTo(HaveOccurred())
-					}
-					close(doneCh)
-				}()
-
-				for i := 0; i < 10; i++ {
-					// start the scale operation. waits until the last scale op has finished
-					stopCh <- struct{}{}
-					resp, err := http.Get(app.URL)
-					Expect(err).To(BeNil())
-					Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusOK))
-				}
+			It("that app runs a single cmd process", func() {
+				Expect(ps.Types(user, app)).To(Equal([]string{ps.CmdType}))
+				ps.ExpectEventually(user, app, ps.Expected{Counts: map[string]int{ps.CmdType: 1}, Version: version})
+			})
+
+			psSpecs(&user, &app, &version)
+
+		})
+
+		Context("who owns an existing buildpack app with a worker that has already been deployed", func() {
+
+			var app model.App
+			var version int
+
+			BeforeEach(func() {
+				_, keyPath := keys.Add(user)
+				f := fixtures.Go.WithProcess("worker", "sh -c 'while true; do echo working; sleep 10; done'")
+				os.Chdir(writeFixture(f))
+				app = apps.Create(user)
+				version = git.Push(user, keyPath, app, f.Manifest.Response).Version
+				// Only the web process is scaled up by a push.
+				ps.Scale(user, app, "worker", 1)
+			})
+
+			AfterEach(func() {
+				apps.Destroy(user, app)
+			})
+
+			It("that app runs web and worker processes", func() {
+				Expect(ps.Types(user, app)).To(Equal([]string{"web", "worker"}))
+				ps.ExpectEventually(user, app, ps.Expected{Counts: map[string]int{"web": 1, "worker": 1}, Version: version})
+			})
+
+			It("that user can scale the worker processes without affecting the web processes", func() {
+				web := ps.List(user, app, "web")
+				ps.Scale(user, app, "worker", 3)
+				ps.ExpectEventually(user, app, ps.Expected{Counts: map[string]int{"web": 1, "worker": 3}, Version: version})
+				Expect(ps.List(user, app, "web")).To(Equal(web))
 
-				// wait until the goroutine that was scaling the app shuts down. not strictly necessary, just good practice
-				Eventually(doneCh).Should(BeClosed())
+				ps.Scale(user, app, "worker", 0)
+				ps.ExpectEventually(user, app, ps.Expected{Counts: map[string]int{"web": 1, "worker": 0}, Version: version})
+				probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: 200})
 			})
 
-			DescribeTable("that user can restart that app's processes",
-				func(restart string, scaleTo int, respCode int) {
-					// TODO: need some way to choose between "web" and "cmd" here!
-					// scale the app's processes to the desired number
-					sess, err := cmd.Start("deis ps:scale cmd=%d --app=%s", &user, scaleTo, app.Name)
-
-					Eventually(sess).Should(Say("Scaling processes... but first,"))
-					Eventually(sess, settings.MaxEventuallyTimeout).Should(Say(`done in \d+s`))
-					Eventually(sess).Should(Say("=== %s Processes", app.Name))
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(0))
-
-					// capture the process names
-					beforeProcs := scrapeProcs(app.Name, sess.Out.Contents())
-
-					// restart the app's process(es)
-					var arg string
-					switch restart {
-					case "all":
-						arg = ""
-					case "by type":
-						// TODO: need some way to choose between "web" and "cmd" here!
-						arg = "cmd"
-					case "by wrong type":
-						// TODO: need some way to choose between "web" and "cmd" here!
-						arg = "web"
-					case "one":
-						procsLen := len(beforeProcs)
-						Expect(procsLen).To(BeNumerically(">", 0))
-						arg = beforeProcs[rand.Intn(procsLen)]
-					}
-					sess, err = cmd.Start("deis ps:restart %s --app=%s", &user, arg, app.Name)
-					Eventually(sess).Should(Say("Restarting processes... but first,"))
-					if scaleTo == 0 || restart == "by wrong type" {
-						Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("Could not find any processes to restart"))
-					} else {
-						Eventually(sess, settings.MaxEventuallyTimeout).Should(Say(`done in \d+s`))
-						Eventually(sess).Should(Say("=== %s Processes", app.Name))
-					}
-					Expect(err).NotTo(HaveOccurred())
-					Eventually(sess).Should(Exit(0))
-
-					// capture the process names
-					procsListing := listProcs(user, app, "").Out.Contents()
-					afterProcs := scrapeProcs(app.Name, procsListing)
-
-					// compare the before and after sets of process names
-					Expect(afterProcs).To(HaveLen(scaleTo))
-					if scaleTo > 0 && restart != "by wrong type" {
-						Expect(beforeProcs).NotTo(Equal(afterProcs))
-					}
-
-					// curl the app's root URL and print just the HTTP response code
-					cmdRetryTimeout := 60
-					curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-					Eventually(cmd.Retry(curlCmd, strconv.Itoa(respCode), cmdRetryTimeout)).Should(BeTrue())
-				},
-				Entry("restarts one of 1", "one", 1, 200),
-				Entry("restarts all of 1", "all", 1, 200),
-				Entry("restarts all of 1 by type", "by type", 1, 200),
-				Entry("restarts all of 1 by wrong type", "by wrong type", 1, 200),
-				Entry("restarts one of 3", "one", 3, 200),
-				Entry("restarts all of 3", "all", 3, 200),
-				Entry("restarts all of 3 by type", "by type", 3, 200),
-				Entry("restarts all of 3 by wrong type", "by wrong type", 3, 200),
-				Entry("restarts all of 0", "all", 0, 503),
-				Entry("restarts all of 0 by type", "by type", 0, 503),
-				Entry("restarts all of 0 by wrong type", "by wrong type", 0, 503),
-			)
+			psSpecs(&user, &app, &version)
 
 		})
 
@@ -206,29 +102,155 @@ var _ = Describe("deis ps", func() {
 
 })
 
-func listProcs(user model.User, app model.App, proctype string) *Session {
-	sess, err := cmd.Start("deis ps:list --app=%s", &user, app.Name)
-	Eventually(sess).Should(Say("=== %s Processes", app.Name))
-	if proctype != "" {
-		Eventually(sess).Should(Say("--- %s:", proctype))
+// psSpecs declares the specs that scale and restart the processes of an app that has been deployed,
+// whatever its process types. They are declared by a function so that they may run against apps
+// deployed in different ways, which the user, app and version of its current release point to once
+// they have been set up.
+func psSpecs(user *model.User, app *model.App, version *int) {
+
+	DescribeTable("that user can scale each of that app's process types up and down",
+		func(scaleTo, respCode int) {
+			types := ps.Types(*user, *app)
+			for _, procType := range types {
+				ps.Scale(*user, *app, procType, scaleTo)
+			}
+			ps.ExpectEventually(*user, *app, ps.Expected{Counts: counts(types, scaleTo), Version: *version})
+
+			// request the app's root URL and check just the HTTP response code
+			probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: respCode})
+		},
+		Entry("scales to 1", 1, 200),
+		Entry("scales to 3", 3, 200),
+		Entry("scales to 0", 0, 503),
+	)
+
+	DescribeTable("that user can interrupt a scaling event",
+		func(scaleTo, respCode int) {
+			procType := servingType(ps.Types(*user, *app))
+			sess, err := cmd.Start("deis ps:scale %s=%d --app=%s", user, procType, scaleTo, app.Name)
+			Eventually(sess).Should(Say("Scaling processes... but first,"))
+
+			Expect(err).NotTo(HaveOccurred())
+
+			// Sleep for a split second to ensure scale command makes it to the server.
+			time.Sleep(200 * time.Millisecond)
+
+			// Interrupt and wait for exit.
+			sess = sess.Interrupt().Wait()
+
+			// Ensure the right number of processes listed.
+			ps.ExpectEventually(*user, *app, ps.Expected{Counts: map[string]int{procType: scaleTo}})
+
+			// request the app's root URL and check just the HTTP response code
+			probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: respCode})
+		},
+		Entry("scales to 3", 3, 200),
+		Entry("scales to 0", 0, 503),
+	)
+
+	It("that app remains responsive during a scaling event", func() {
+		procType := servingType(ps.Types(*user, *app))
+		probe.ExpectAvailableDuring(app.URL, func() {
+			ps.Scale(*user, *app, procType, 4)
+		})
+	})
+
+	It("that app remains responsive while its processes are restarted", func() {
+		procType := servingType(ps.Types(*user, *app))
+		ps.Scale(*user, *app, procType, 2)
+
+		probe.ExpectAvailableDuring(app.URL, func() {
+			Expect(ps.Restart(*user, *app, "", "")).NotTo(BeEmpty())
+		})
+	})
+
+	DescribeTable("that user can restart that app's processes",
+		func(restart string, scaleTo int, respCode int) {
+			// scale every one of the app's process types to the desired number
+			types := ps.Types(*user, *app)
+			for _, procType := range types {
+				ps.Scale(*user, *app, procType, scaleTo)
+			}
+			before := ps.List(*user, *app, "")
+			Expect(before).To(HaveLen(len(types) * scaleTo))
+
+			// restart the app's process(es)
+			var procType, name string
+			switch restart {
+			case "by type":
+				procType = servingType(types)
+			case "by wrong type":
+				procType = missingType(types)
+			case "one":
+				Expect(before).NotTo(BeEmpty())
+				victim := before[rand.Intn(len(before))]
+				procType, name = victim.Type, victim.Name
+			}
+			restarted := ps.Restart(*user, *app, procType, name)
+			if scaleTo == 0 || restart == "by wrong type" {
+				Expect(restarted).To(BeEmpty())
+			} else {
+				Expect(restarted).NotTo(BeEmpty())
+			}
+
+			// compare the before and after sets of process names, type by type
+			ps.ExpectEventually(*user, *app, ps.Expected{Counts: counts(types, scaleTo), Version: *version})
+			after := ps.Names(ps.List(*user, *app, ""))
+			Expect(after).To(HaveLen(len(before)))
+			for _, proc := range before {
+				replaced := restart == "all" ||
+					(restart == "by type" && proc.Type == procType) ||
+					(restart == "one" && proc.Name == name)
+				if replaced {
+					Expect(after).NotTo(ContainElement(proc.Name))
+				} else {
+					Expect(after).To(ContainElement(proc.Name))
+				}
+			}
+
+			// request the app's root URL and check just the HTTP response code
+			probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: respCode})
+		},
+		Entry("restarts one of 1", "one", 1, 200),
+		Entry("restarts all of 1", "all", 1, 200),
+		Entry("restarts all of 1 by type", "by type", 1, 200),
+		Entry("restarts all of 1 by wrong type", "by wrong type", 1, 200),
+		Entry("restarts one of 3", "one", 3, 200),
+		Entry("restarts all of 3", "all", 3, 200),
+		Entry("restarts all of 3 by type", "by type", 3, 200),
+		Entry("restarts all of 3 by wrong type", "by wrong type", 3, 200),
+		Entry("restarts all of 0", "all", 0, 503),
+		Entry("restarts all of 0 by type", "by type", 0, 503),
+		Entry("restarts all of 0 by wrong type", "by wrong type", 0, 503),
+	)
+
+}
+
+// servingType returns the one of the specified process types that serves HTTP requests: web, if
+// it is declared, or else cmd.
+func servingType(types []string) string {
+	for _, procType := range types {
+		if procType == "web" {
+			return procType
+		}
+	}
+	return ps.CmdType
+}
+
+// missingType returns a process type that is not one of the specified types.
+func missingType(types []string) string {
+	if servingType(types) == ps.CmdType {
+		return "web"
 	}
-	Expect(err).NotTo(HaveOccurred())
-	Eventually(sess).Should(Exit(0))
-	return sess
+	return ps.CmdType
 }
 
-// scrapeProcs returns the sorted process names for an app from the given output.
-// It matches the current "deis ps" output for a healthy container:
-//   earthy-vocalist-cmd-123456789-1d73e up (v2)
-//   myapp-web-123456789-bujlq up (v16)
-func scrapeProcs(app string, output []byte) []string {
-	procsRegexp := `(%s-[\w-]+) up \(v\d+\)`
-	re := regexp.MustCompile(fmt.Sprintf(procsRegexp, app))
-	found := re.FindAllSubmatch(output, -1)
-	procs := make([]string, len(found))
-	for i := range found {
-		procs[i] = string(found[i][1])
+// counts returns the expected process counts of an app with the specified process types, each of
+// which has been scaled to the specified number.
+func counts(types []string, scaleTo int) map[string]int {
+	counts := map[string]int{}
+	for _, procType := range types {
+		counts[procType] = scaleTo
 	}
-	sort.Strings(procs)
-	return procs
+	return counts
 }
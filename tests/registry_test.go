@@ -16,6 +16,14 @@ import (
 	. "github.com/onsi/gomega/gexec"
 )
 
+// skipWithoutLocalRegistry skips the current spec unless the suite serves a private image from a
+// local registry, which only it has the credentials for.
+func skipWithoutLocalRegistry() {
+	if settings.RegistryHost == "" {
+		Skip("deploying from a private registry requires E2E_REGISTRY_HOST")
+	}
+}
+
 var _ = Describe("deis registry", func() {
 
 	Context("with an existing user", func() {
@@ -80,8 +88,9 @@ var _ = Describe("deis registry", func() {
 			})
 
 			Specify("that user can not deploy from a private registry due to lack of credentials", func() {
+				skipWithoutLocalRegistry()
 				// do an unsuccessful deploy
-				image := "quay.io/deisci/e2e-private-registry-test"
+				image := settings.RegistryHost + "/" + privateRepository
 				sess, err := cmd.Start("deis pull --app=%s %s", &user, app.Name, image)
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Say("Creating build..."))
@@ -90,6 +99,7 @@ var _ = Describe("deis registry", func() {
 			})
 
 			Specify("that user can deploy from a private registry using registry credentials", func() {
+				skipWithoutLocalRegistry()
 				// Setting a port first is required for a private registry
 				sess, err := cmd.Start("deis config:set -a %s PORT=8080", &user, app.Name)
 				Expect(err).NotTo(HaveOccurred())
@@ -99,15 +109,14 @@ var _ = Describe("deis registry", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
-				// read-only access
-				registry_creds := "TP5BS3NHW0OZ20GER4IORTIJF90J48KKJ8NX8YC7Z22N5P7WE27BRKVMQ4QAEID8"
-				sess, err = cmd.Start("deis registry:set --app=%s username=deisci+e2e_registry password=%s", &user, app.Name, registry_creds)
+				// the local registry's credentials are generated for this run
+				sess, err = cmd.Start("deis registry:set --app=%s username=%s password=%s", &user, app.Name, settings.RegistryUsername, settings.RegistryPassword)
 				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("=== %s Registry", app.Name))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
 				// do a successful deploy
-				image := "quay.io/deisci/e2e-private-registry-test"
+				image := settings.RegistryHost + "/" + privateRepository
 				sess, err = cmd.Start("deis pull --app=%s %s", &user, app.Name, image)
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Say("Creating build..."))
@@ -5,6 +5,7 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
 
@@ -85,6 +86,15 @@ var _ = Describe("deis releases", func() {
 					Eventually(sess).Should(Exit(0))
 				})
 
+				Specify("that app remains responsive while it is rolled back to the second release", func() {
+					probe.ExpectAvailableDuring(app.URL, func() {
+						sess, err := cmd.Start("deis releases:rollback v2 -a %s", &user, app.Name)
+						Expect(err).NotTo(HaveOccurred())
+						Eventually(sess, settings.MaxEventuallyTimeout).Should(Say(`...done`))
+						Eventually(sess).Should(Exit(0))
+					})
+				})
+
 			})
 
 		})
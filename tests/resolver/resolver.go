@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/settings"
+)
+
+// The functions in this file route requests for apps, custom domains and the controller to the
+// Workflow router without relying on DNS or /etc/hosts. When $DEIS_ROUTER_SERVICE_HOST is not set,
+// they do nothing, and hostnames are resolved as usual.
+
+// Enabled returns true if requests are being routed to the router at $DEIS_ROUTER_SERVICE_HOST.
+func Enabled() bool {
+	return settings.RouterHost != ""
+}
+
+// URL returns the URL at which the router serves the specified hostname using the specified
+// scheme, which may be "http" or "https".
+func URL(scheme, hostname string) string {
+	port := settings.RouterPort
+	defaultPort := "80"
+	if scheme == "https" {
+		port = settings.RouterHTTPSPort
+		defaultPort = "443"
+	}
+	if port == "" || port == defaultPort {
+		return fmt.Sprintf("%s://%s", scheme, hostname)
+	}
+	return fmt.Sprintf("%s://%s:%s", scheme, hostname, port)
+}
+
+// CurlFlags returns the curl flags that route a request for the specified URL to the router. The
+// hostname is left intact, so the Host header and, for https, SNI are the same as they would be
+// if it had been resolved by DNS.
+func CurlFlags(rawurl string) string {
+	if !Enabled() {
+		return ""
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	host, port := splitHostPort(u)
+	return fmt.Sprintf("--resolve %s:%s:%s", host, port, settings.RouterHost)
+}
+
+// DialContext connects to the router on the port in addr, regardless of the host in addr. It is
+// suitable for use as an http.Transport's DialContext.
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if Enabled() {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(settings.RouterHost, port)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// Transport returns an http.Transport that routes every request to the router. Certificates are
+// not verified, since the router is likely to be serving self-signed certificates.
+func Transport() *http.Transport {
+	return &http.Transport{
+		DialContext:         DialContext,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// NewControllerProxy starts a local HTTP server that forwards every request to the controller
+// at controllerURL by way of the router, for the benefit of clients such as the deis CLI that
+// cannot be told how to reach it. The caller must close the returned server.
+func NewControllerProxy(controllerURL string) (*httptest.Server, error) {
+	target, err := url.Parse(controllerURL)
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		// The router routes requests by their Host header, so it must name the controller.
+		req.Host = target.Host
+	}
+	proxy.Transport = Transport()
+	return httptest.NewServer(proxy), nil
+}
+
+func splitHostPort(u *url.URL) (string, string) {
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return host, port
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/deis/workflow-e2e/tests/cmd"
+	"github.com/deis/workflow-e2e/tests/cmd/apps"
+	"github.com/deis/workflow-e2e/tests/cmd/auth"
+	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/cmd/configs"
+	"github.com/deis/workflow-e2e/tests/cmd/run"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/parse"
+	"github.com/deis/workflow-e2e/tests/settings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("deis apps:run", func() {
+
+	Context("with an existing user", func() {
+
+		var user model.User
+
+		BeforeEach(func() {
+			user = auth.Register()
+		})
+
+		AfterEach(func() {
+			auth.Cancel(user)
+		})
+
+		Context("who owns an existing app that has already been deployed", func() {
+
+			var app model.App
+
+			BeforeEach(func() {
+				app = apps.Create(user, "--no-remote")
+				builds.Pull(user, app)
+			})
+
+			AfterEach(func() {
+				apps.Destroy(user, app)
+			})
+
+			DescribeTable("the exit code of a command run in that app's environment is that of the command",
+				func(command string, exitCode int) {
+					result := run.Run(user, app, command)
+					Expect(result.ExitCode).To(Equal(exitCode), string(result.Output))
+				},
+				Entry("exits zero", "true", 0),
+				Entry("exits one", "false", 1),
+				Entry("exits with a chosen code", "exit 42", 42),
+				Entry("is not found", "/usr/bin/boguscmd", 127),
+				Entry("is terminated", "sh -c 'kill -TERM $$'", 128+15),
+				Entry("is killed", "sh -c 'kill -KILL $$'", 128+9),
+			)
+
+			Specify("that user can pipe between commands run in that app's environment", func() {
+				result := run.Run(user, app, "echo 'Hello, world' | tr a-z A-Z | tee /dev/stderr")
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(string(result.Output)).To(Equal("HELLO, WORLD\nHELLO, WORLD\n"))
+			})
+
+			Specify("that user's standard input is not passed to a command run in that app's environment", func() {
+				result := run.RunWithStdin(user, app, "cat; echo read all input", []byte("this is not forwarded\n"))
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(string(result.Output)).To(Equal("read all input\n"))
+			})
+
+			Specify("the output of a command run in that app's environment is returned byte for byte", func() {
+				// Every ASCII character, NUL included, then some UTF-8, without a trailing newline.
+				expected := []byte{}
+				for b := 0; b < 128; b++ {
+					expected = append(expected, byte(b))
+				}
+				expected = append(expected, "Hello, 世界"...)
+				result := run.Run(user, app, "printf '"+octal(expected)+"'")
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(result.Output).To(Equal(expected))
+			})
+
+			Specify("output that is not UTF-8 reaches that user intact once encoded", func() {
+				// The controller returns the output as a JSON string, which cannot carry bytes that are
+				// not valid UTF-8, such as 0x80-0xFF on their own. Every byte value therefore makes the
+				// round trip as base64.
+				expected := []byte{}
+				for b := 0; b < 256; b++ {
+					expected = append(expected, byte(b))
+				}
+				result := run.Run(user, app, "printf '"+octal(expected)+"' | base64")
+				Expect(result.ExitCode).To(Equal(0), string(result.Output))
+				decoded, err := base64.StdEncoding.DecodeString(string(result.Output))
+				Expect(err).NotTo(HaveOccurred(), string(result.Output))
+				Expect(decoded).To(Equal(expected))
+			})
+
+			Specify("that user can run a long-running command in that app's environment", func() {
+				result := run.Run(user, app, "sleep 30 && echo finished")
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(string(result.Output)).To(Equal("finished\n"))
+			})
+
+			Specify("that user can give up on a command that outlasts its deadline and run another", func() {
+				_, err := run.RunWithin(user, app, "sleep 600", settings.DefaultEventuallyTimeout)
+				Expect(cmd.IsTimeout(err)).To(BeTrue(), "%v", err)
+
+				result := run.Run(user, app, "echo still running")
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(string(result.Output)).To(Equal("still running\n"))
+			})
+
+			Specify("a command run in that app's environment sees that app's config", func() {
+				configs.Set(user, app, "E2E_GREETING", "hello-world")
+				configs.Set(user, app, "E2E_PORT", "5000")
+				sess, err := cmd.Deis(&user, "config:list", "--app="+app.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess).Should(Exit(0))
+				config, err := parse.ConfigList(sess.Out.Contents())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config).To(HaveKey("E2E_GREETING"))
+
+				env := run.Env(run.Run(user, app, "env"))
+				for key, value := range config {
+					Expect(env).To(HaveKeyWithValue(key, value))
+				}
+			})
+
+			Specify("that user can run several commands in that app's environment at once", func() {
+				var commands []string
+				for i := 0; i < 5; i++ {
+					commands = append(commands, fmt.Sprintf("sleep 5; echo run %d", i))
+				}
+				for i, result := range run.RunConcurrently(user, app, commands...) {
+					Expect(result.ExitCode).To(Equal(0))
+					Expect(string(result.Output)).To(Equal(fmt.Sprintf("run %d\n", i)))
+				}
+			})
+
+			Specify("that user can run a command in that app's environment while it is being deployed", func() {
+				// New processes are not ready until well after the command has run, so the deploy that
+				// follows is still rolling out once the command returns.
+				sess, err := cmd.Start("deis healthchecks:set readiness exec --initial-delay-timeout=%d -a %s -- /bin/true",
+					&user, int(slowDeploy.Seconds()), app.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+
+				sess, err = cmd.Deis(&user, "config:set", "E2E_DEPLOYED=yes", "--app="+app.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess).Should(Say("Creating config..."))
+
+				result := run.Run(user, app, "echo during the deploy")
+				Expect(result.ExitCode).To(Equal(0))
+				Expect(string(result.Output)).To(Equal("during the deploy\n"))
+				Expect(sess.ExitCode()).To(Equal(-1), "the deploy was over before the command returned")
+
+				Eventually(sess, settings.MaxEventuallyTimeout).Should(Exit(0))
+				Expect(run.Env(run.Run(user, app, "env"))).To(HaveKeyWithValue("E2E_DEPLOYED", "yes"))
+			})
+
+		})
+
+	})
+
+})
+
+// slowDeploy is how long after they start the processes of a deploy are made to become ready, by
+// the spec that runs a command while the app is being deployed.
+const slowDeploy = 60 * time.Second
+
+// octal returns the specified bytes escaped for printf, so that a shell passes them on intact.
+func octal(b []byte) string {
+	var escaped bytes.Buffer
+	for _, c := range b {
+		fmt.Fprintf(&escaped, `\%03o`, c)
+	}
+	return escaped.String()
+}
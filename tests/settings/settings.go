@@ -3,29 +3,86 @@ package settings
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
-
-	"github.com/deis/workflow-e2e/tests/util"
 )
 
 const (
 	DeisRootHostname = "k8s.local"
+	// FakeControllerURL may be exported as DEIS_CONTROLLER_URL to run the suite against an
+	// in-process fake controller instead of a Workflow cluster.
+	FakeControllerURL = "fake://"
+	// CLIDriver and SDKDriver are the values that may be exported as E2E_DRIVER to select whether
+	// the helpers in tests/cmd execute the deis CLI or call the controller via controller-sdk-go.
+	CLIDriver = "cli"
+	SDKDriver = "sdk"
 )
 
 var (
 	ActualHome               = os.Getenv("HOME")
 	TestHome                 string
 	TestRoot                 string
+	SpecHome                 string
 	DeisControllerURL        string
+	RouterHost               = os.Getenv("DEIS_ROUTER_SERVICE_HOST")
+	RouterPort               = os.Getenv("DEIS_ROUTER_SERVICE_PORT")
+	RouterHTTPSPort          = os.Getenv("DEIS_ROUTER_SERVICE_PORT_HTTPS")
 	DefaultEventuallyTimeout time.Duration
 	MaxEventuallyTimeout     time.Duration
 	GitSSH                   string
 	Debug                    = os.Getenv("DEBUG") != ""
+	UseFakeController        bool
+	Driver                   string
+	// RoutedControllerURL is the URL at which the router serves the controller. Unlike
+	// DeisControllerURL, it is never replaced by the address of a proxy or fake controller, so app
+	// URLs may always be derived from it.
+	RoutedControllerURL string
+	// ProxyController is true if DEIS_CONTROLLER_URL was not set, in which case the controller is
+	// reached through a local proxy to the router.
+	ProxyController bool
+	// LoadRate, LoadMaxErrorRate and LoadMaxP99 govern the load generated against apps by specs
+	// that verify they remain available during an operation such as a scaling event or a deploy.
+	LoadRate         int
+	LoadMaxErrorRate float64
+	LoadMaxP99       time.Duration
+	// LogsMaxLatency is how long after a spec writes a line to an app's logs it may first be seen in
+	// the output of `deis logs`.
+	LogsMaxLatency time.Duration
+	// Kubeconfig is the path to the kubeconfig used to inspect the resources Workflow creates for
+	// apps in the cluster under test. If there is no such file, the suite inspects the cluster it
+	// runs in.
+	Kubeconfig string
+	// RegistryHost is the host:port at which the cluster under test can reach a registry that the
+	// suite serves from this machine, on the same port. When it is set, that registry serves the
+	// images that specs deploy, instead of Docker Hub and quay.io. RegistryTokenAuth makes the
+	// registry require clients to authenticate with a bearer token.
+	RegistryHost      = os.Getenv("E2E_REGISTRY_HOST")
+	RegistryTokenAuth = os.Getenv("E2E_REGISTRY_TOKEN_AUTH") == "true"
+	// RegistryUsername and RegistryPassword are the credentials, generated anew for each run, that
+	// are required to pull private images from that registry.
+	RegistryUsername string
+	RegistryPassword string
 )
 
 func init() {
 	DeisControllerURL = getControllerURL()
+	Kubeconfig = os.Getenv("KUBECONFIG")
+	if Kubeconfig == "" {
+		Kubeconfig = filepath.Join(ActualHome, ".kube", "config")
+	}
+	RoutedControllerURL = DeisControllerURL
+	ProxyController = os.Getenv("DEIS_CONTROLLER_URL") == ""
+	UseFakeController = DeisControllerURL == FakeControllerURL
+	Driver = os.Getenv("E2E_DRIVER")
+	if Driver == "" {
+		Driver = CLIDriver
+	}
+	if Driver != CLIDriver && Driver != SDKDriver {
+		log.Fatalf("E2E_DRIVER must be either %q or %q (got %q)", CLIDriver, SDKDriver, Driver)
+	}
 	defaultEventuallyTimeoutStr := os.Getenv("DEFAULT_EVENTUALLY_TIMEOUT")
 	if defaultEventuallyTimeoutStr == "" {
 		DefaultEventuallyTimeout = 60 * time.Second
@@ -39,8 +96,52 @@ func init() {
 	} else {
 		MaxEventuallyTimeout, _ = time.ParseDuration(maxEventuallyTimeoutStr)
 	}
+
+	loadRateStr := os.Getenv("LOAD_RATE")
+	if loadRateStr == "" {
+		LoadRate = 10
+	} else {
+		LoadRate, _ = strconv.Atoi(loadRateStr)
+	}
+	if LoadRate <= 0 {
+		log.Fatalf("LOAD_RATE must be a positive number of requests per second (got %q)", loadRateStr)
+	}
+
+	loadMaxErrorRateStr := os.Getenv("LOAD_MAX_ERROR_RATE")
+	if loadMaxErrorRateStr != "" {
+		LoadMaxErrorRate, _ = strconv.ParseFloat(loadMaxErrorRateStr, 64)
+	}
+
+	loadMaxP99Str := os.Getenv("LOAD_MAX_P99")
+	if loadMaxP99Str == "" {
+		LoadMaxP99 = 2 * time.Second
+	} else {
+		LoadMaxP99, _ = time.ParseDuration(loadMaxP99Str)
+	}
+
+	logsMaxLatencyStr := os.Getenv("LOGS_MAX_LATENCY")
+	if logsMaxLatencyStr == "" {
+		LogsMaxLatency = 30 * time.Second
+	} else {
+		LogsMaxLatency, _ = time.ParseDuration(logsMaxLatencyStr)
+	}
+
+	if RegistryHost != "" {
+		if _, _, err := net.SplitHostPort(RegistryHost); err != nil {
+			log.Fatalf("E2E_REGISTRY_HOST must be of the form host:port (got %q)", RegistryHost)
+		}
+	}
 }
 
+// NoControllerMessage explains how to tell the suite where the controller is, for when
+// DeisControllerURL is empty because neither DEIS_CONTROLLER_URL nor DEIS_ROUTER_SERVICE_HOST is
+// set.
+const NoControllerMessage = `Set the router host and port for tests, such as:
+
+$ DEIS_ROUTER_SERVICE_HOST=192.0.2.10 DEIS_ROUTER_SERVICE_PORT=31182 make test-integration`
+
+// getControllerURL returns $DEIS_CONTROLLER_URL or, if it is not set, the URL at which the router
+// serves the controller. It returns "" if the router is not known either.
 func getControllerURL() string {
 	// if DEIS_CONTROLLER_URL exists in the environment, use that
 	controllerURL := os.Getenv("DEIS_CONTROLLER_URL")
@@ -48,24 +149,18 @@ func getControllerURL() string {
 		return controllerURL
 	}
 
-	// otherwise, rely on kubernetes and some DNS magic
-	host := "deis." + DeisRootHostname
-	if err := util.AddToEtcHosts(host); err != nil {
-		log.Fatalf("Could not write %s to /etc/hosts (%s)", host, err)
-	}
-	// also gotta write a route for the builder
-	builderHost := "deis-builder." + DeisRootHostname
-	if err := util.AddToEtcHosts(builderHost); err != nil {
-		log.Fatalf("Could not write %s to /etc/hosts (%s)", builderHost, err)
+	// otherwise, rely on kubernetes to tell us where the router is
+	if RouterHost == "" {
+		return ""
 	}
 
-	port := os.Getenv("DEIS_ROUTER_SERVICE_PORT")
-	switch port {
+	host := "deis." + DeisRootHostname
+	switch RouterPort {
 	case "443":
 		return "https://" + host
 	case "80", "":
 		return "http://" + host
 	default:
-		return fmt.Sprintf("http://%s:%s", host, port)
+		return fmt.Sprintf("http://%s:%s", host, RouterPort)
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/kube"
 	"github.com/deis/workflow-e2e/tests/model"
 	"github.com/deis/workflow-e2e/tests/settings"
 	"github.com/deis/workflow-e2e/tests/util"
@@ -94,6 +95,12 @@ var _ = Describe("deis tags", func() {
 				Eventually(sess).Should(Say(`%s\s+%s`, label[0], label[1]))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
+
+				// Check that the app's pods are scheduled using the tag
+				Eventually(func() (map[string]string, error) {
+					spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+					return kube.NodeSelector(spec), err
+				}, settings.MaxEventuallyTimeout).Should(HaveKeyWithValue(label[0], label[1]))
 			})
 
 			Context("and a tag has already been added to the app", func() {
@@ -133,6 +140,12 @@ var _ = Describe("deis tags", func() {
 					Eventually(sess).ShouldNot(Say(`munkafolyamat\s+yeah`, app.Name))
 					Expect(err).NotTo(HaveOccurred())
 					Eventually(sess).Should(Exit(0))
+
+					// Check that the app's pods are no longer scheduled using the tag
+					Eventually(func() (map[string]string, error) {
+						spec, err := kubeCluster().PodSpec(app.Name, "cmd")
+						return kube.NodeSelector(spec), err
+					}, settings.MaxEventuallyTimeout).ShouldNot(HaveKey(label[0]))
 				})
 
 			})
@@ -1,17 +1,30 @@
 package tests
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
+	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/fake"
+	"github.com/deis/workflow-e2e/tests/fixtures"
+	"github.com/deis/workflow-e2e/tests/janitor"
+	"github.com/deis/workflow-e2e/tests/kube"
+	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/resolver"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/ginkgo"
@@ -20,15 +33,51 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// suiteState is the one-time setup performed on the first Ginkgo node, as handed to every node
+// by SynchronizedBeforeSuite.
+type suiteState struct {
+	TestHome         string `json:"testHome"`
+	ControllerURL    string `json:"controllerURL"`
+	RegistryHost     string `json:"registryHost"`
+	RegistryUsername string `json:"registryUsername"`
+	RegistryPassword string `json:"registryPassword"`
+}
+
+// privateRepository is the repository that the local registry serves only to clients with its
+// credentials.
+const privateRepository = "deisci/e2e-private-registry-test"
+
+// fakeController is only started (on the first Ginkgo node) when DEIS_CONTROLLER_URL=fake://
+var fakeController *fake.Controller
+
+// controllerProxy is only started (on the first Ginkgo node) when DEIS_CONTROLLER_URL is not set.
+var controllerProxy *httptest.Server
+
+// localRegistry is only started (on the first Ginkgo node) when E2E_REGISTRY_HOST is set, or
+// alongside fakeController.
+var localRegistry *fake.Registry
+
+// fakeBuilder is only started (on the first Ginkgo node) alongside fakeController.
+var fakeBuilder *fake.Builder
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
 func TestTests(t *testing.T) {
+	if settings.DeisControllerURL == "" {
+		t.Fatal(settings.NoControllerMessage)
+	}
 	RegisterFailHandler(Fail)
 
 	enableJunit := os.Getenv("JUNIT")
 	if enableJunit == "true" {
+		// Alongside each node's JUnit report, record every command that node executes.
+		reportPath := filepath.Join(settings.ActualHome, fmt.Sprintf("commands-%d.jsonl", GinkgoConfig.ParallelNode))
+		if err := cmd.OpenReport(reportPath); err != nil {
+			t.Fatalf("Could not create %s (%s)", reportPath, err)
+		}
+		defer cmd.CloseReport()
 		junitReporter := reporters.NewJUnitReporter(filepath.Join(settings.ActualHome, fmt.Sprintf("junit-%d.xml", GinkgoConfig.ParallelNode)))
 		RunSpecsWithDefaultAndCustomReporters(t, "Deis Workflow", []Reporter{junitReporter})
 	} else {
@@ -62,7 +111,6 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	if settings.Debug {
 		sshFlags = sshFlags + " -v"
 	}
-	ioutil.WriteFile(settings.GitSSH, []byte(fmt.Sprintf("#!/bin/sh\nSSH_ORIGINAL_COMMAND=\"ssh $@\"\nexec /usr/bin/ssh %s -i \"$GIT_KEY\" \"$@\"\n", sshFlags)), 0777)
 
 	// Set $HOME before we go any further. The user registration step below will need this to be
 	// set correctly in order for the profile containing the user's auth token to be written to
@@ -73,16 +121,56 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	// registration and we'll want this timeout set before then.
 	SetDefaultEventuallyTimeout(settings.DefaultEventuallyTimeout)
 
+	// When asked to, start a fake controller in this process and point every node at it. This
+	// node's process lives until the whole suite has finished, so the fake will outlive all specs.
+	if settings.UseFakeController {
+		fakeController = fake.NewController(settings.DeisRootHostname)
+		settings.DeisControllerURL = fakeController.URL
+		// Likewise a fake builder, to which every push is sent instead of to the builder that the
+		// git remote names.
+		fakeBuilder, err = fake.NewBuilder("127.0.0.1:0", fakeController)
+		Expect(err).NotTo(HaveOccurred())
+	} else if settings.ProxyController {
+		// Likewise, the deis CLI cannot be told to reach the controller via the router, so give it a
+		// proxy to talk to instead.
+		controllerProxy, err = resolver.NewControllerProxy(settings.DeisControllerURL)
+		Expect(err).NotTo(HaveOccurred())
+		settings.DeisControllerURL = controllerProxy.URL
+	}
+
+	// When asked to, serve the images that specs deploy from a registry in this process, so that
+	// neither Docker Hub nor quay.io need be reachable from the cluster.
+	if settings.RegistryHost != "" || settings.UseFakeController {
+		localRegistry = startRegistry()
+	}
+
+	// Now that it is known where pushes must go, install the git wrapper script.
+	writeGitSSH(sshFlags)
+
 	// ATTEMPT to register the admin user. Since the FIRST user to regiser in a new cluster is
 	// automatically the admin, it's vitally important that this happen now. If the admin user
 	// already exists, this step will attempt to login as that user.
 	auth.RegisterAdmin()
 
-	// Return the value of testHome as bytes. Ginkgo will pass these to the function below, which
-	// will be executed on every node (like BeforeSuite would if we were using it.)
-	return []byte(testHome)
+	// Return the suite state as bytes. Ginkgo will pass these to the function below, which will be
+	// executed on every node (like BeforeSuite would if we were using it.)
+	data, err := json.Marshal(suiteState{
+		TestHome:         testHome,
+		ControllerURL:    settings.DeisControllerURL,
+		RegistryHost:     settings.RegistryHost,
+		RegistryUsername: settings.RegistryUsername,
+		RegistryPassword: settings.RegistryPassword,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return data
 }, func(data []byte) {
-	settings.TestHome = string(data)
+	var state suiteState
+	Expect(json.Unmarshal(data, &state)).To(Succeed())
+	settings.TestHome = state.TestHome
+	settings.DeisControllerURL = state.ControllerURL
+	settings.RegistryHost = state.RegistryHost
+	settings.RegistryUsername = state.RegistryUsername
+	settings.RegistryPassword = state.RegistryPassword
 
 	// Set $HOME for the benefit of all commands we will fork to execute.
 	os.Setenv("HOME", settings.TestHome)
@@ -103,10 +191,149 @@ var _ = BeforeEach(func() {
 	Expect(err).NotTo(HaveOccurred())
 	// Everything we do, we do from within that directory...
 	os.Chdir(settings.TestRoot)
-	// But note that all test users and tests still share a common $HOME!
+	// And every user registered by the test gets a private $HOME beneath the shared one, in which to
+	// keep their profile, SSH keys and git configuration. Only the admin's profile lives in the
+	// shared $HOME itself.
+	settings.SpecHome, err = ioutil.TempDir(settings.TestHome, "spec-home")
+	Expect(err).NotTo(HaveOccurred())
 })
 
-var _ = SynchronizedAfterSuite(func() {}, func() {
+var _ = AfterEach(func() {
+	// This runs after every AfterEach within the test, so its users have all been cancelled.
+	os.RemoveAll(settings.SpecHome)
+	settings.SpecHome = ""
+})
+
+var _ = SynchronizedAfterSuite(func() {
+	// Specs that fail before they can clean up after themselves leave resources behind. Tear down
+	// whatever this node's specs leaked before the admin that will do so is cancelled.
+	leftovers := model.Created.Leftovers()
+	if len(leftovers) == 0 {
+		return
+	}
+	client, err := cmd.ProfileClient(model.Admin)
+	Expect(err).NotTo(HaveOccurred())
+	for _, err := range janitor.New(client, os.Stdout).Sweep(leftovers) {
+		fmt.Printf("WARNING: %s\n", err)
+	}
+}, func() {
 	auth.CancelAdmin()
 	os.RemoveAll(settings.TestHome)
+	if fakeController != nil {
+		fakeController.Close()
+	}
+	if controllerProxy != nil {
+		controllerProxy.Close()
+	}
+	if localRegistry != nil {
+		localRegistry.Close()
+	}
+	if fakeBuilder != nil {
+		fakeBuilder.Close()
+	}
 })
+
+// writeGitSSH writes the git wrapper script to settings.GitSSH. It executes ssh with the specified
+// flags and the key at $GIT_KEY. Whatever host the git remote names, it connects to the fake
+// builder when fakeBuilder is running, dropping the port that git passes in favor of the fake
+// builder's, or else to the router when the resolver is enabled.
+func writeGitSSH(sshFlags string) {
+	script := "#!/bin/sh\nSSH_ORIGINAL_COMMAND=\"ssh $@\"\n"
+	if fakeBuilder != nil {
+		host, port, err := net.SplitHostPort(fakeBuilder.Addr)
+		Expect(err).NotTo(HaveOccurred())
+		script += `for arg; do
+	shift
+	if [ -n "$skip" ]; then skip=; continue; fi
+	if [ "$arg" = "-p" ]; then skip=1; continue; fi
+	set -- "$@" "$arg"
+done
+`
+		sshFlags = fmt.Sprintf("%s -o HostName=%s -p %s", sshFlags, host, port)
+	} else if resolver.Enabled() {
+		sshFlags = sshFlags + " -o HostName=" + settings.RouterHost
+	}
+	script += fmt.Sprintf("exec /usr/bin/ssh %s -i \"$GIT_KEY\" \"$@\"\n", sshFlags)
+	Expect(ioutil.WriteFile(settings.GitSSH, []byte(script), 0777)).To(Succeed())
+}
+
+// startRegistry starts a registry listening on the port of settings.RegistryHost, and seeds it
+// with an image of the Go fixture, both publicly as builds.ExampleRepository and privately as
+// privateRepository. It records the registry's host and credentials in settings.
+func startRegistry() *fake.Registry {
+	var registry *fake.Registry
+	if settings.UseFakeController {
+		// Nothing pulls the images that the fake controller deploys, so the registry need only be
+		// reachable from this machine.
+		var err error
+		registry, err = fake.NewRegistry("", "")
+		Expect(err).NotTo(HaveOccurred())
+	} else {
+		_, port, err := net.SplitHostPort(settings.RegistryHost)
+		Expect(err).NotTo(HaveOccurred())
+		registry, err = fake.NewRegistry(":"+port, settings.RegistryHost)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	registry.TokenAuth = settings.RegistryTokenAuth
+
+	image, err := fixtures.Go.Image()
+	Expect(err).NotTo(HaveOccurred())
+	_, err = registry.AddImage(builds.ExampleRepository, "latest", true, image.Config, image.Layers...)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(registry.Copy(builds.ExampleRepository, privateRepository, false)).To(Succeed())
+
+	settings.RegistryHost = registry.Host
+	settings.RegistryUsername = registry.Username
+	settings.RegistryPassword = registry.Password
+	return registry
+}
+
+// sharedCluster is connected to the cluster under test by the first call to kubeCluster on each
+// node.
+var (
+	sharedCluster    *kube.Cluster
+	sharedClusterErr error
+	connectCluster   sync.Once
+)
+
+// kubeCluster returns a kube.Cluster for inspecting the resources Workflow has created for apps in
+// the cluster under test. Every call on a node returns the same one, so it may be called within
+// the functions polled by Eventually without building a new client each time.
+func kubeCluster() *kube.Cluster {
+	connectCluster.Do(func() {
+		sharedCluster, sharedClusterErr = kube.Connect(settings.Kubeconfig)
+	})
+	ExpectWithOffset(1, sharedClusterErr).NotTo(HaveOccurred())
+	return sharedCluster
+}
+
+// nodeLabel returns KEY=value for a label of a node in the cluster under test, or of the fake
+// controller's imaginary nodes, preferring the node's hostname.
+func nodeLabel() string {
+	labels := fake.DefaultNodeLabels
+	if !settings.UseFakeController {
+		nodes, err := kubeCluster().NodeLabels()
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		ExpectWithOffset(1, nodes).NotTo(BeEmpty(), "the cluster has no nodes")
+		labels = nodes[0]
+	}
+	key := "kubernetes.io/hostname"
+	if _, ok := labels[key]; !ok {
+		var keys []string
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		ExpectWithOffset(1, keys).NotTo(BeEmpty(), "the node has no labels")
+		sort.Strings(keys)
+		key = keys[0]
+	}
+	return key + "=" + labels[key]
+}
+
+// writeFixture materializes the specified example app as a git repo within settings.TestRoot and
+// returns its path.
+func writeFixture(f fixtures.Fixture) string {
+	dir, err := f.Materialize(settings.TestRoot)
+	Expect(err).NotTo(HaveOccurred())
+	return dir
+}
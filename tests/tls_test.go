@@ -1,14 +1,17 @@
 package tests
 
 import (
-	"fmt"
-	"strconv"
+	"net/http"
 
 	"github.com/deis/workflow-e2e/tests/cmd"
 	"github.com/deis/workflow-e2e/tests/cmd/apps"
 	"github.com/deis/workflow-e2e/tests/cmd/auth"
 	"github.com/deis/workflow-e2e/tests/cmd/builds"
+	"github.com/deis/workflow-e2e/tests/cmd/certs"
+	"github.com/deis/workflow-e2e/tests/cmd/domains"
+	probe "github.com/deis/workflow-e2e/tests/http"
 	"github.com/deis/workflow-e2e/tests/model"
+	"github.com/deis/workflow-e2e/tests/resolver"
 	"github.com/deis/workflow-e2e/tests/settings"
 
 	. "github.com/onsi/ginkgo"
@@ -50,19 +53,75 @@ var _ = Describe("deis tls", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
-				// curl the app's root URL and ensure we get a 301 redirect
-				cmdRetryTimeout := 60
-				curlCmd := model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-				Eventually(cmd.Retry(curlCmd, strconv.Itoa(301), cmdRetryTimeout)).Should(BeTrue())
+				// request the app's root URL without following redirects and ensure we get a 301
+				// redirect to https
+				unfollowed := probe.Get(app.URL)
+				unfollowed.FollowRedirects = false
+				probe.ExpectEventually(unfollowed, probe.Expected{StatusCode: 301, Header: map[string]string{"Location": "https://"}})
 
 				sess, err = cmd.Start("deis tls:disable --app=%s", &user, app.Name)
 				Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("done"))
 				Expect(err).NotTo(HaveOccurred())
 				Eventually(sess).Should(Exit(0))
 
-				cmdRetryTimeout = 60
-				curlCmd = model.Cmd{CommandLineString: fmt.Sprintf(`curl -sL -w "%%{http_code}\\n" "%s" -o /dev/null`, app.URL)}
-				Eventually(cmd.Retry(curlCmd, strconv.Itoa(200), cmdRetryTimeout)).Should(BeTrue())
+				probe.ExpectEventually(probe.Get(app.URL), probe.Expected{StatusCode: 200})
+			})
+
+			Context("with a domain added to it, to which a cert is attached", func() {
+
+				var domain string
+				var cert model.Cert
+				// defaultCert is the fingerprint of the cert the router served for the domain before
+				// the cert was attached.
+				var defaultCert string
+
+				BeforeEach(func() {
+					domain = getRandDomain()
+					domains.Add(user, app, domain)
+					cert = model.NewCertWith(model.CertOptions{CommonName: domain, Intermediates: 1})
+					certs.Add(user, cert)
+					defaultCert = certs.ServedFingerprint(domain)
+					certs.Attach(user, cert, domain)
+					certs.ExpectServed(cert, domain)
+				})
+
+				AfterEach(func() {
+					certs.Detach(user, cert, domain)
+					certs.ExpectNotServed(cert, domain, defaultCert)
+					certs.Remove(user, cert)
+					domains.Remove(user, app, domain)
+				})
+
+				Specify("can enable/disable tls, which redirects that domain to https with HSTS", func() {
+					sess, err := cmd.Start("deis tls:enable --app=%s", &user, app.Name)
+					Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("done"))
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(sess).Should(Exit(0))
+
+					domainURL := resolver.URL("http", domain)
+					unfollowed := probe.Get(domainURL)
+					unfollowed.FollowRedirects = false
+					probe.ExpectEventually(unfollowed, probe.Expected{
+						StatusCode: http.StatusMovedPermanently,
+						Header:     map[string]string{"Location": "https://" + domain},
+					})
+					secure := probe.Get(resolver.URL("https", domain))
+					secure.RootCAs = model.CertAuthority().Pool()
+					probe.ExpectEventually(secure, probe.Expected{
+						StatusCode:     http.StatusOK,
+						Header:         map[string]string{"Strict-Transport-Security": "max-age="},
+						PeerCommonName: domain,
+					})
+					certs.ExpectServed(cert, domain)
+
+					sess, err = cmd.Start("deis tls:disable --app=%s", &user, app.Name)
+					Eventually(sess, settings.MaxEventuallyTimeout).Should(Say("done"))
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(sess).Should(Exit(0))
+
+					probe.ExpectEventually(probe.Get(domainURL), probe.Expected{StatusCode: http.StatusOK})
+				})
+
 			})
 		})
 	})